@@ -0,0 +1,16 @@
+package main
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+// blockSize returns the block size of the file system holding the named file,
+// which need not exist yet, or 0 if it can't be determined.
+func blockSize(name string) int64 {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Dir(name), &st); err != nil {
+		return 0
+	}
+	return int64(st.Bsize)
+}
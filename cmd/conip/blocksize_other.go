@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// blockSize reports that the block size of file systems is unknown on this
+// platform, so that output buffers keep the default size.
+func blockSize(name string) int64 {
+	return 0
+}
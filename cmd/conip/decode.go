@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/zephyrtronium/conip/debruijn"
+)
+
+// decode runs the decode command and returns the exit status: 0 if the whole
+// stream is decoded, 1 if it can't be read or the output can't be written,
+// and 2 for bad arguments.
+func decode(args []string) int {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `usage: conip decode [flags] [file]
+
+Print the IPv4 address of each four-byte window of the binary output of
+conip generate -bin in file, or stdin if file is absent or -, one per line in
+the order the windows begin. The three bytes at the end of the stream begin
+no complete window, so a stream of n bytes holds n-3 addresses, and one
+shorter than four bytes holds none. With -format, the stream is instead the
+output of conip generate -format with the same name, whose terms are read back
+as the bytes of the windows.
+
+`)
+		fs.PrintDefaults()
+	}
+	formatName := "bin"
+	fs.StringVar(&formatName, "format", "bin", "encoding of the stream: any format of conip generate -format but quad")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	format, err := debruijn.ParseFormat(formatName)
+	if err != nil || format == debruijn.IPv4 {
+		names := slices.DeleteFunc(debruijn.FormatNames(), func(s string) bool { return s == debruijn.IPv4.String() })
+		log.Printf("unknown -format %q; choose from %s", formatName, strings.Join(names, ", "))
+		return 2
+	}
+	// As with generate, take broken pipes as errors from writes rather than
+	// dying by SIGPIPE, so that a reader going away isn't a failure.
+	signal.Ignore(syscall.SIGPIPE)
+	var r io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	rd := bufio.NewReaderSize(r, 64<<10)
+	var br io.ByteReader = rd
+	if d := format.NewDecoder(rd); d != nil {
+		br = d
+	}
+	w := bufio.NewWriterSize(os.Stdout, 64<<10)
+	n, err := decodeWindows(w, br)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		if errors.Is(err, syscall.EPIPE) {
+			return 0
+		}
+		log.Printf("decoding failed after %d bytes: %v", n, err)
+		return 1
+	}
+	return 0
+}
+
+// decodeWindows writes to w the address of each window of the bytes of r, one
+// per line, until r is exhausted. It returns the number of bytes read from r.
+func decodeWindows(w io.Writer, r io.ByteReader) (int64, error) {
+	var win [4]byte
+	var n int64
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				// Fewer than four bytes remain in the window, so it isn't
+				// an address.
+				err = nil
+			}
+			return n, err
+		}
+		copy(win[:], win[1:])
+		win[3] = b
+		n++
+		if n < 4 {
+			continue
+		}
+		line = netip.AddrFrom4(win).AppendTo(line[:0])
+		line = append(line, '\n')
+		if _, err := w.Write(line); err != nil {
+			return n, err
+		}
+	}
+}
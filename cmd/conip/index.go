@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/netip"
+	"strconv"
+
+	"github.com/zephyrtronium/conip/debruijn"
+)
+
+// index runs the index command and returns the exit status: 0 if every
+// argument converts, 1 if any doesn't, and 2 for bad arguments.
+func index(args []string) int {
+	fs := flag.NewFlagSet("index", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `usage: conip index [flags] addr...
+       conip index -offset [flags] offset...
+
+Print the offset in B(256, 4) of the window of each IPv4 address, one per
+line, or with -offset, the address whose window begins at each offset.
+
+`)
+		fs.PrintDefaults()
+	}
+	offsets := false
+	fs.BoolVar(&offsets, "offset", false, "convert offsets to addresses instead of addresses to offsets")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	status := 0
+	for _, arg := range fs.Args() {
+		if offsets {
+			off, err := strconv.ParseInt(arg, 0, 64)
+			if err != nil {
+				log.Printf("bad offset %q", arg)
+				status = 1
+				continue
+			}
+			addr, err := debruijn.AddrAt(off)
+			if err != nil {
+				log.Printf("bad offset %q: %v", arg, err)
+				status = 1
+				continue
+			}
+			fmt.Println(addr)
+			continue
+		}
+		addr, err := netip.ParseAddr(arg)
+		if err != nil || !addr.Unmap().Is4() {
+			log.Printf("bad address %q: must be an IPv4 address", arg)
+			status = 1
+			continue
+		}
+		fmt.Println(debruijn.Rank(addr))
+	}
+	return status
+}
@@ -0,0 +1,3382 @@
+// conip prints a minimal-size string containing every IPv4 address.
+//
+// The particular sequence printed is a de Bruijn sequence B(256, 4) beginning
+// with four zeros. With the default text output, the alphabet is the set
+// {"0", "1", "2", ..., "255"}. A "." or newline character separates each
+// sequence term. The output is around 14.2 GiB.
+//
+// The output described here is that of the generate command, which is the
+// default when no command is given. The size command prints the size of the
+// output for the same flags, the verify command checks that a binary stream
+// contains every window exactly once, the index command converts between
+// addresses and their offsets in the sequence, the decode command prints the
+// address of each window of a binary stream, and the serve command serves the
+// sequence over HTTP on the address given by -http, :8080 by default, with
+// Range requests for the binary output. Run conip help for the list
+// and conip command -h for each command's flags. Every command exits with
+// status 0 on success, 1 on failure, and 2 for unusable arguments, and
+// generate and size also with the statuses for output errors and signals
+// described at the end.
+//
+// -format name selects the encoding of the output: bin for binary, dot for
+// the default text, lines for text with a term per line, quad for -ipv4, hex
+// and hexlines for -hex, or padded and paddedlines for -pad, the latter of each
+// pair with a term per line. The older -bin and -n are kept as deprecated
+// aliases for -format bin and -format lines, where -n with -hex or -pad picks
+// the line-per-term form of that format instead.
+//
+// With binary output, the alphabet is the set {0, 1, 2, ..., 255}, and each
+// term is written as a single byte with no separating characters. The output
+// is exactly 4 GiB plus three bytes.
+//
+// With -order n, the sequence is instead B(256, n), containing every string of
+// n terms. Its binary output is exactly 256^n + n - 1 bytes, and its text
+// output is exactly 658·256^(n-1) + 256^n + 2n - 3 bytes.
+//
+// The last n - 1 terms of the output repeat the first ones so that every
+// window of the cycle appears in the linear string, including those spanning
+// the end of the cycle. With -nowrap, conip omits them, writing exactly the
+// 256^n terms of the cycle. That loses the n - 1 windows across the seam, for
+// order 4 by default 255.255.255.0, 255.255.0.0, and 255.0.0.0, which appear
+// only if the output is read as a cycle.
+//
+// With -alphabet k, the sequence is instead B(k, n), whose terms run from 0 to
+// k-1. Alphabets above 256 require binary output, in which each term is two
+// bytes in big-endian order, or little-endian with -endian little. The sizes
+// involved are enormous: -alphabet 65536 -order 2, which contains every pair
+// of 16-bit values, is 8 GiB plus two bytes, and order 3 is 512 TiB.
+//
+// With -sep s, text output separates terms by the string s instead, e.g.
+// -sep ", " for comma-separated values. Backslash escapes are interpreted as
+// in Go strings, so -sep '\t' separates by tabs and -sep '\r\n' by CRLF line
+// endings; -n is short for -sep '\n', and -crlf for -sep '\r\n'. An empty
+// separator concatenates the digits of the terms, which is compact but can't
+// be read back.
+//
+// With -json, the terms are written as a JSON array of integers, separated by
+// commas between brackets, for programs which ingest JSON. The array is
+// written as it's generated, so it is never held in memory.
+//
+// With -count, conip generates the sequence without formatting or writing
+// it, prints the number of terms and the number of bytes they would take in
+// the chosen format, and exits with status 1 if either differs from what the
+// mathematics says it should be: 2^32 + 3 terms for B(256, 4). It is a quick
+// self-check of a build.
+//
+// With -wrap N, text output separated by "." ends a line after every N terms,
+// following the separator, so the output can be read by tools which work line
+// by line. Removing the newlines gives the output without -wrap.
+//
+// With -hex, text output writes each term as two lowercase hexadecimal digits,
+// from 00 to ff, instead of in decimal. Its output is exactly 3·256^n + 3n - 4
+// bytes, or 12 GiB plus eight bytes for order 4.
+//
+// With -pad, text output zero-pads each decimal term to three digits, from
+// 000 to 255, so that every term takes four bytes with its separator, the
+// term at index i begins at byte 4i, and the output is exactly 4·256^n + 4n - 5
+// bytes, or 16 GiB plus eleven bytes for order 4.
+//
+// With -encoding name, terms are formatted by the named encoder from the
+// debruijn package's registry, which holds an encoder for each -format but
+// quad, under the same name and equivalent to it, and bin also as binary.
+//
+// With -ipv4, the output is instead every IPv4 address in dotted-quad form, one
+// per line, in the order in which each address's window of four terms
+// completes in the sequence. This produces about 4.3 billion lines and is
+// around 57.1 GiB. Adding -exclude with a comma-separated list of CIDR
+// prefixes, such as -exclude 10.0.0.0/8,127.0.0.0/8, omits the addresses in
+// those prefixes. Adding -prefix with one CIDR prefix, such as -prefix
+// 192.168.0.0/16, writes only the addresses in it, still in the order of the
+// sequence, for a bounded slice of real addresses. For prefixes of at least 12
+// bits, conip finds where each address falls in the sequence directly;
+// shorter ones take a scan through the sequence.
+//
+// With -cidr N as well, each address is written in CIDR notation with the
+// prefix length N, from 0 to 32, such as 10.1.2.3/24. The host bits are left
+// as they are.
+//
+// With -shuffle N as well, the addresses are instead written in a
+// pseudorandom order derived from the seed N, for testing consumers that must
+// not depend on the order. It is a keyed permutation of the 32-bit values, so
+// it still produces every address exactly once, using no more memory than the
+// usual order. It only applies to -ipv4 output.
+//
+// With -start-addr a.b.c.d, the output is a rotation of the cycle beginning
+// with the window of that address. In place of the usual wrap-around terms, it
+// ends with the terms that precede the address in the cycle, then the first
+// three terms of the address again, so it is just as long and still contains
+// every address. With -ipv4, the first line is that address.
+//
+// With -start a,b,c,d, the output is likewise a rotation of the cycle, but
+// beginning with a particular Lyndon word of the sequence, given as four bytes
+// which repeat the word. For example, -start 0,1,0,1 begins with the word 0,1.
+// The bytes must be the least of their rotations.
+//
+// With -reverse, the output is the sequence in reverse, which is also a de
+// Bruijn sequence. It begins with the three wrap-around zeros, and in -ipv4
+// mode, the first address is 0.0.0.255, followed by 0.0.255.255.
+//
+// With -xor m, each term is XORed with the byte m, given in hex like 0xff or in
+// decimal, before it is written, in every mode. The result is still a de
+// Bruijn sequence containing every address, in a different order; -xor 0xff
+// produces the complement of the sequence.
+//
+// With -permute-seed N, each term is instead substituted through a
+// pseudorandom permutation of the bytes derived from the seed N, after any
+// -xor mask. Like -xor, it preserves coverage while scrambling the order of
+// the addresses. The same seed always gives the same output; -permute-seed
+// random picks a seed, and -print-seed prints the seed to stderr so that the
+// run can be reproduced.
+//
+// With -limit N, conip stops after N terms, or N addresses with -ipv4, so
+// that the output is a prefix of the full output. With -limit-bytes N, it
+// stops after as many whole terms or addresses as fit in N bytes, never
+// cutting one in half. In binary, a term is a byte, so -limit N writes N
+// bytes. The output never repeats the cycle: with N past the cycle length,
+// such as 2^32 for the default order, the output goes on into the order-1
+// terms which close it, and any N beyond them writes the whole output. With
+// -nowrap, those terms aren't part of the output, so the cycle length is
+// the most -limit can write.
+//
+// With -duration D, such as -duration 60s, conip writes as much of the output
+// as it can in that time, then stops between two terms, flushes what it has,
+// and prints the byte offset to continue from with -resume and the index of
+// the next term, exiting successfully. -stop-after T does the same at the time
+// T, given in RFC 3339 format. Either combines with -limit, stopping at
+// whichever comes first.
+//
+// With -skip N, output begins at the term with index N, or with -ipv4, the
+// address whose window begins at offset N, without a separator before it. For
+// order 4, generation jumps directly to it. Together with -limit, this writes
+// any slice of the output, and the text of consecutive slices joined with a
+// separator is the full output.
+//
+// With -from a.b.c.d and -until e.f.g.h, the output is the slice of the full
+// output from the first term of the window of a.b.c.d through the last term of
+// the window of e.f.g.h, or with -ipv4, the addresses from a.b.c.d through
+// e.f.g.h. Either may be given alone to start at the beginning or to run to
+// the end. It is an error for the -from window to come after the -until
+// window.
+//
+// With -head N, conip stops after exactly N bytes of output, the same as
+// piping it through head -c N: it may end in the middle of a term. Use
+// -limit-bytes instead to end on a whole term.
+//
+// With -resume N, output starts at byte N of what it would otherwise be, so
+// that appending it to the first N bytes of an interrupted run produces the
+// full output. In binary mode, generation jumps directly to the term at index
+// N. In text and -ipv4 modes, where a term's byte offset depends on the terms
+// before it, the generator instead runs from the start and suppresses the
+// first N bytes, so if byte N falls in the middle of a term, output begins
+// with the rest of that term.
+//
+// On SIGINT or SIGTERM, such as from Ctrl-C, conip stops generating, flushes
+// and closes the output with everything generated so far, and prints the
+// offset to continue from with -resume, exiting with status 130 for SIGINT or
+// 143 for SIGTERM. A second signal exits immediately, with the same status.
+// With -shards, -workers, or -mmap, which write in place, the signal instead
+// ends conip at once, again with that status.
+//
+// With -append and -o name, conip resumes at the end of an existing file
+// instead, appending the rest of the output to it. If the file ends in the
+// middle of a term, the append begins with the rest of that term, so the
+// result is the same as an uninterrupted run. Before appending anything, it
+// compares the last 4096 bytes of the file, or the number given by
+// -append-check, with the output there, and exits with an error if they
+// differ, such as when the flags don't match those of the first run. With
+// -resume N as well, it continues from byte N instead of the end of the file,
+// cutting off whatever follows byte N once the bytes before it have matched,
+// as when only the first N bytes of a copy are known to be good.
+//
+// -o - means stdout, as no -o does; a file named - is ./-. Binary output to a
+// terminal, whether stdout or a -o file like /dev/tty, is refused as bad
+// arguments unless -force is given, since the raw bytes are of no use on a
+// screen and can garble it.
+//
+// With -no-clobber, conip refuses to overwrite an existing -o file, exiting
+// with status 3 instead, whether it finds the file before writing or only once
+// it comes to create or rename the output. With -atomic, it writes the output
+// to a temporary file in the same directory and renames it to the -o name only
+// once the output is complete, removing the temporary file if it fails or is
+// interrupted, so the name never refers to partial output.
+//
+// With -fsync, conip syncs the -o file to storage before closing it, and with
+// -atomic, syncs the directory after renaming the file into place, so that
+// finished output survives a power loss. If syncing fails, it exits with an
+// error noting how many bytes it wrote.
+//
+// With -mmap, -bin, and -o name, conip sizes the file in advance, maps it into
+// memory, and generates the terms directly into the mapping, avoiding the
+// copies and system calls of ordinary writes. It syncs the mapping to the file
+// once done. Where mapping isn't available, it writes normally.
+//
+// With -gzip, the output is compressed with gzip as it is generated. Text
+// output compresses to roughly a sixth of its size. Binary output does not
+// compress at all: every run of four bytes appears exactly once, and deflate
+// only matches runs at least that long. Offsets for -resume and sizes reported
+// by -size refer to the uncompressed output.
+//
+// With -zstd, the output is compressed with zstd instead, which is faster than
+// gzip and makes text output about a fifth the size gzip does. With either,
+// -level sets the compression level: 1 to 9 for gzip and 1 to 22 for zstd.
+//
+// With -size, conip prints the exact number of bytes it would write with the
+// other options given and exits without generating anything. The size
+// accounts for every mode and for the alphabet, order, rotation, and
+// substitution, since text sizes depend on which terms wrap around the cycle
+// and on how many digits each substituted term takes.
+//
+// With -dry-run, conip prints the size of the output as -size does when it
+// can, the number of terms in the sequence, the terms or windows each shard
+// would hold, and an estimate of the time the run would take, measured by
+// generating a short burst of the output and discarding it. It writes nothing
+// to the output.
+//
+// With -shards N and -o name, the output is split into N files named name.000,
+// name.001, and so on. Each holds an equal share of the terms, or of the
+// addresses with -ipv4, and is generated independently. Concatenating the
+// shards in order, e.g. with cat name.* > name, reassembles the full output.
+// With -gzip or -zstd, each shard is compressed separately, and the
+// concatenated shards still decompress to the full output.
+//
+// With -tee and -o name, the output is written to stdout as well as the file.
+// If the reader of stdout goes away, conip keeps writing the file, so that
+// e.g. -tee -o name | head shows the start of the output while saving all of
+// it. Any other error writing either one stops the run.
+//
+// With -addr host:port, the output is sent over a TCP connection instead of
+// to a file. If the connection fails, conip exits with an error that notes how
+// far it got. Since some of the bytes sent might not have been received,
+// continue with -resume set to the number of bytes the receiver did get.
+//
+// With -rate N, conip writes at most N bytes per second on average, counting
+// the bytes after compression, so as not to flood a slow link or consumer.
+// N may have a unit, binary like 10MiB/s or decimal like 500KB/s; the /s is
+// optional. Output goes out in bursts of up to the buffer size set by -buf.
+//
+// With -sha256, conip prints the SHA-256 digest of the output to stderr once
+// it finishes, in the format of sha256sum. With -gzip or -zstd, the digest is
+// of the uncompressed output.
+//
+// With -print-hash alg, conip generates the output without writing it, as
+// -discard does, and prints to stdout its digest by the algorithm alg, one of
+// sha256, sha512, or blake2b (BLAKE2b-512), followed by its size in bytes, to
+// check a downloaded copy without storing another. With -limit, the digest is
+// of the prefix alone, for a quick check of the start of a copy.
+//
+// With -crc-interval N, such as -crc-interval 1GiB, conip prints the CRC-32
+// (IEEE) of the output so far to stderr after every N bytes and once more at
+// the end, so that a copy whose checksums are computed the same way shows
+// which stretch of N bytes first differs. As with -sha256, the checksums are
+// of the uncompressed output. After -resume, they begin at the resume offset.
+//
+// With -check, conip reads the file named by -o back once it's written and
+// checks it: the complete binary output as the verify command does, and any
+// other output by comparing it with the output generated again, after
+// decompressing it for -gzip or -zstd. It logs the result, with the offset at
+// which the file first differs if it does, and then exits with status 1.
+// With -progress, the check shows its own progress.
+//
+// With -progress, conip shows on stderr the number of bytes written so far,
+// along with the percentage of the total, the current rate, and an estimate of
+// the time remaining when the total is known. It redraws a single line a few
+// times a second if stderr is a terminal and does nothing otherwise.
+// -progress=always logs a line once a second in that case instead, and
+// -progress=never, the default, turns it off.
+//
+// With -progress-json dest, conip writes progress for other programs to the
+// file named dest, or to the open file descriptor if dest is a number, as one
+// JSON object per line, like {"bytes":N,"total":M,"terms":K,"elapsed_ms":T},
+// every -progress-interval, one second by default. A total of -1 means the
+// size of the output isn't known. The last event adds "done":true and the
+// exit status, with "error" or "signal" if the run failed or was interrupted,
+// and is written however the run ends, including by -limit or a signal.
+//
+// With -discard, conip generates the output without writing it anywhere and
+// logs its size and the rate at which it was generated, to measure generation
+// and encoding apart from storage. Binary output then skips the buffer too.
+//
+// With -cpuprofile name or -memprofile name, conip writes a CPU profile or an
+// allocation profile to the named file when it exits, for go tool pprof, as
+// the flags of the same names do for go test. The profiles are written however
+// the run ends, whether by finishing, -limit, an error, or a signal.
+//
+// Once the output is complete, conip logs the number of bytes it wrote and how
+// long that took. With -q, it logs nothing but errors. With -v or -verbose, it
+// also logs each time the output reaches the block of Lyndon words beginning
+// with the next term, along with the offset, and at the end, the number of
+// Lyndon words of each length emitted.
+//
+// If the reader of the output goes away early, as when piping to head, conip
+// stops and exits with status 0 rather than reporting an error. Otherwise, a
+// failure prints one line to stderr, noting how many bytes of output were
+// written if any were, and conip exits with status 2 for bad arguments, 3 for
+// an error opening, writing, or syncing the output or refusing to overwrite it
+// with -no-clobber, 130 or 143 when stopped by SIGINT or SIGTERM, or 1 for
+// anything else.
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"iter"
+	"log"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/term"
+
+	"github.com/zephyrtronium/conip/debruijn"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		if !errors.As(err, new(exitStatus)) {
+			log.Print(err)
+		}
+		os.Exit(statusOf(err))
+	}
+}
+
+// statusOf returns the status with which to exit after run returns err.
+func statusOf(err error) int {
+	var status exitStatus
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &status):
+		return int(status)
+	case errors.As(err, new(usageError)):
+		return 2
+	case errors.As(err, new(outputError)):
+		return 3
+	}
+	return 1
+}
+
+// run runs the command given by args, the arguments after the program name.
+func run(args []string) error {
+	cmd := "generate"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "generate", "size":
+		return generate(cmd, args)
+	case "verify":
+		return exitCode(verify(args))
+	case "index":
+		return exitCode(index(args))
+	case "decode":
+		return exitCode(decode(args))
+	case "serve":
+		return exitCode(serve(args))
+	case "help":
+		usage(os.Stdout)
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, "conip: unknown command %q\n\n", cmd)
+		usage(os.Stderr)
+		return exitStatus(2)
+	}
+}
+
+// exitStatus is an error which carries only the status with which to exit,
+// for failures which have already been reported.
+type exitStatus int
+
+func (s exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
+}
+
+// usageError is an error in the arguments to a command, for which conip exits
+// with status 2.
+type usageError struct{ error }
+
+func (e usageError) Unwrap() error {
+	return e.error
+}
+
+// outputError is an error writing the output, for which conip exits with
+// status 3. It notes how many bytes of output came before it, since not all
+// of them might have reached their destination.
+type outputError struct {
+	off int64
+	err error
+}
+
+func (e outputError) Error() string {
+	if e.off == 0 {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("writing output failed after %d bytes: %v", e.off, e.err)
+}
+
+func (e outputError) Unwrap() error {
+	return e.err
+}
+
+// exitCode returns the error for a command's exit status, or nil for 0.
+func exitCode(status int) error {
+	if status == 0 {
+		return nil
+	}
+	return exitStatus(status)
+}
+
+// usage writes the list of commands to w.
+func usage(w io.Writer) {
+	fmt.Fprint(w, `usage: conip [command] [flags] [args]
+
+Commands:
+  generate  write the sequence (the default when no command is given)
+  size      print the size of the output generate would write
+  verify    check that a binary stream contains every window exactly once
+  index     convert between IPv4 addresses and their offsets in the sequence
+  decode    print the address of each window of a binary stream
+  serve     serve the sequence over HTTP
+  help      print this message
+
+Run conip command -h for the flags of each command.
+`)
+}
+
+// generate runs the generate command, or the size command if name is "size",
+// which takes the same flags but only prints the size of the output.
+func generate(name string, args []string) error {
+	f, err := parseGenerateFlags(name, args)
+	if f == nil {
+		return err
+	}
+	// Handle broken pipes as errors from writes rather than dying by SIGPIPE,
+	// so that the progress reporter and buffers are shut down in order.
+	signal.Ignore(syscall.SIGPIPE)
+	if err := f.validate(); err != nil {
+		return err
+	}
+	j, err := newJob(f)
+	if err != nil {
+		return err
+	}
+	if j.cpuProfile != "" || j.memProfile != "" {
+		stop, err := startProfiles(j.cpuProfile, j.memProfile)
+		if err != nil {
+			return err
+		}
+		// Write the profiles however the run ends.
+		atSignal(func(os.Signal) { stop() })
+		defer stop()
+	}
+	switch {
+	case j.size:
+		n, err := j.outputSize()
+		if err != nil {
+			return err
+		}
+		fmt.Println(max(n-j.skip-j.resume, 0))
+		return nil
+	case j.dryRun:
+		return j.estimate()
+	case j.countOnly:
+		return j.countOutput()
+	}
+	return j.write()
+}
+
+// generateFlags holds the flags of the generate and size commands as they are
+// given, but for -o - and the size command, which parseGenerateFlags takes as
+// no -o and -size.
+type generateFlags struct {
+	formatName        string
+	bin, nl, hex, pad bool
+	ipv4              bool
+	encoding          string
+	separator         string
+	crlf, jsonOut     bool
+	noWrap            bool
+	wrap              int64
+	alphabet, order   int
+	endian            string
+	xor, permuteSeed  string
+	printSeed         bool
+	rev               bool
+	start, startAddr  string
+	cidr              int
+	shuffle           string
+	exclude, within   string
+	head              int64
+	duration          time.Duration
+	stopAfter         string
+	limit, limitBytes int64
+	skip              int64
+	from, until       string
+	resume            int64
+	buf               int
+	o                 string
+	force             bool
+	mmapOut           bool
+	noClobber         bool
+	atomicOut         bool
+	discard           bool
+	fsync             bool
+	appendOut         bool
+	appendCheck       int64
+	tee               bool
+	rate              int64
+	addr              string
+	workers, shards   int
+	sha               bool
+	printHash         string
+	crcInterval       int64
+	check             bool
+	countOnly, dryRun bool
+	size              bool
+	gz, zst           bool
+	level             int
+	progress          progressMode
+	verbose, quiet    bool
+	progressJSON      string
+	progressInterval  time.Duration
+	cpuProfile        string
+	memProfile        string
+	// sepSet and bufSet are whether -sep and -buf were given at all.
+	sepSet, bufSet bool
+}
+
+// parseGenerateFlags parses args as the flags of the generate command, or the
+// size command if name is "size". It returns nil and the error for the
+// command to return if they can't be parsed or ask for help.
+func parseGenerateFlags(name string, args []string) (*generateFlags, error) {
+	f := &generateFlags{buf: 4096, progress: "never"}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		if name == "size" {
+			fmt.Fprint(fs.Output(), "usage: conip size [flags]\n\nPrint the exact size in bytes of the output that generate would write with\nthe same flags, without generating it.\n\n")
+		} else {
+			fmt.Fprint(fs.Output(), "usage: conip [generate] [flags]\n\nWrite a de Bruijn sequence containing every IPv4 address.\n\n")
+		}
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&f.formatName, "format", "", "encoding of the output: "+strings.Join(debruijn.FormatNames(), ", ")+"; dot if empty")
+	fs.BoolVar(&f.bin, "bin", false, "deprecated: the same as -format bin")
+	fs.BoolVar(&f.nl, "n", false, "deprecated: the same as -format lines, or with -hex or -pad, -format hexlines or paddedlines")
+	fs.StringVar(&f.separator, "sep", "", "in text mode, separate terms by this string instead of ., interpreting escapes like \\t and \\r\\n")
+	fs.BoolVar(&f.crlf, "crlf", false, "in text mode, separate terms by CRLF line endings for Windows tools; short for -sep '\\r\\n'")
+	fs.BoolVar(&f.noWrap, "nowrap", false, "omit the last order-1 terms, which repeat the first ones to close the cycle, writing the cyclic sequence instead of the linear one")
+	fs.BoolVar(&f.jsonOut, "json", false, "write the terms as a JSON array of integers, like [0,0,0,0,1,...]")
+	fs.Int64Var(&f.wrap, "wrap", 0, "in text mode with . separators, end a line after every this many terms; 0 for one line")
+	fs.BoolVar(&f.hex, "hex", false, "in text mode, write terms as two hexadecimal digits instead of decimal")
+	fs.BoolVar(&f.pad, "pad", false, "in text mode, zero-pad each decimal term to three digits, like 007, so every term has the same width")
+	fs.StringVar(&f.encoding, "encoding", "", "format terms with the named encoder instead: "+strings.Join(debruijn.EncoderNames(), ", "))
+	fs.Var((*byteSize)(&f.buf), "buf", "output buffer size, with a unit like 64KiB or 4M if desired; with -o, 1 MiB or more by default, depending on the file system's block size")
+	fs.StringVar(&f.o, "o", "", "output file name; stdout if empty or -")
+	fs.BoolVar(&f.force, "force", false, "write binary output even to a terminal")
+	fs.BoolVar(&f.mmapOut, "mmap", false, "with -bin and -o, generate directly into the file mapped into memory")
+	fs.BoolVar(&f.noClobber, "no-clobber", false, "with -o, fail rather than overwrite an existing file")
+	fs.BoolVar(&f.atomicOut, "atomic", false, "with -o, write to a temporary file and rename it into place only once the output is complete")
+	fs.BoolVar(&f.discard, "discard", false, "generate the output without writing it anywhere, then print its size and the rate at which it was generated")
+	fs.BoolVar(&f.fsync, "fsync", false, "with -o, sync the output to storage before exiting, and with -atomic, the directory holding it")
+	fs.BoolVar(&f.appendOut, "append", false, "with -o, continue an interrupted run by appending to the file what follows its contents")
+	fs.Int64Var(&f.appendCheck, "append-check", 4096, "with -append, number of bytes at the end of the file which must match the output before appending")
+	fs.BoolVar(&f.tee, "tee", false, "with -o, also write the output to stdout")
+	fs.Var((*byteRate)(&f.rate), "rate", "limit the output to this many bytes per second, after compression, with a unit like 500KB/s or 10MiB/s; 0 for no limit")
+	fs.StringVar(&f.addr, "addr", "", "send the output over a TCP connection to this host:port instead of to a file")
+	fs.IntVar(&f.alphabet, "alphabet", 256, "number of distinct terms, up to 65536; above 256, requires -bin and writes each term as two big-endian bytes")
+	fs.StringVar(&f.endian, "endian", "big", "byte order of terms wider than a byte, as with -alphabet above 256: big or little")
+	fs.IntVar(&f.order, "order", 4, "order of the de Bruijn sequence, the length of the strings it contains")
+	fs.BoolVar(&f.ipv4, "ipv4", false, "output each IPv4 address in dotted-quad form, one per line, instead of terms")
+	fs.StringVar(&f.xor, "xor", "", "XOR each term with this byte, in hex like 0xff or decimal, reordering the output while still containing every address")
+	fs.StringVar(&f.permuteSeed, "permute-seed", "", "substitute each term through a pseudorandom permutation of the bytes derived from this seed, or from a random seed if \"random\"")
+	fs.BoolVar(&f.printSeed, "print-seed", false, "print the seed used for -permute-seed to stderr")
+	fs.BoolVar(&f.rev, "reverse", false, "output the sequence in reverse, which also contains every address")
+	fs.StringVar(&f.start, "start", "", "begin with this Lyndon word, given as four comma-separated bytes repeating the word, e.g. 0,1,0,1 for the word 0,1")
+	fs.StringVar(&f.startAddr, "start-addr", "", "begin with the window of this IPv4 address, rotating the cycle so the output still contains every address")
+	fs.IntVar(&f.cidr, "cidr", -1, "with -ipv4, write each address with this prefix length in CIDR notation, like 10.1.2.3/24")
+	fs.StringVar(&f.shuffle, "shuffle", "", "with -ipv4, write every address once in a pseudorandom order derived from this seed instead of the order of the sequence")
+	fs.StringVar(&f.exclude, "exclude", "", "with -ipv4, comma-separated list of CIDR prefixes whose addresses to omit")
+	fs.StringVar(&f.within, "prefix", "", "with -ipv4, write only the addresses in this CIDR prefix, in the order of the sequence")
+	fs.Int64Var(&f.head, "head", -1, "stop after exactly this many bytes, even in the middle of a term; no limit if negative")
+	fs.DurationVar(&f.duration, "duration", 0, "stop cleanly after this long, like 60s, at a term boundary, printing where to resume; 0 for no limit")
+	fs.StringVar(&f.stopAfter, "stop-after", "", "stop cleanly at this time in RFC 3339 format, like 2006-01-02T15:04:05Z, as -duration does")
+	fs.Int64Var(&f.limit, "limit", -1, "stop after this many terms, or addresses with -ipv4; no limit if negative")
+	fs.Int64Var(&f.limitBytes, "limit-bytes", -1, "stop after at most this many bytes, without cutting a term or address in half; no limit if negative")
+	fs.Int64Var(&f.skip, "skip", 0, "begin output at the term with this index, or the window at this offset with -ipv4, jumping there directly for order 4")
+	fs.StringVar(&f.from, "from", "", "begin output with the window of this IPv4 address")
+	fs.StringVar(&f.until, "until", "", "end output with the window of this IPv4 address")
+	fs.Int64Var(&f.resume, "resume", 0, "start output at this byte offset, e.g. the size of an interrupted run's output")
+	fs.IntVar(&f.workers, "workers", 1, "number of goroutines generating binary output; more than 1 requires -bin and -o")
+	fs.IntVar(&f.shards, "shards", 1, "split the output into this many files named by -o with a suffix like .000")
+	fs.BoolVar(&f.sha, "sha256", false, "print the SHA-256 digest of the uncompressed output to stderr when finished")
+	fs.StringVar(&f.printHash, "print-hash", "", "generate the output without writing it, and print its digest by this algorithm and its size to stdout: "+strings.Join(slices.Sorted(maps.Keys(hashes)), ", "))
+	fs.Var((*byteCount)(&f.crcInterval), "crc-interval", "print the CRC-32 of the uncompressed output so far to stderr after every this many bytes, with a unit like 1GiB if desired, and at the end")
+	fs.BoolVar(&f.check, "check", false, "with -o, read the file back once it's written and check that it holds the output, exiting with status 1 if it doesn't")
+	fs.BoolVar(&f.countOnly, "count", false, "generate the sequence without writing it, print the number of terms and bytes of output, and exit with an error if they are not as expected")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "print the size of the output, its terms and shards, and an estimate of the time to write it, without writing anything")
+	fs.BoolVar(&f.size, "size", false, "print the exact size of the output in bytes and exit without generating it")
+	fs.BoolVar(&f.gz, "gzip", false, "compress the output with gzip")
+	fs.BoolVar(&f.zst, "zstd", false, "compress the output with zstd, which is much faster than gzip")
+	fs.IntVar(&f.level, "level", 0, "compression level for -gzip, from 1 to 9, or -zstd, from 1 to 22; 0 for the default")
+	fs.Var(&f.progress, "progress", "report the amount of output written on stderr by `mode`: always, never, or auto, only if stderr is a terminal; -progress alone means auto")
+	fs.BoolVar(&f.verbose, "verbose", false, "log the offset at which the output reaches each block of Lyndon words beginning with a new term, and the number of Lyndon words of each length emitted")
+	fs.BoolVar(&f.verbose, "v", false, "short for -verbose")
+	fs.StringVar(&f.progressJSON, "progress-json", "", "write progress as newline-delimited JSON events to this file descriptor number or file, ending with a summary with the exit status")
+	fs.DurationVar(&f.progressInterval, "progress-interval", time.Second, "with -progress-json, time between events")
+	fs.StringVar(&f.cpuProfile, "cpuprofile", "", "write a CPU profile to this file, as go test does")
+	fs.StringVar(&f.memProfile, "memprofile", "", "write an allocation profile to this file on exit, as go test does")
+	fs.BoolVar(&f.quiet, "q", false, "log nothing but errors, not even the summary of the bytes written")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, nil
+		}
+		return nil, exitStatus(2)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return nil, exitStatus(2)
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		f.sepSet = f.sepSet || fl.Name == "sep"
+		f.bufSet = f.bufSet || fl.Name == "buf"
+	})
+	if name == "size" {
+		f.size = true
+	}
+	if f.o == "-" {
+		// A file named - is still there as ./-.
+		f.o = ""
+	}
+	return f, nil
+}
+
+// outputFormat returns the format of the output which the flags select and
+// whether its size is known, which it isn't for encoders other than the
+// built-in ones.
+func (f *generateFlags) outputFormat() (debruijn.Format, bool, error) {
+	switch {
+	case f.formatName != "":
+		format, err := debruijn.ParseFormat(f.formatName)
+		if err != nil {
+			return 0, false, usageError{fmt.Errorf("unknown -format %q; choose from %s", f.formatName, strings.Join(debruijn.FormatNames(), ", "))}
+		}
+		return format, true, nil
+	case f.encoding != "":
+		e := debruijn.LookupEncoder(f.encoding)
+		if e == nil {
+			return 0, false, usageError{fmt.Errorf("unknown -encoding %q; choose from %s", f.encoding, strings.Join(debruijn.EncoderNames(), ", "))}
+		}
+		format, sized := encodingFormat(e)
+		return format, sized, nil
+	case f.ipv4:
+		return debruijn.IPv4, true, nil
+	case f.bin:
+		return debruijn.Binary, true, nil
+	case f.hex && f.nl:
+		return debruijn.HexLines, true, nil
+	case f.hex:
+		return debruijn.HexDot, true, nil
+	case f.pad && f.nl:
+		return debruijn.PaddedLines, true, nil
+	case f.pad:
+		return debruijn.PaddedDot, true, nil
+	case f.nl:
+		return debruijn.Lines, true, nil
+	}
+	return debruijn.Dot, true, nil
+}
+
+// showProgress reports whether to show progress on stderr for -progress.
+func (f *generateFlags) showProgress() bool {
+	return f.progress == "always" || f.progress == "auto" && isTerminal(os.Stderr)
+}
+
+// validate returns a usageError for the first combination of the flags which
+// conip can't write, or nil if there is none. The rules are those for the
+// flags themselves, such as those which stand for the same option and those
+// for where the output goes; Options.Validate has the rules of the library
+// for the options they set, which newJob checks once it has parsed them.
+func (f *generateFlags) validate() error {
+	if f.formatName != "" && (f.bin || f.nl || f.hex || f.pad || f.ipv4 || f.encoding != "") {
+		return usageError{errors.New("-format cannot be used with -bin, -n, -hex, -pad, -ipv4, or -encoding")}
+	}
+	if f.encoding != "" && (f.bin || f.nl || f.hex || f.pad || f.ipv4) {
+		return usageError{errors.New("-encoding cannot be used with -bin, -n, -hex, -pad, or -ipv4")}
+	}
+	format, sized, err := f.outputFormat()
+	if err != nil {
+		return err
+	}
+	// The rest of the rules are in terms of the older flags, which -format
+	// stands for and -encoding doesn't.
+	is := func(formats ...debruijn.Format) bool {
+		return f.encoding == "" && slices.Contains(formats, format)
+	}
+	bin, ipv4 := is(debruijn.Binary), is(debruijn.IPv4)
+	hex, pad := is(debruijn.HexDot, debruijn.HexLines), is(debruijn.PaddedDot, debruijn.PaddedLines)
+	nl := is(debruijn.Lines, debruijn.HexLines, debruijn.PaddedLines)
+	compress := f.gz || f.zst
+	// Options takes an order or alphabet of 0 for the default, which on the
+	// command line is what the flags default to instead.
+	if f.order < 1 {
+		return usageError{fmt.Errorf("order must be at least 1, got %d", f.order)}
+	}
+	if f.alphabet < 1 {
+		return usageError{fmt.Errorf("alphabet size must be at least 1, got %d", f.alphabet)}
+	}
+	if f.resume < 0 {
+		return usageError{fmt.Errorf("resume offset must not be negative, got %d", f.resume)}
+	}
+	if f.alphabet != 256 && (f.workers > 1 || f.shards > 1 || f.startAddr != "" || f.start != "") {
+		return usageError{errors.New("-alphabet other than 256 cannot be used with -workers, -shards, -start, or -start-addr")}
+	}
+	if f.noWrap && (ipv4 || f.alphabet > 256 || f.shards > 1 || f.workers > 1 || f.countOnly) {
+		return usageError{errors.New("-nowrap cannot be used with -ipv4, -alphabet above 256, -shards, -workers, or -count")}
+	}
+	if f.bin && f.ipv4 {
+		return usageError{errors.New("-bin and -ipv4 are different output modes; choose one")}
+	}
+	if f.hex && (f.bin || f.ipv4) {
+		return usageError{errors.New("-hex cannot be used with -bin or -ipv4")}
+	}
+	if f.nl && (f.bin || f.ipv4) {
+		return usageError{errors.New("-n cannot be used with -bin or -ipv4")}
+	}
+	if f.pad && (f.bin || f.ipv4 || f.hex) {
+		return usageError{errors.New("-pad cannot be used with -bin, -ipv4, or -hex")}
+	}
+	if f.crlf && (f.sepSet || nl) {
+		return usageError{errors.New("-crlf cannot be used with -sep or -n")}
+	}
+	if f.jsonOut {
+		// JSON numbers are in decimal, so the separator and brackets make
+		// them an array only in the dot format.
+		if f.sepSet || f.crlf || nl || bin || hex || pad || ipv4 || f.encoding != "" {
+			return usageError{errors.New("-json cannot be used with -sep, -crlf, -n, -bin, -hex, -pad, -ipv4, or -encoding")}
+		}
+		// The brackets around the array make offsets into the output
+		// differ from those into the terms.
+		if f.resume != 0 || f.appendOut || f.head >= 0 || f.limitBytes >= 0 || f.shards > 1 || f.countOnly {
+			return usageError{errors.New("-json cannot be used with -resume, -append, -head, -limit-bytes, -shards, or -count")}
+		}
+	}
+	if f.sepSet && nl {
+		return usageError{errors.New("-sep cannot be used with -n")}
+	}
+	// For no separator at all, newJob builds an encoder of the format, which
+	// only the text formats have.
+	if f.sepSet && f.separator == "" && !is(debruijn.Dot, debruijn.HexDot, debruijn.PaddedDot) {
+		return usageError{errors.New("an empty -sep requires a text format and cannot be used with -encoding")}
+	}
+	if f.exclude != "" && f.size {
+		return usageError{errors.New("-size cannot account for -exclude")}
+	}
+	if f.within != "" && (f.from != "" || f.until != "" || f.shards > 1 || f.limitBytes >= 0 || f.size || f.countOnly) {
+		return usageError{errors.New("-prefix cannot be used with -from, -until, -shards, -limit-bytes, -size, or -count")}
+	}
+	if f.startAddr != "" && (f.order != 4 || f.workers > 1 || f.shards > 1) {
+		return usageError{errors.New("-start-addr requires order 4 and cannot be used with -workers or -shards")}
+	}
+	if f.start != "" && (f.startAddr != "" || f.order != 4 || f.workers > 1 || f.shards > 1) {
+		return usageError{errors.New("-start requires order 4 and cannot be used with -start-addr, -workers, or -shards")}
+	}
+	if f.printSeed && f.permuteSeed == "" {
+		return usageError{errors.New("-print-seed requires -permute-seed")}
+	}
+	if f.workers > 1 && (f.xor != "" || f.permuteSeed != "") {
+		return usageError{errors.New("-workers greater than 1 cannot be used with -xor or -permute-seed")}
+	}
+	if f.rev && (f.workers > 1 || f.shards > 1) {
+		return usageError{errors.New("-reverse cannot be used with -workers or -shards")}
+	}
+	if f.gz && f.zst {
+		return usageError{errors.New("-gzip and -zstd cannot be used together")}
+	}
+	if !sized && f.size {
+		return usageError{fmt.Errorf("-size cannot account for -encoding %s", f.encoding)}
+	}
+	// -from and -until set the -skip and -limit of the slice they describe.
+	if (f.from != "" || f.until != "") && (f.skip != 0 || f.limit >= 0 || f.limitBytes >= 0 || f.rev || f.resume != 0 || f.appendOut || f.shards > 1 || f.workers > 1 || f.size || f.countOnly || f.order != 4 || f.alphabet != 256) {
+		return usageError{errors.New("-from and -until require order 4 and cannot be used with -skip, -limit, -limit-bytes, -reverse, -resume, -append, -shards, -workers, -size, or -count")}
+	}
+	if f.skip != 0 && (f.skip < 0 || f.shards > 1 || f.workers > 1 || !sized || (f.size || f.countOnly) && format != debruijn.Binary) {
+		return usageError{errors.New("-skip must not be negative and cannot be used with -shards, -workers, or -encoding other than the built-in ones, nor with -size or -count except in binary")}
+	}
+	if f.wrap < 0 || f.wrap > 0 && f.shards > 1 {
+		return usageError{errors.New("-wrap must not be negative and cannot be used with -shards")}
+	}
+	if f.shuffle != "" && (f.shards > 1 || f.limitBytes >= 0 || f.countOnly || f.from != "" || f.until != "") {
+		return usageError{errors.New("-shuffle cannot be used with -shards, -limit-bytes, -count, -from, or -until")}
+	}
+	if f.appendOut {
+		if f.o == "" || f.limit >= 0 || f.limitBytes >= 0 || f.head >= 0 || f.shards > 1 || f.workers > 1 || f.mmapOut || compress || f.sha || f.size || f.countOnly {
+			return usageError{errors.New("-append requires -o and cannot be used with -limit, -limit-bytes, -head, -shards, -workers, -mmap, -gzip, -zstd, -sha256, -size, or -count")}
+		}
+		if f.appendCheck < 0 {
+			return usageError{fmt.Errorf("-append-check must not be negative, got %d", f.appendCheck)}
+		}
+	}
+	if (f.noClobber || f.atomicOut) && (f.o == "" || f.appendOut) {
+		return usageError{errors.New("-no-clobber and -atomic require -o and cannot be used with -append")}
+	}
+	stops := f.duration != 0 || f.stopAfter != ""
+	if stops && (f.duration < 0 || ipv4 || f.alphabet > 256 || f.wrap > 0 || f.jsonOut || f.shards > 1 || f.workers > 1 || f.mmapOut) {
+		return usageError{errors.New("-duration must not be negative, and -duration and -stop-after cannot be used with -ipv4, -alphabet above 256, -wrap, -json, -shards, -workers, or -mmap")}
+	}
+	if f.check && (f.o == "" || f.shards > 1 || f.workers > 1 || f.mmapOut || stops || f.size || f.countOnly) {
+		return usageError{errors.New("-check requires -o and cannot be used with -shards, -workers, -mmap, -duration, -stop-after, -size, or -count")}
+	}
+	if f.crcInterval > 0 && (f.shards > 1 || f.workers > 1 || f.mmapOut) {
+		return usageError{errors.New("-crc-interval cannot be used with -shards, -workers, or -mmap")}
+	}
+	if f.progressJSON != "" && f.progressInterval <= 0 {
+		return usageError{fmt.Errorf("-progress-interval must be positive, got %v", f.progressInterval)}
+	}
+	if f.progressJSON == "1" && f.o == "" && f.addr == "" && !f.discard {
+		return usageError{errors.New("-progress-json 1 cannot be used when the output is written to stdout")}
+	}
+	if f.quiet && (f.verbose || f.showProgress()) {
+		return usageError{errors.New("-q cannot be used with -verbose or -progress")}
+	}
+	if f.printHash != "" {
+		if f.o != "" || f.addr != "" || f.discard || f.sha || f.shards > 1 || f.workers > 1 || compress || f.size || f.countOnly || f.dryRun {
+			return usageError{errors.New("-print-hash cannot be used with -o, -addr, -discard, -sha256, -shards, -workers, -gzip, -zstd, -size, -count, or -dry-run")}
+		}
+		if hashes[f.printHash] == nil {
+			return usageError{fmt.Errorf("unknown -print-hash %q; choose from %s", f.printHash, strings.Join(slices.Sorted(maps.Keys(hashes)), ", "))}
+		}
+	}
+	if f.discard && (f.o != "" || f.addr != "" || f.shards > 1 || f.workers > 1) {
+		return usageError{errors.New("-discard cannot be used with -o, -addr, -shards, or -workers")}
+	}
+	// -print-hash discards the output but for the digest.
+	discard := f.discard || f.printHash != ""
+	if format == debruijn.Binary && sized && !f.force && !discard && f.addr == "" && f.shards <= 1 && !f.size && !f.countOnly && !f.dryRun && (toTerminal(f.o) || f.tee && isTerminal(os.Stdout)) {
+		return usageError{errors.New("refusing to write binary output to a terminal; send it to a file or a pipe, or add -force")}
+	}
+	if f.fsync && f.o == "" {
+		return usageError{errors.New("-fsync requires -o")}
+	}
+	if f.atomicOut && f.shards > 1 {
+		return usageError{errors.New("-atomic cannot be used with -shards")}
+	}
+	if f.rate < 0 || f.rate > 0 && (f.workers > 1 || f.mmapOut) {
+		return usageError{errors.New("-rate must not be negative and cannot be used with -workers or -mmap")}
+	}
+	if f.workers > 1 && (!bin || f.o == "" || f.order != 4 || compress) {
+		return usageError{errors.New("-workers greater than 1 requires -bin, -o, and order 4, and cannot be used with -gzip or -zstd")}
+	}
+	if f.addr != "" && (f.o != "" || f.workers > 1 || f.shards > 1) {
+		return usageError{errors.New("-addr cannot be used with -o, -workers, or -shards")}
+	}
+	if (f.limit >= 0 || f.limitBytes >= 0) && (f.resume != 0 || f.shards > 1 || f.workers > 1 || f.alphabet > 256 || f.size || f.countOnly) {
+		return usageError{errors.New("-limit and -limit-bytes cannot be used with -resume, -shards, -workers, -size, -count, or -alphabet above 256")}
+	}
+	if f.head >= 0 && (f.shards > 1 || f.workers > 1 || f.mmapOut || f.size || f.countOnly) {
+		return usageError{errors.New("-head cannot be used with -shards, -workers, -mmap, -size, or -count")}
+	}
+	if f.mmapOut && (!bin || f.o == "" || compress || f.tee || f.shards > 1 || f.workers > 1 || f.alphabet > 256) {
+		return usageError{errors.New("-mmap requires -bin and -o and cannot be used with -gzip, -zstd, -tee, -shards, -workers, or -alphabet above 256")}
+	}
+	if f.tee && (f.o == "" || f.workers > 1 || f.shards > 1) {
+		return usageError{errors.New("-tee requires -o and cannot be used with -workers or -shards")}
+	}
+	if f.sha && f.workers > 1 {
+		return usageError{errors.New("-sha256 cannot be used with -workers")}
+	}
+	if f.shards > 1 && (f.o == "" || f.resume != 0 || f.workers > 1) {
+		return usageError{errors.New("-shards greater than 1 requires -o and cannot be used with -resume or -workers")}
+	}
+	if f.shards > 1 && !ipv4 && f.order > 7 {
+		return usageError{errors.New("-shards greater than 1 requires order at most 7")}
+	}
+	if f.dryRun && (f.size || f.countOnly) {
+		return usageError{errors.New("-dry-run cannot be used with -size or -count")}
+	}
+	if f.countOnly && (ipv4 || f.alphabet > 256 || !sized || f.resume != 0 || f.o != "" || f.addr != "" || f.shards > 1 || f.workers > 1) {
+		return usageError{errors.New("-count cannot be used with -ipv4, -alphabet above 256, -encoding other than the built-in ones, -resume, -o, -addr, -shards, or -workers")}
+	}
+	return nil
+}
+
+// job is a run of the generate command: its flags, with those which stand for
+// others resolved, and what they mean for the output.
+type job struct {
+	generateFlags
+	// opts describes the output for the debruijn package, which does the
+	// formatting but for -ipv4 shards.
+	opts   debruijn.Options
+	format debruijn.Format
+	// sized is whether the size of the output in the format is known.
+	sized bool
+	// sepLen is the length of the separator before each term but the first.
+	sepLen int64
+	// suffix follows each address in -ipv4 mode before the newline.
+	suffix   string
+	prefixes []netip.Prefix
+	inside   netip.Prefix
+	// compress and decompress compress the output and read it back for
+	// -check, or are nil without compression.
+	compress   func(io.Writer) (io.WriteCloser, error)
+	decompress func(io.Reader) (io.Reader, error)
+	// tail holds the end of the file for -append, which the output must
+	// reproduce before anything is appended.
+	tail []byte
+	// cut is the length to truncate the file to for -append with -resume,
+	// or -1 to keep all of it.
+	cut int64
+	// deadline is when to stop for -duration and -stop-after, or zero to
+	// write the entire output.
+	deadline time.Time
+	prog     bool
+}
+
+// newJob parses the values of flags, which validate has checked against each
+// other, into the job they describe. It reads the end of the -o file for
+// -append, where the output resumes.
+func newJob(flags *generateFlags) (*job, error) {
+	j := &job{generateFlags: *flags, cut: -1}
+	var err error
+	if j.format, j.sized, err = j.outputFormat(); err != nil {
+		return nil, err
+	}
+	// The rest of the job is in terms of the older flags, which -format
+	// stands for.
+	j.bin = j.encoding == "" && j.format == debruijn.Binary
+	j.ipv4 = j.encoding == "" && j.format == debruijn.IPv4
+	j.prog = j.showProgress()
+	var enc debruijn.Encoder
+	if j.encoding != "" {
+		enc = debruijn.LookupEncoder(j.encoding)
+	}
+	var byteOrder binary.ByteOrder
+	switch j.endian {
+	case "big":
+		byteOrder = binary.BigEndian
+	case "little":
+		byteOrder = binary.LittleEndian
+	default:
+		return nil, usageError{fmt.Errorf("-endian must be big or little, got %q", j.endian)}
+	}
+	switch {
+	case j.crlf:
+		j.separator, j.sepSet = `\r\n`, true
+	case j.jsonOut:
+		j.separator, j.sepSet = ",", true
+	}
+	if j.sepSet {
+		if j.separator, err = unescape(j.separator); err != nil {
+			return nil, usageError{fmt.Errorf("bad -sep: %v", err)}
+		}
+		// The default separators of the text formats have their own
+		// formats, which are faster and can be sized without -sep's
+		// adjustments. Anywhere else, the separator is for Validate to
+		// refuse.
+		lines := map[debruijn.Format]debruijn.Format{
+			debruijn.Dot:       debruijn.Lines,
+			debruijn.HexDot:    debruijn.HexLines,
+			debruijn.PaddedDot: debruijn.PaddedLines,
+		}
+		if l, text := lines[j.format]; text && enc == nil {
+			switch j.separator {
+			case ".":
+				j.sepSet = false
+			case "\n":
+				j.sepSet, j.format = false, l
+			}
+		}
+	}
+	j.sepLen = 1
+	switch {
+	case j.sepSet:
+		j.sepLen = int64(len(j.separator))
+	case j.format == debruijn.Binary || j.format == debruijn.IPv4:
+		j.sepLen = 0
+	}
+	if j.cidr != -1 {
+		j.suffix = "/" + strconv.Itoa(j.cidr)
+	}
+	if j.exclude != "" {
+		for _, s := range strings.Split(j.exclude, ",") {
+			p, err := netip.ParsePrefix(strings.TrimSpace(s))
+			if err != nil {
+				return nil, usageError{fmt.Errorf("bad -exclude prefix: %v", err)}
+			}
+			j.prefixes = append(j.prefixes, p)
+		}
+	}
+	if j.within != "" {
+		j.inside, err = netip.ParsePrefix(j.within)
+		if err != nil || !j.inside.Addr().Is4() {
+			return nil, usageError{fmt.Errorf("bad -prefix %q: must be an IPv4 CIDR prefix", j.within)}
+		}
+	}
+	var rot int64
+	if j.startAddr != "" {
+		a, err := netip.ParseAddr(j.startAddr)
+		if err != nil || !a.Is4() {
+			return nil, usageError{fmt.Errorf("bad -start-addr %q: must be an IPv4 address", j.startAddr)}
+		}
+		rot = debruijn.Rank(a)
+	}
+	if j.start != "" {
+		if rot, err = startWord(j.start); err != nil {
+			return nil, usageError{fmt.Errorf("bad -start %q: %v", j.start, err)}
+		}
+	}
+	var mask byte
+	if j.xor != "" {
+		m, err := strconv.ParseUint(j.xor, 0, 8)
+		if err != nil {
+			return nil, usageError{fmt.Errorf("bad -xor %q: must be a byte in hex or decimal", j.xor)}
+		}
+		mask = byte(m)
+	}
+	var perm *[256]byte
+	if j.permuteSeed != "" {
+		seed := rand.Uint64()
+		if j.permuteSeed != "random" {
+			if seed, err = strconv.ParseUint(j.permuteSeed, 0, 64); err != nil {
+				return nil, usageError{fmt.Errorf("bad -permute-seed %q: must be an unsigned integer or \"random\"", j.permuteSeed)}
+			}
+		}
+		if j.printSeed {
+			fmt.Fprintf(os.Stderr, "permute seed %d\n", seed)
+		}
+		p := debruijn.Permutation(seed)
+		perm = &p
+	}
+	compression := debruijn.NoCompression
+	switch {
+	case j.gz:
+		compression = debruijn.Gzip
+	case j.zst:
+		compression = debruijn.Zstd
+	}
+	if j.from != "" || j.until != "" {
+		lo, hi := int64(0), int64(1<<32-1)
+		if j.from != "" {
+			if lo, err = windowOffset(j.from, rot, mask, perm); err != nil {
+				return nil, usageError{fmt.Errorf("bad -from: %v", err)}
+			}
+		}
+		if j.until != "" {
+			if hi, err = windowOffset(j.until, rot, mask, perm); err != nil {
+				return nil, usageError{fmt.Errorf("bad -until: %v", err)}
+			}
+		}
+		if lo > hi {
+			return nil, usageError{fmt.Errorf("the window of -from %s at offset %d comes after the window of -until %s at offset %d", j.from, lo, j.until, hi)}
+		}
+		// Output the windows from lo through hi, which in terms includes
+		// the three after the start of the last window.
+		j.skip, j.limit = lo, hi-lo+1
+		if !j.ipv4 {
+			j.limit += 3
+		}
+	}
+	var seed uint64
+	if j.shuffle != "" {
+		if seed, err = strconv.ParseUint(j.shuffle, 0, 64); err != nil {
+			return nil, usageError{fmt.Errorf("bad -shuffle %q: must be an unsigned integer", j.shuffle)}
+		}
+	}
+	j.opts = debruijn.Options{
+		Alphabet:    j.alphabet,
+		Order:       j.order,
+		ByteOrder:   byteOrder,
+		Format:      j.format,
+		Encoder:     enc,
+		Skip:        uint64(j.skip),
+		Compression: compression,
+		Level:       j.level,
+		Wrap:        j.wrap,
+		CIDR:        j.cidr != -1,
+		Bits:        j.cidr,
+		Exclude:     j.prefixes,
+		Within:      j.inside,
+		Shuffle:     j.shuffle != "",
+		Seed:        seed,
+		Rotate:      uint64(rot),
+		Reverse:     j.rev,
+		Mask:        mask,
+		Permutation: perm,
+	}
+	if j.sepSet {
+		j.opts.Separator = j.separator
+	}
+	if j.sepSet && j.separator == "" {
+		// An empty Separator is the format's own, so no separator at all
+		// takes an encoder built without one.
+		var build func(string) (debruijn.Encoder, error)
+		switch j.format {
+		case debruijn.Dot:
+			build = debruijn.TextEncoder
+		case debruijn.HexDot:
+			build = debruijn.HexEncoder
+		case debruijn.PaddedDot:
+			build = debruijn.PaddedEncoder
+		}
+		if j.opts.Encoder, err = build(""); err != nil {
+			return nil, usageError{fmt.Errorf("bad -sep: %v", err)}
+		}
+	}
+	if j.limit > 0 {
+		j.opts.Limit = j.limit
+	}
+	if err := j.opts.Validate(); err != nil {
+		return nil, usageError{err}
+	}
+	// The sink compresses the output after counting and hashing it, so the
+	// library writes it uncompressed.
+	j.compress, j.decompress = compressors(compression, j.level)
+	j.opts.Compression, j.opts.Level = debruijn.NoCompression, 0
+	if j.duration > 0 {
+		j.deadline = now().Add(j.duration)
+	}
+	if j.stopAfter != "" {
+		t, err := time.Parse(time.RFC3339, j.stopAfter)
+		if err != nil {
+			return nil, usageError{fmt.Errorf("bad -stop-after: %v", err)}
+		}
+		if j.deadline.IsZero() || t.Before(j.deadline) {
+			j.deadline = t
+		}
+	}
+	if j.printHash != "" {
+		// Hashing is discarding the output but for the digest.
+		j.discard = true
+	}
+	if j.o != "" && !j.bufSet && j.rate == 0 {
+		// Large sequential writes go faster with a buffer of many blocks.
+		j.buf = fileBuffer(blockSize(j.o))
+	}
+	if j.appendOut {
+		end := int64(-1)
+		if j.resume > 0 {
+			end, j.cut = j.resume, j.resume
+		}
+		if j.resume, j.tail, err = fileTail(j.o, j.appendCheck, end); err != nil {
+			return nil, err
+		}
+	}
+	if j.noClobber {
+		// Fail before doing any work; creating the file checks again.
+		if _, err := os.Lstat(j.o); err == nil {
+			return nil, outputError{err: errClobber(j.o)}
+		}
+	}
+	return j, nil
+}
+
+// compressors returns the functions which compress the output with c at the
+// level for -gzip or -zstd and read it back for -check, or nil for
+// NoCompression.
+func compressors(c debruijn.Compression, level int) (func(io.Writer) (io.WriteCloser, error), func(io.Reader) (io.Reader, error)) {
+	if c == debruijn.NoCompression {
+		return nil, nil
+	}
+	compress := func(w io.Writer) (io.WriteCloser, error) { return c.NewWriter(w, level) }
+	decompress := func(r io.Reader) (io.Reader, error) { return c.NewReader(r) }
+	return compress, decompress
+}
+
+// outputSize returns the size of the complete output, ignoring -resume.
+func (j *job) outputSize() (int64, error) {
+	if j.alphabet > 256 {
+		wg, err := debruijn.NewWide(j.alphabet, j.order)
+		if err != nil {
+			return 0, err
+		}
+		return wg.Size()
+	}
+	g, err := j.opts.Generator()
+	if err != nil {
+		return 0, err
+	}
+	n, err := g.Size(j.format)
+	if err != nil {
+		return n, err
+	}
+	if j.format == debruijn.IPv4 {
+		return n + (1<<32)*int64(len(j.suffix)), nil
+	}
+	terms, _ := g.Size(debruijn.Binary)
+	if j.noWrap {
+		// Leave out the terms which close the cycle, which repeat the
+		// first ones of the output, each with a separator before it.
+		start := j.opts
+		start.Skip = 0
+		c, err := start.Generator()
+		if err != nil {
+			return 0, err
+		}
+		width := termWidths(j.format, 1)
+		for range j.order - 1 {
+			t, _ := c.Next()
+			n -= width[t]
+		}
+		terms -= int64(j.order - 1)
+	}
+	if j.wrap > 0 {
+		// A newline follows every wrap terms but those that end the
+		// output.
+		n += (terms - 1) / j.wrap
+	}
+	if !j.sepSet {
+		return n, nil
+	}
+	// Each separator but the missing first one differs in length
+	// from the one in the format.
+	n += (terms - 1) * int64(len(j.separator)-1)
+	if j.jsonOut {
+		// The brackets around the array.
+		n += 2
+	}
+	return n, nil
+}
+
+// estimate prints what the job would write for -dry-run, along with an
+// estimate of how long it would take from timing a short burst of it.
+func (j *job) estimate() error {
+	dest := "stdout"
+	switch {
+	case j.o != "" && j.shards > 1:
+		dest = fmt.Sprintf("%s through %s", shardName(j.o, 0), shardName(j.o, j.shards-1))
+	case j.o != "":
+		dest = j.o
+	case j.addr != "":
+		dest = j.addr
+	case j.discard:
+		dest = "nowhere"
+	}
+	// The exact size is known under the same conditions as for -size,
+	// and in binary, also with limits.
+	total := int64(-1)
+	if n, err := j.outputSize(); err == nil && len(j.prefixes) == 0 && !j.inside.IsValid() && j.sized && (j.skip == 0 || j.format == debruijn.Binary) {
+		total = max(n-j.skip-j.resume, 0)
+		for _, l := range []int64{j.limit, j.limitBytes} {
+			if l >= 0 {
+				total = min(total, l)
+				if j.format != debruijn.Binary {
+					total = -1
+				}
+			}
+		}
+		if j.head >= 0 && total >= 0 {
+			total = min(total, j.head)
+		}
+	}
+	if total >= 0 {
+		fmt.Printf("would write %d bytes to %s\n", total, dest)
+	} else {
+		fmt.Printf("would write to %s; the exact size depends on flags -size can't account for\n", dest)
+	}
+	var terms int64
+	if j.alphabet > 256 {
+		wg, err := debruijn.NewWide(j.alphabet, j.order)
+		if err != nil {
+			return err
+		}
+		n, err := wg.Size()
+		if err != nil {
+			return err
+		}
+		terms = n / 2
+	} else {
+		g, err := j.opts.Generator()
+		if err != nil {
+			return err
+		}
+		if terms, err = g.Size(debruijn.Binary); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("sequence B(%d, %d) of %d terms\n", j.alphabet, j.order, terms)
+	if j.shards > 1 {
+		counts, err := shardCounts(j.shards, j.order, j.ipv4)
+		if err != nil {
+			return err
+		}
+		unit := "terms"
+		if j.ipv4 {
+			unit = "windows"
+		}
+		for k, n := range counts {
+			fmt.Printf("%s: %d %s\n", shardName(j.o, k), n, unit)
+		}
+	}
+	// Time a short burst of the same output, compressed the same way, to
+	// estimate how long the whole of it would take.
+	const burst = 32 << 20
+	bw, finish, err := sink(io.Discard, j.buf, 0, j.compress, nil, nil)
+	if err != nil {
+		return err
+	}
+	hw := &headWriter{w: bw, n: burst}
+	// Start from the beginning so that there's enough to time however
+	// little of the output is asked for.
+	whole := j.opts
+	whole.Skip, whole.Limit = 0, 0
+	began := time.Now()
+	if _, err = debruijn.Write(hw, whole); err == nil || errors.Is(err, errHead) {
+		err = finish()
+	}
+	if err != nil {
+		return err
+	}
+	speed := float64(burst-hw.n) / time.Since(began).Seconds()
+	if j.rate > 0 && j.compress == nil {
+		speed = min(speed, float64(j.rate))
+	}
+	if total >= 0 && speed > 0 {
+		eta := time.Duration(float64(total) / speed * float64(time.Second))
+		fmt.Printf("estimated %v at %.1f MB/s\n", eta.Round(time.Second), speed/1e6)
+	} else {
+		fmt.Printf("generates %.1f MB/s\n", speed/1e6)
+	}
+	return nil
+}
+
+// countOutput counts the terms and bytes of the output for -count, failing
+// if either isn't what it should be.
+func (j *job) countOutput() error {
+	g, err := j.opts.Generator()
+	if err != nil {
+		return err
+	}
+	size := func() (int64, error) {
+		n, err := j.outputSize()
+		return max(n-j.skip, 0), err
+	}
+	if !countTerms(g, j.format, j.sepLen, j.wrap, size) {
+		// countTerms has said what's wrong.
+		return exitStatus(1)
+	}
+	return nil
+}
+
+// total returns the size of the output for progress reports, or -1 if it
+// isn't known.
+func (j *job) total() int64 {
+	n, err := j.outputSize()
+	if err != nil || len(j.prefixes) > 0 || j.inside.IsValid() || !j.sized || j.limit >= 0 || j.limitBytes >= 0 || j.skip != 0 && j.format != debruijn.Binary {
+		return -1
+	}
+	total := max(n-j.skip-j.resume, 0)
+	if j.head >= 0 {
+		total = min(total, j.head)
+	}
+	return total
+}
+
+// write writes the output, with the progress, digests, and checks the flags
+// ask for.
+func (j *job) write() (err error) {
+	// count tracks the bytes of output written for -progress and the summary
+	// at the end.
+	count := new(atomic.Int64)
+	// gate ends the output cleanly on a signal.
+	gate := new(stopWriter)
+	total := j.total()
+	// terms counts the terms generated in text formats for -progress-json,
+	// where they aren't the bytes, sampled as the output is written.
+	var terms *atomic.Int64
+	if j.progressJSON != "" {
+		var finish func(error, os.Signal)
+		// Don't shadow err, which the final event reports.
+		terms, finish, err = j.startEvents(count, total)
+		if err != nil {
+			return err
+		}
+		atSignal(func(sig os.Signal) { finish(nil, sig) })
+		defer func() { finish(err, gate.signal()) }()
+	}
+	// stopProgress ends -progress, before -check reports its own.
+	stopProgress := func() {}
+	if j.prog {
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go report("wrote", count, total, isTerminal(os.Stderr), stop, done)
+		stopProgress = sync.OnceFunc(func() {
+			close(stop)
+			<-done
+		})
+		defer stopProgress()
+	}
+	began := time.Now()
+	// piped is whether the output ended because its reader went away, which
+	// leaves the count short of the output.
+	piped := false
+	defer func() {
+		// -progress and -discard end with summaries of their own.
+		if err == nil && !j.quiet && !j.prog && !j.discard && !piped {
+			log.Printf("wrote %d bytes in %v", count.Load(), time.Since(began).Round(time.Millisecond))
+		}
+	}()
+	// From here on, errors are failures to write the output, except that
+	// -append refusing the file is a failure of its own.
+	defer func() {
+		var status exitStatus
+		if err == nil || errors.As(err, &status) || errors.Is(err, errTail) {
+			return
+		}
+		err = outputError{off: j.resume + count.Load(), err: err}
+	}()
+
+	sum := j.hash()
+	if j.shards > 1 {
+		err := writeShards(j.o, j.shards, j.order, j.ipv4, j.noClobber, func(f *os.File, g *debruijn.Generator, n int64) error {
+			var file io.Writer = f
+			if j.fsync {
+				file = syncedFile{f}
+			}
+			w, finish, err := sink(file, j.buf, j.rate, j.compress, count, sum)
+			if err != nil {
+				return err
+			}
+			if err := j.substitute(g); err != nil {
+				return err
+			}
+			if err := j.emit(w, g, n); err != nil {
+				return err
+			}
+			return finish()
+		})
+		if err != nil {
+			return err
+		}
+		printSum(sum, "")
+		return nil
+	}
+
+	var out io.Writer = os.Stdout
+	var tc *tailWriter
+	// tmp is the temporary file for -atomic until it is renamed into place.
+	var tmp *atomicFile
+	// Also covers returning an error before committing.
+	defer func() { tmp.remove() }()
+	switch {
+	case j.discard:
+		out = io.Discard
+	case j.addr != "":
+		conn, err := net.Dial("tcp", j.addr)
+		if err != nil {
+			return err
+		}
+		out = conn
+	case j.o != "":
+		var f *os.File
+		f, tmp, err = j.openFile()
+		if err != nil {
+			return err
+		}
+		if j.workers > 1 {
+			if err := writeParallel(f, j.resume, j.workers, count, j.fsync); err != nil {
+				return err
+			}
+			return tmp.commit()
+		}
+		if j.mmapOut {
+			err := j.writeMapped(f, count, sum)
+			if err == nil {
+				if err := tmp.commit(); err != nil {
+					return err
+				}
+				printSum(sum, j.o)
+				return nil
+			}
+			if !errors.Is(err, errNoMap) {
+				return err
+			}
+			if !j.quiet {
+				log.Printf("%v; writing normally", err)
+			}
+		}
+		out, tc = j.fileOutput(f)
+	}
+	// digests receives the uncompressed output for -sha256 and
+	// -crc-interval.
+	digests, crc := j.digests(sum)
+	var w io.Writer
+	var finish func() error
+	if j.discard && j.bin && j.compress == nil && j.rate == 0 && digests == nil {
+		// Measure the generator alone, without copying through a buffer.
+		w = &countWriter{w: out, n: count}
+		finish = func() error { return nil }
+	} else {
+		w, finish, err = sink(out, j.buf, j.rate, j.compress, count, digests)
+		if err != nil {
+			return err
+		}
+	}
+	// On a signal, stop writing and keep what's written so far, ending
+	// between two writes to the buffer.
+	gate.w = w
+	w = gate
+	atInterrupt(gate.stop)
+	g, err := j.produce(w, true, terms)
+	if err == nil || errors.Is(err, errHead) {
+		err = finish()
+	} else if errors.Is(err, errInterrupted) {
+		// Flush and close what we have so that it can be resumed.
+		if ferr := finish(); ferr != nil {
+			err = ferr
+		}
+	}
+	if err == nil && tc != nil {
+		err = tc.done()
+	}
+	if errors.Is(err, errInterrupted) {
+		n := j.resume + gate.n
+		switch {
+		case j.atomicOut:
+			log.Printf("interrupted after %d bytes of output, which -atomic discards", n)
+		case j.appendOut:
+			log.Printf("interrupted after %d bytes of output; continue with the same flags", n)
+		default:
+			log.Printf("interrupted after %d bytes of output; continue with the same flags and -resume %d", n, n)
+		}
+		return interruptStatus(gate.sig)
+	}
+	if err != nil {
+		if errors.Is(err, errTail) {
+			return fmt.Errorf("not appending to %s: %w", j.o, err)
+		}
+		if j.addr != "" {
+			return fmt.Errorf("sending to %s failed: %w; rerun with -resume set to the number of bytes received", j.addr, err)
+		}
+		if errors.Is(err, errSync) {
+			return err
+		}
+		if errors.Is(err, syscall.EPIPE) {
+			// The reader has gone away, e.g. head has all it wants. That's
+			// a normal way for the output to end, not a failure.
+			piped = true
+			return nil
+		}
+		return err
+	}
+	if err := tmp.commit(); err != nil {
+		return err
+	}
+	sumName := j.o
+	if j.compress != nil {
+		// The digest is of the uncompressed output, so it doesn't match
+		// the file.
+		sumName = ""
+	}
+	if j.printHash != "" {
+		fmt.Printf("%x  %d bytes\n", sum.Sum(nil), count.Load())
+	} else {
+		printSum(sum, sumName)
+	}
+	if crc != nil {
+		crc.done()
+	}
+	if j.check {
+		stopProgress()
+		if err := j.checkOutput(count.Load()); err != nil {
+			return err
+		}
+	}
+	if j.discard && j.printHash == "" {
+		n, elapsed := count.Load(), time.Since(began)
+		log.Printf("generated %d bytes in %v, %.1f MB/s", n, elapsed.Round(time.Millisecond), float64(n)/elapsed.Seconds()/1e6)
+	}
+	if j.verbose && g != nil {
+		for l := 1; l <= j.order; l++ {
+			if j.order%l == 0 {
+				log.Printf("emitted %d %d-element Lyndon words", g.WordCount(l), l)
+			}
+		}
+	}
+	return nil
+}
+
+// startEvents opens the destination of -progress-json and starts writing
+// events to it every -progress-interval. It returns the counter of terms for
+// the events to sample, if the format needs one, and the function which ends
+// the events with the final one for the run's error or signal.
+func (j *job) startEvents(count *atomic.Int64, total int64) (*atomic.Int64, func(error, os.Signal), error) {
+	f, err := openEvents(j.progressJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad -progress-json: %v", err)
+	}
+	var terms *atomic.Int64
+	width := int64(0)
+	switch {
+	case j.alphabet > 256:
+		width = 2
+	case j.bin:
+		width = 1
+	default:
+		terms = new(atomic.Int64)
+	}
+	ev := &events{w: f, count: count, terms: terms, width: width, total: total, start: time.Now()}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go ev.run(j.progressInterval, stop, done)
+	finish := func(err error, sig os.Signal) {
+		close(stop)
+		<-done
+		ev.final(err, sig)
+	}
+	return terms, finish, nil
+}
+
+// hash returns the hash of the output for -sha256 or -print-hash, or nil if
+// neither is given.
+func (j *job) hash() hash.Hash {
+	switch {
+	case j.sha:
+		return sha256.New()
+	case j.printHash != "":
+		return hashes[j.printHash]()
+	}
+	return nil
+}
+
+// digests returns the writer which feeds the uncompressed output to sum, if
+// it isn't nil, and the CRC writer for -crc-interval, if given, along with
+// that CRC writer, or nil if neither is needed.
+func (j *job) digests(sum hash.Hash) (io.Writer, *crcWriter) {
+	var digests io.Writer
+	if sum != nil {
+		digests = sum
+	}
+	if j.crcInterval <= 0 {
+		return digests, nil
+	}
+	crc := &crcWriter{interval: j.crcInterval, off: j.resume}
+	if sum != nil {
+		return io.MultiWriter(sum, crc), crc
+	}
+	return crc, crc
+}
+
+// substitute applies the mask and permutation to a fresh generator.
+func (j *job) substitute(g *debruijn.Generator) error {
+	g.Mask(j.opts.Mask)
+	if j.opts.Permutation != nil {
+		return g.WithPermutation(*j.opts.Permutation)
+	}
+	return nil
+}
+
+// emit writes n units of output from g to w: addresses in -ipv4 mode and
+// terms otherwise. If n is negative, it writes all of them.
+func (j *job) emit(w io.Writer, g *debruijn.Generator, n int64) error {
+	var err error
+	switch {
+	case j.ipv4:
+		// Shards count windows, while -limit counts the addresses
+		// written.
+		addrs := excluding(take(g.Addrs(), n), j.prefixes)
+		if j.cidr >= 0 {
+			_, err = debruijn.WriteCIDR(w, addrs, j.cidr, j.limit)
+		} else {
+			_, err = debruijn.WriteAddrs(w, addrs, j.limit)
+		}
+	case n != 0:
+		// Options has no limit of zero, which writes nothing.
+		part := j.opts
+		part.Limit = n
+		_, err = part.WriteFrom(w, g)
+	}
+	return err
+}
+
+// openFile opens the -o file: to append to with -append, as a temporary file
+// to rename into place with -atomic, and otherwise created in place. The
+// temporary file is removed on a signal, and otherwise, the caller must
+// commit or remove it.
+func (j *job) openFile() (*os.File, *atomicFile, error) {
+	switch {
+	case j.appendOut:
+		f, err := os.OpenFile(j.o, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o666)
+		return f, nil, err
+	case j.atomicOut:
+		f, err := createTemp(j.o)
+		if err != nil {
+			return nil, nil, err
+		}
+		tmp := &atomicFile{name: f.Name(), dest: j.o, noClobber: j.noClobber, fsync: j.fsync}
+		// Don't leave the incomplete file behind if we're interrupted.
+		atSignal(func(os.Signal) { tmp.remove() })
+		return f, tmp, nil
+	}
+	f, err := createOutput(j.o, j.noClobber)
+	return f, nil, err
+}
+
+// fileOutput returns the writer for the output to the -o file f, which syncs
+// it on closing for -fsync and copies the output to stdout for -tee. For
+// -append, it compares the output with the end of the file before appending,
+// through the returned tailWriter.
+func (j *job) fileOutput(f *os.File) (io.Writer, *tailWriter) {
+	var file io.WriteCloser = f
+	if j.fsync {
+		file = syncedFile{f}
+	}
+	var out io.Writer = file
+	if j.tee {
+		// Keep writing the file even if stdout goes away, as when
+		// watching the start of the output with head.
+		out = struct {
+			io.Writer
+			io.Closer
+		}{io.MultiWriter(f, &pipeWriter{w: os.Stdout}), file}
+	}
+	if !j.appendOut {
+		return out, nil
+	}
+	// Output resumes with the end of the file, which we compare rather than
+	// write again.
+	tc := &tailWriter{w: out, want: j.tail, off: j.resume}
+	if j.cut >= 0 {
+		// Discard what follows the resume offset only once what precedes
+		// it is known to be right.
+		tc.truncate = func() error { return os.Truncate(j.o, j.cut) }
+	}
+	return struct {
+		io.Writer
+		io.Closer
+	}{tc, file}, tc
+}
+
+// writeMapped writes the binary output into f mapped into memory for -mmap.
+// It returns an error wrapping errNoMap if f can't be mapped, before writing
+// anything.
+func (j *job) writeMapped(f *os.File, count *atomic.Int64, sum hash.Hash) error {
+	g, err := j.opts.Generator()
+	if err != nil {
+		return err
+	}
+	g.Skip(uint64(j.resume))
+	n, err := j.outputSize()
+	if err != nil {
+		return err
+	}
+	n = max(n-j.skip-j.resume, 0)
+	for _, l := range []int64{j.limit, j.limitBytes} {
+		if l >= 0 {
+			n = min(n, l)
+		}
+	}
+	return writeMapped(f, g, n, count, sum, j.fsync)
+}
+
+// produce writes the output from its start through w. If watch is true, it
+// also feeds -verbose and the terms counter for -progress-json, if not nil,
+// and stops at the deadline; -check calls it again without them to compare
+// the output with the file.
+func (j *job) produce(w io.Writer, watch bool, terms *atomic.Int64) (*debruijn.Generator, error) {
+	opts, limit := j.opts, j.limit
+	// The encoders write through tw, which drops the bytes before the
+	// resume offset that we can't skip by skipping terms.
+	tw := &skipWriter{w: w}
+	if j.head >= 0 {
+		tw.w = &headWriter{w: w, n: j.head}
+	}
+	if j.alphabet > 256 {
+		tw.skip = j.resume
+		_, err := debruijn.Write(tw, opts)
+		return nil, err
+	}
+	opts.Skip = uint64(j.skip)
+	if j.bin {
+		opts.Skip += uint64(j.resume)
+	} else {
+		tw.skip = j.resume
+	}
+	g, err := opts.Generator()
+	if err != nil {
+		return nil, err
+	}
+	if j.noWrap {
+		// Stop where the cycle does.
+		terms, _ := g.Size(debruijn.Binary)
+		n := max(terms-int64(j.order-1)-int64(opts.Skip), 0)
+		if limit < 0 || n < limit {
+			limit = n
+		}
+	}
+	if j.limitBytes >= 0 {
+		n, err := fitting(g, j.format, j.sepLen, j.wrap, int64(len(j.suffix)), j.prefixes, j.limitBytes)
+		if err != nil {
+			return g, err
+		}
+		if limit < 0 || n < limit {
+			limit = n
+		}
+	}
+	if j.jsonOut {
+		_, err = io.WriteString(tw, "[")
+	}
+	// Options has no limit of zero, which writes nothing.
+	if limit != 0 && err == nil {
+		opts.Limit = limit
+		var gw io.Writer = tw
+		if watch && j.verbose {
+			// Binary output jumps straight to the resume offset.
+			off := int64(0)
+			if j.bin {
+				off = j.resume
+			}
+			gw = &milestoneWriter{w: gw, g: g, seen: g.WordCount(1), off: off}
+		}
+		if watch && terms != nil {
+			gw = &termWriter{w: gw, g: g, start: g.Offset(), n: terms}
+		}
+		if !watch || j.deadline.IsZero() {
+			_, err = opts.WriteFrom(gw, g)
+		} else {
+			var n int64
+			var stopped bool
+			n, stopped, err = writeUntil(gw, opts, g, j.deadline)
+			if stopped && err == nil {
+				// Binary output skipped the terms before the resume
+				// offset rather than writing them.
+				if j.bin {
+					n += j.resume
+				}
+				log.Printf("stopped at the deadline after %d bytes of output, before term %d; continue with the same flags and -resume %d", n, g.Offset(), n)
+			}
+		}
+	}
+	if j.jsonOut && err == nil {
+		_, err = io.WriteString(tw, "]")
+	}
+	return g, err
+}
+
+// checkOutput reads back the -o file for -check, holding n bytes of output,
+// and checks it.
+func (j *job) checkOutput(n int64) error {
+	// The file holds the output from the resume offset, or all of it after
+	// -append. The complete binary sequence can be checked for itself;
+	// anything else is checked against the output again.
+	off := int64(0)
+	if j.appendOut {
+		off = j.resume
+	}
+	whole := 0
+	if j.bin && j.alphabet == 256 && j.order <= 4 && j.skip == 0 && j.limit < 0 && j.limitBytes < 0 && j.head < 0 && !j.noWrap && (j.resume == 0 || j.appendOut) {
+		off, n, whole = 0, j.resume+n, j.order
+	}
+	regenerate := func(w io.Writer) error {
+		_, err := j.produce(w, false, nil)
+		return err
+	}
+	return checkFile(j.o, off, j.decompress, whole, regenerate, n, j.prog, j.quiet)
+}
+
+// countTerms generates the output of g without formatting it and prints the
+// number of terms and the number of bytes they would take in the format, each
+// separated from the next by sepLen bytes and a newline after every wrap terms
+// if wrap is positive. It compares them with the number of terms in a de
+// Bruijn sequence and the size that outputSize predicts, reporting whether
+// both match.
+func countTerms(g *debruijn.Generator, format debruijn.Format, sepLen, wrap int64, outputSize func() (int64, error)) bool {
+	width := termWidths(format, sepLen)
+	var terms, bytes int64
+	for slab := range g.Chunks(0) {
+		terms += int64(len(slab))
+		for _, t := range slab {
+			bytes += width[t]
+		}
+	}
+	if format != debruijn.Binary && terms > 0 {
+		// No separator precedes the first term.
+		bytes -= sepLen
+		if wrap > 0 {
+			bytes += (terms - 1) / wrap
+		}
+	}
+	fmt.Printf("%d terms, %d bytes\n", terms, bytes)
+	ok := true
+	want := int64(1)
+	for range g.Order() {
+		want *= int64(g.Alphabet())
+	}
+	want += int64(g.Order()) - 1
+	if terms != want {
+		log.Printf("expected %d terms", want)
+		ok = false
+	}
+	if size, err := outputSize(); err != nil {
+		log.Printf("can't compute expected size: %v", err)
+		ok = false
+	} else if bytes != size {
+		log.Printf("expected %d bytes", size)
+		ok = false
+	}
+	return ok
+}
+
+// windowOffset returns the offset in the output of the window of the IPv4
+// address s, for the output rotated by rot terms and with each term
+// substituted by the mask and then perm, if it is not nil.
+func windowOffset(s string, rot int64, mask byte, perm *[256]byte) (int64, error) {
+	a, err := netip.ParseAddr(s)
+	if err != nil || !a.Is4() {
+		return 0, fmt.Errorf("%q is not an IPv4 address", s)
+	}
+	w := a.As4()
+	// Find the window of the sequence which becomes w when substituted.
+	for i, b := range w {
+		if perm != nil {
+			b = byte(slices.Index(perm[:], b))
+		}
+		w[i] = b ^ mask
+	}
+	return (debruijn.Rank(netip.AddrFrom4(w)) - rot) & (1<<32 - 1), nil
+}
+
+// unescape interprets the backslash escapes of Go string literals in s, such
+// as \t, \r\n, and \x00, so that separators can be given on the command line.
+func unescape(s string) (string, error) {
+	var b strings.Builder
+	for s != "" {
+		r, multibyte, tail, err := strconv.UnquoteChar(s, 0)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		if r < utf8.RuneSelf || !multibyte {
+			b.WriteByte(byte(r))
+		} else {
+			b.WriteRune(r)
+		}
+		s = tail
+	}
+	return b.String(), nil
+}
+
+// termWidths returns the number of bytes each term takes in the format
+// together with the separator of sepLen bytes before it.
+func termWidths(format debruijn.Format, sepLen int64) (width [256]int64) {
+	for t := range width {
+		switch format {
+		case debruijn.Binary:
+			width[t] = 1
+		case debruijn.HexDot, debruijn.HexLines:
+			width[t] = 2 + sepLen
+		case debruijn.PaddedDot, debruijn.PaddedLines:
+			width[t] = 3 + sepLen
+		default:
+			width[t] = int64(len(strconv.Itoa(t))) + sepLen
+		}
+	}
+	return width
+}
+
+// fitting returns the number of whole terms of g, or addresses not in
+// prefixes in the IPv4 format, whose encoding in the format fits in limit
+// bytes, each term separated from the last by sepLen bytes, with a newline
+// after every wrap terms if wrap is positive, and each address followed by a
+// suffix of suffixLen bytes. It works on a copy of g, leaving g where it is.
+func fitting(g *debruijn.Generator, format debruijn.Format, sepLen, wrap, suffixLen int64, prefixes []netip.Prefix, limit int64) (int64, error) {
+	if format == debruijn.Binary {
+		return limit, nil
+	}
+	c, err := debruijn.ResumeFrom(g.State())
+	if err != nil {
+		return 0, err
+	}
+	var n, bytes int64
+	if format == debruijn.IPv4 {
+		for addr := range excluding(c.Addrs(), prefixes) {
+			bytes += int64(len(addr.String())) + suffixLen + 1
+			if bytes > limit {
+				break
+			}
+			n++
+		}
+		return n, nil
+	}
+	width := termWidths(format, sepLen)
+	// The first term has no separator before it if it begins the output.
+	bytes = -sepLen
+	for term := range c.Terms() {
+		bytes += width[term]
+		if wrap > 0 && n > 0 && n%wrap == 0 {
+			bytes++
+		}
+		if bytes > limit {
+			break
+		}
+		n++
+	}
+	return n, nil
+}
+
+// encodingFormat returns the format whose encoder e is, by which to compute
+// sizes, and whether there is one, which there isn't for registered encoders
+// other than the built-in ones.
+func encodingFormat(e debruijn.Encoder) (debruijn.Format, bool) {
+	for i := range debruijn.FormatNames() {
+		if f := debruijn.Format(i); f.Encoder() != nil && f.Encoder() == e {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// fileBuffer returns the default size of the buffer for writing to a file on
+// a file system with the given block size: the first multiple of the block
+// size from 1 MiB, up to 4 MiB, or 4096 bytes if the block size is unknown.
+func fileBuffer(block int64) int {
+	if block <= 0 {
+		return 4096
+	}
+	n := (1<<20 + block - 1) / block * block
+	return int(min(n, 4<<20))
+}
+
+// sink returns the buffered writer through which to write output to f and a
+// function to call once the output is complete. The buffer sits between the
+// generator and the compressor, if any, so that the compressor receives large
+// writes. The compressor, if compress is not nil, is the one it returns. The
+// finish function flushes the buffer into the compressor before closing it,
+// so that the gzip trailer or the end of the zstd frame follows every term,
+// then closes f if it is a file other than stdout or a network connection. If
+// count is not nil, the uncompressed bytes leaving the buffer are added to it,
+// and if sum is not nil, they are also written to it, as for digests. If rate
+// is positive, the bytes reaching f are throttled to that many per second, in
+// bursts of up to the buffer size.
+func sink(f io.Writer, buf int, rate int64, compress func(io.Writer) (io.WriteCloser, error), count *atomic.Int64, sum io.Writer) (*bufio.Writer, func() error, error) {
+	var zw io.WriteCloser
+	var w io.Writer = f
+	if rate > 0 {
+		w = newRateWriter(w, rate, buf)
+	}
+	if compress != nil {
+		var err error
+		zw, err = compress(w)
+		if err != nil {
+			return nil, nil, err
+		}
+		w = zw
+	}
+	if sum != nil {
+		w = io.MultiWriter(w, sum)
+	}
+	if count != nil {
+		w = &countWriter{w: w, n: count}
+	}
+	bw := bufio.NewWriterSize(w, buf)
+	finish := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if zw != nil {
+			if err := zw.Close(); err != nil {
+				return err
+			}
+		}
+		c, ok := f.(io.Closer)
+		if !ok || f == io.Writer(os.Stdout) {
+			return nil
+		}
+		return c.Close()
+	}
+	return bw, finish, nil
+}
+
+// writeShards splits the output into the given number of files named with
+// the given prefix and a three-digit shard number. Each shard holds an equal
+// share of the terms, or of the windows in -ipv4 mode, up to one. For each
+// shard, write receives the shard's file, a generator positioned at its first
+// term, and the number of terms or windows it holds. writeShards stops at the
+// first error from write.
+func writeShards(prefix string, shards, order int, ipv4, noClobber bool, write func(f *os.File, g *debruijn.Generator, n int64) error) error {
+	counts, err := shardCounts(shards, order, ipv4)
+	if err != nil {
+		return err
+	}
+	var start int64
+	for k, n := range counts {
+		f, err := createOutput(shardName(prefix, k), noClobber)
+		if err != nil {
+			return err
+		}
+		// Each shard gets its own generator, which jumps directly to the
+		// shard's first term when the order is 4.
+		g, err := debruijn.New(order)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		g.Skip(uint64(start))
+		if err := write(f, g, n); err != nil {
+			f.Close()
+			return err
+		}
+		start += n
+	}
+	return nil
+}
+
+// shardCounts returns the number of terms, or of windows if ipv4 is true, in
+// each of the given number of shards of the output of the given order.
+func shardCounts(shards, order int, ipv4 bool) ([]int64, error) {
+	total := int64(1 << 32)
+	if !ipv4 {
+		var err error
+		total, err = debruijn.Size(debruijn.Binary, order)
+		if err != nil {
+			return nil, err
+		}
+	}
+	counts := make([]int64, shards)
+	q, r := total/int64(shards), total%int64(shards)
+	for k := range counts {
+		counts[k] = q
+		if int64(k) < r {
+			counts[k]++
+		}
+	}
+	return counts, nil
+}
+
+// shardName returns the name of shard k of the output named prefix.
+func shardName(prefix string, k int) string {
+	return fmt.Sprintf("%s.%03d", prefix, k)
+}
+
+// errNoMap is the error from writeMapped when the file can't be mapped.
+var errNoMap = errors.New("can't map the output file")
+
+// writeMapped writes size bytes of the binary output of g to f by mapping f
+// into memory and generating the terms directly into the mapping, then closes
+// f. If count is not nil, the bytes written are added to it, and if sum is not
+// nil, they are written to it. If fsync is true, f is synced to storage before
+// it's closed. If f can't be mapped, writeMapped returns an error wrapping
+// errNoMap without having written anything.
+func writeMapped(f *os.File, g *debruijn.Generator, size int64, count *atomic.Int64, sum hash.Hash, fsync bool) error {
+	b, unmap, err := mapFile(f, size)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errNoMap, err)
+	}
+	// Fill the mapping in pieces so that progress reports advance.
+	const piece = 64 << 20
+	for k := 0; k < len(b); k += piece {
+		p := b[k:min(k+piece, len(b))]
+		if _, err := io.ReadFull(g, p); err != nil {
+			unmap()
+			return err
+		}
+		if count != nil {
+			count.Add(int64(len(p)))
+		}
+		if sum != nil {
+			sum.Write(p)
+		}
+	}
+	if err := unmap(); err != nil {
+		return err
+	}
+	return closeFile(f, fsync)
+}
+
+// writeParallel writes the binary sequence from offset resume onward to f
+// using multiple workers. If count is not nil, the bytes written are added to
+// it. If fsync is true, f is synced to storage before it's closed. It closes f
+// in any case.
+func writeParallel(f *os.File, resume int64, workers int, count *atomic.Int64, fsync bool) error {
+	const size = 1<<32 + 3
+	if err := f.Truncate(max(size-resume, 0)); err != nil {
+		f.Close()
+		return err
+	}
+	var w io.WriterAt = f
+	if count != nil {
+		w = &countWriter{w: f, n: count}
+	}
+	if _, err := debruijn.WriteParallel(w, resume, workers); err != nil {
+		f.Close()
+		return err
+	}
+	return closeFile(f, fsync)
+}
+
+// startWord parses the argument to -start, four bytes giving a Lyndon word
+// repeated to length 4, and returns the offset at which the word begins.
+func startWord(s string) (int64, error) {
+	f := strings.Split(s, ",")
+	if len(f) != 4 {
+		return 0, errors.New("need four bytes")
+	}
+	var w [4]byte
+	for i, v := range f {
+		b, err := strconv.ParseUint(strings.TrimSpace(v), 0, 8)
+		if err != nil {
+			return 0, err
+		}
+		w[i] = byte(b)
+	}
+	// Take the word itself from its repetitions.
+	word := w[:]
+	if w[0] == w[2] && w[1] == w[3] {
+		word = w[:2]
+		if w[0] == w[1] {
+			word = w[:1]
+		}
+	}
+	return debruijn.WordOffset(word)
+}
+
+// crcWriter is an io.Writer which computes the CRC-32 of the bytes written to
+// it, with the IEEE polynomial as gzip and zip use, which hash/crc32
+// accelerates with the processor's instructions where it can. Each time the
+// output passes a multiple of interval bytes, it prints the checksum of
+// everything so far to stderr, so that a copy checked the same way shows
+// where it first differs.
+type crcWriter struct {
+	interval int64
+	// off is the offset in the output of the next byte, which is the
+	// resume offset at first.
+	off int64
+	crc uint32
+}
+
+func (c *crcWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		k := min(int64(len(p)), c.interval-c.off%c.interval)
+		c.crc = crc32.Update(c.crc, crc32.IEEETable, p[:k])
+		c.off += k
+		p = p[k:]
+		if c.off%c.interval == 0 {
+			c.print()
+		}
+	}
+	return n, nil
+}
+
+// print prints the checksum of the output through the current offset.
+func (c *crcWriter) print() {
+	fmt.Fprintf(os.Stderr, "crc32 %08x through byte %d\n", c.crc, c.off)
+}
+
+// done prints the checksum of the whole output, unless it ended at a multiple
+// of the interval, which has already been printed.
+func (c *crcWriter) done() {
+	if c.off%c.interval != 0 {
+		c.print()
+	}
+}
+
+// hashes gives the digests which -print-hash can compute, by name.
+var hashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"blake2b": func() hash.Hash {
+		// There's no key, so there's no error.
+		h, _ := blake2b.New512(nil)
+		return h
+	},
+}
+
+// printSum prints the hex digest of sum to stderr along with the output name,
+// in the same format as sha256sum. An empty name stands for stdout. If sum is
+// nil, printSum does nothing.
+func printSum(sum hash.Hash, name string) {
+	if sum == nil {
+		return
+	}
+	if name == "" {
+		name = "-"
+	}
+	fmt.Fprintf(os.Stderr, "%x  %s\n", sum.Sum(nil), name)
+}
+
+// excluding returns an iterator over the addresses of addrs which are not in
+// any of the given prefixes.
+func excluding(addrs iter.Seq[netip.Addr], prefixes []netip.Prefix) iter.Seq[netip.Addr] {
+	if len(prefixes) == 0 {
+		return addrs
+	}
+	return func(yield func(netip.Addr) bool) {
+		for addr := range addrs {
+			if !slices.ContainsFunc(prefixes, func(p netip.Prefix) bool { return p.Contains(addr) }) && !yield(addr) {
+				return
+			}
+		}
+	}
+}
+
+// take returns an iterator over the first n addresses of addrs, or all of them
+// if n is negative.
+func take(addrs iter.Seq[netip.Addr], n int64) iter.Seq[netip.Addr] {
+	if n < 0 {
+		return addrs
+	}
+	return func(yield func(netip.Addr) bool) {
+		if n == 0 {
+			return
+		}
+		k := n
+		for addr := range addrs {
+			if !yield(addr) {
+				return
+			}
+			k--
+			if k == 0 {
+				return
+			}
+		}
+	}
+}
+
+// progressMode is the value of -progress: whether to report progress always,
+// never, or only when stderr is a terminal. As a boolean flag, -progress
+// alone means auto.
+type progressMode string
+
+func (m *progressMode) String() string {
+	return string(*m)
+}
+
+func (m *progressMode) Set(s string) error {
+	switch s {
+	case "always", "never", "auto":
+		*m = progressMode(s)
+	case "true":
+		*m = "auto"
+	case "false":
+		*m = "never"
+	default:
+		return errors.New("must be always, never, or auto")
+	}
+	return nil
+}
+
+func (m *progressMode) IsBoolFlag() bool {
+	return true
+}
+
+// byteRate is the value of -rate, a number of bytes per second written like
+// 10MiB/s, 500KB/s, or just 1000000.
+type byteRate int64
+
+func (r *byteRate) String() string {
+	return strconv.FormatInt(int64(*r), 10)
+}
+
+func (r *byteRate) Set(s string) error {
+	n, err := parseBytes(strings.TrimSuffix(s, "/s"))
+	if err != nil {
+		return err
+	}
+	*r = byteRate(n)
+	return nil
+}
+
+// byteSize is the value of -buf, a positive number of bytes written like
+// 64KiB, 4M, or just 65536, up to a buffer as large as anyone could want.
+type byteSize int
+
+func (b *byteSize) String() string {
+	return strconv.Itoa(int(*b))
+}
+
+func (b *byteSize) Set(s string) error {
+	n, err := parseBytes(s)
+	if err != nil {
+		return err
+	}
+	if n <= 0 || n > 1<<30 {
+		return fmt.Errorf("must be between 1 byte and 1GiB, got %q", s)
+	}
+	*b = byteSize(n)
+	return nil
+}
+
+// byteCount is the value of -crc-interval, a number of bytes written like
+// 1GiB or just 1073741824. Unlike a byteRate, it takes no /s, and unlike a
+// byteSize, it isn't bounded by what a buffer can hold.
+type byteCount int64
+
+func (c *byteCount) String() string {
+	return strconv.FormatInt(int64(*c), 10)
+}
+
+func (c *byteCount) Set(s string) error {
+	n, err := parseBytes(s)
+	if err != nil {
+		return err
+	}
+	*c = byteCount(n)
+	return nil
+}
+
+// byteUnits are the multipliers of the units parseBytes accepts. The bare
+// letters are binary, as with dd.
+var byteUnits = map[string]float64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"M":   1 << 20,
+	"G":   1 << 30,
+	"T":   1 << 40,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+	"kB":  1e3,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+}
+
+// parseBytes parses a number of bytes with an optional unit, like 64KiB or
+// 1.5GB.
+func parseBytes(s string) (int64, error) {
+	if strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("%q is negative", s)
+	}
+	i := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+	switch i {
+	case -1:
+		i = len(s)
+	case 0:
+		return 0, fmt.Errorf("invalid number of bytes %q", s)
+	}
+	unit, ok := byteUnits[s[i:]]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q in %q", s[i:], s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number of bytes %q", s)
+	}
+	n *= unit
+	if n >= 1<<63 {
+		return 0, fmt.Errorf("%q is too many bytes", s)
+	}
+	return int64(n), nil
+}
+
+// toTerminal reports whether output to the file named name, or to stdout if
+// name is empty, would go to a terminal, as with -o /dev/tty.
+func toTerminal(name string) bool {
+	if name == "" {
+		return isTerminal(os.Stdout)
+	}
+	st, err := os.Stat(name)
+	if err != nil || st.Mode()&os.ModeCharDevice == 0 {
+		// Not even a device, so no need to open it and see.
+		return false
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return isTerminal(f)
+}
+
+// isTerminal reports whether f is a terminal, rather than another device
+// like /dev/null. Tests replace it to stand in for one.
+var isTerminal = func(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// report describes the progress of the output to stderr until stop is
+// closed, then logs the number of bytes written, or whatever verb says was
+// done with them, and the time taken and closes done. total is the size of
+// the complete output, or -1 if it isn't known. If tty is true, report
+// redraws a single line four times a second; otherwise, it logs a line once a
+// second. It only samples count, so the writers which add to it do nothing
+// more for reporting.
+func report(verb string, count *atomic.Int64, total int64, tty bool, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	start := time.Now()
+	interval := time.Second
+	if tty {
+		interval = time.Second / 4
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+		case <-stop:
+			if tty {
+				// Clear the line for the logs which follow.
+				fmt.Fprint(os.Stderr, "\r\x1b[K")
+			}
+			log.Printf("%s %d bytes in %v", verb, count.Load(), time.Since(start).Round(time.Second))
+			return
+		}
+		line := progressLine(verb, count.Load(), total, time.Since(start))
+		if tty {
+			fmt.Fprintf(os.Stderr, "\r%s\x1b[K", line)
+		} else {
+			log.Print(line)
+		}
+	}
+}
+
+// progressLine describes n bytes written of total, or of an unknown total if
+// it is negative, in the given time, with verb in place of "wrote".
+func progressLine(verb string, n, total int64, elapsed time.Duration) string {
+	rate := float64(n) / elapsed.Seconds()
+	if total < 0 || rate == 0 {
+		return fmt.Sprintf("%s %d bytes, %.1f MB/s", verb, n, rate/1e6)
+	}
+	eta := time.Duration(float64(total-n) / rate * float64(time.Second))
+	return fmt.Sprintf("%s %d of %d bytes (%.2f%%), %.1f MB/s, ETA %v", verb, n, total, 100*float64(n)/float64(max(total, 1)), rate/1e6, eta.Round(time.Second))
+}
+
+// startProfiles starts CPU profiling into the file named cpu, if it isn't
+// empty. It returns a function which stops it and writes the allocation
+// profile to the file named mem, if that isn't empty, logging any errors. The
+// function does its work only the first time it's called.
+func startProfiles(cpu, mem string) (func(), error) {
+	var cf *os.File
+	if cpu != "" {
+		var err error
+		cf, err = os.Create(cpu)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(cf); err != nil {
+			cf.Close()
+			return nil, err
+		}
+	}
+	return sync.OnceFunc(func() {
+		if cf != nil {
+			pprof.StopCPUProfile()
+			if err := cf.Close(); err != nil {
+				log.Printf("writing CPU profile: %v", err)
+			}
+		}
+		if mem != "" {
+			f, err := os.Create(mem)
+			if err != nil {
+				log.Printf("writing allocation profile: %v", err)
+				return
+			}
+			// Get up-to-date statistics, as go test does.
+			runtime.GC()
+			if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+				log.Printf("writing allocation profile: %v", err)
+			}
+			if err := f.Close(); err != nil {
+				log.Printf("writing allocation profile: %v", err)
+			}
+		}
+	}), nil
+}
+
+// writeUntil is like opts.WriteFrom, but stops between terms once the
+// deadline passes. It returns the number of bytes written and whether it
+// stopped before the end of the output.
+func writeUntil(w io.Writer, opts debruijn.Options, g *debruijn.Generator, deadline time.Time) (int64, bool, error) {
+	// A chunk is short enough to stop soon after the deadline, even at a
+	// slow -rate, and long enough that checking the clock costs nothing.
+	const chunk = 1 << 16
+	total, err := g.Size(debruijn.Binary)
+	if err != nil {
+		return 0, false, err
+	}
+	left := opts.Limit
+	var written int64
+	for {
+		if left == 0 || g.Offset() >= uint64(total) {
+			return written, false, nil
+		}
+		if !now().Before(deadline) {
+			return written, true, nil
+		}
+		part := opts
+		part.Limit = chunk
+		if left > 0 {
+			part.Limit = min(chunk, left)
+		}
+		at := g.Offset()
+		n, err := part.WriteFrom(w, g)
+		written += n
+		if err != nil {
+			return written, false, err
+		}
+		if left > 0 {
+			left -= int64(g.Offset() - at)
+		}
+		// Only the first chunk can begin the output without a separator.
+		opts.Skip = 0
+	}
+}
+
+// openEvents opens the destination of -progress-json, a file descriptor number
+// or the name of a file to create.
+func openEvents(dest string) (io.WriteCloser, error) {
+	if fd, err := strconv.ParseUint(dest, 10, 31); err == nil {
+		f := os.NewFile(uintptr(fd), "fd "+dest)
+		if f == nil {
+			return nil, fmt.Errorf("invalid file descriptor %s", dest)
+		}
+		return f, nil
+	}
+	return os.Create(dest)
+}
+
+// progressEvent is a JSON event of -progress-json. Total is -1 if the size
+// of the output isn't known.
+type progressEvent struct {
+	Bytes     int64 `json:"bytes"`
+	Total     int64 `json:"total"`
+	Terms     int64 `json:"terms"`
+	ElapsedMS int64 `json:"elapsed_ms"`
+}
+
+// finalEvent is the last event of -progress-json, describing how the run
+// ended.
+type finalEvent struct {
+	progressEvent
+	Done   bool   `json:"done"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Signal string `json:"signal,omitempty"`
+}
+
+// events writes the events of -progress-json.
+type events struct {
+	w     io.WriteCloser
+	count *atomic.Int64
+	// terms counts terms if they aren't just the bytes divided by width.
+	terms *atomic.Int64
+	width int64
+	total int64
+	start time.Time
+	// once makes sure only one final event is written even if a signal
+	// arrives as the run ends.
+	once sync.Once
+}
+
+// run writes an event every interval until stop is closed, then closes done.
+func (e *events) run(interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	enc := json.NewEncoder(e.w)
+	for {
+		select {
+		case <-tick.C:
+		case <-stop:
+			return
+		}
+		// The events are for monitoring, which shouldn't fail the run.
+		enc.Encode(e.event())
+	}
+}
+
+// event samples the progress of the output.
+func (e *events) event() progressEvent {
+	ev := progressEvent{
+		Bytes:     e.count.Load(),
+		Total:     e.total,
+		ElapsedMS: time.Since(e.start).Milliseconds(),
+	}
+	if e.terms != nil {
+		ev.Terms = e.terms.Load()
+	} else {
+		ev.Terms = ev.Bytes / e.width
+	}
+	return ev
+}
+
+// final writes the final event for a run which ended with err or was stopped
+// by sig, then closes the destination.
+func (e *events) final(err error, sig os.Signal) {
+	e.once.Do(func() {
+		ev := finalEvent{progressEvent: e.event(), Done: true}
+		// The status is the one conip exits with.
+		switch {
+		case err != nil:
+			ev.Status = statusOf(err)
+			if !errors.As(err, new(exitStatus)) {
+				ev.Error = err.Error()
+			}
+		case sig != nil:
+			ev.Status = int(interruptStatus(sig))
+		}
+		if sig != nil {
+			ev.Signal = sig.String()
+		}
+		json.NewEncoder(e.w).Encode(ev)
+		e.w.Close()
+	})
+}
+
+var (
+	signalMu    sync.Mutex
+	signalFuncs []func(os.Signal)
+	signalStop  func(os.Signal)
+	signalOnce  sync.Once
+)
+
+// atSignal arranges for f to be called if SIGINT or SIGTERM arrives, after
+// the functions registered later, as deferred calls run, before the program
+// exits with the status for the signal, 130 for SIGINT or 143 for SIGTERM.
+func atSignal(f func(os.Signal)) {
+	signalMu.Lock()
+	signalFuncs = append(signalFuncs, f)
+	signalMu.Unlock()
+	notifySignals()
+}
+
+// atInterrupt arranges for the first SIGINT or SIGTERM to call stop instead,
+// so that the output can end cleanly. Another signal after that exits as
+// atSignal describes, in case stopping takes too long.
+func atInterrupt(stop func(os.Signal)) {
+	signalMu.Lock()
+	signalStop = stop
+	signalMu.Unlock()
+	notifySignals()
+}
+
+// notifySignals starts handling SIGINT and SIGTERM for atSignal and
+// atInterrupt.
+func notifySignals() {
+	signalOnce.Do(func() {
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			for sig := range sigc {
+				signalMu.Lock()
+				if stop := signalStop; stop != nil {
+					signalStop = nil
+					signalMu.Unlock()
+					stop(sig)
+					continue
+				}
+				for _, f := range slices.Backward(signalFuncs) {
+					f(sig)
+				}
+				os.Exit(int(interruptStatus(sig)))
+			}
+		}()
+	})
+}
+
+// errInterrupted is the error of a stopWriter after a signal.
+var errInterrupted = errors.New("interrupted")
+
+// stopWriter is an io.Writer that passes writes through to w, counting the
+// bytes in n, until stop is called. Writes after that fail with
+// errInterrupted, so that the output ends with what came before.
+type stopWriter struct {
+	w       io.Writer
+	n       int64
+	sig     os.Signal
+	stopped atomic.Bool
+}
+
+func (s *stopWriter) Write(p []byte) (int, error) {
+	if s.stopped.Load() {
+		return 0, errInterrupted
+	}
+	c, err := s.w.Write(p)
+	s.n += int64(c)
+	return c, err
+}
+
+// stop makes later writes to s fail, noting sig as the reason.
+func (s *stopWriter) stop(sig os.Signal) {
+	s.sig = sig
+	s.stopped.Store(true)
+}
+
+// signal returns the signal which stopped s, or nil if none has.
+func (s *stopWriter) signal() os.Signal {
+	if !s.stopped.Load() {
+		return nil
+	}
+	return s.sig
+}
+
+// interruptStatus is the exit status for the signal sig, 128 plus its number
+// as shells report it.
+func interruptStatus(sig os.Signal) exitStatus {
+	if n, ok := sig.(syscall.Signal); ok {
+		return exitStatus(128 + int(n))
+	}
+	return 1
+}
+
+// termWriter is an io.Writer that passes writes through to w, storing in n
+// after each the number of terms g has emitted since its offset was start,
+// so that n can be sampled without touching g.
+type termWriter struct {
+	w     io.Writer
+	g     *debruijn.Generator
+	start uint64
+	n     *atomic.Int64
+}
+
+func (t *termWriter) Write(p []byte) (int, error) {
+	c, err := t.w.Write(p)
+	t.n.Store(int64(t.g.Offset() - t.start))
+	return c, err
+}
+
+// milestoneWriter is an io.Writer that passes writes through to w, logging
+// after each whenever g has begun a new block of the Lyndon words which begin
+// with the same term. Each block begins with the one-element word of its
+// term, so g's count of those tells which block it's in. The writes are slabs
+// of output, so checking there rather than for each term keeps logging out of
+// the generation loop.
+type milestoneWriter struct {
+	w io.Writer
+	g *debruijn.Generator
+	// seen is the number of blocks logged, and off is the offset in the
+	// output of the next write.
+	seen int64
+	off  int64
+}
+
+func (m *milestoneWriter) Write(p []byte) (int, error) {
+	c, err := m.w.Write(p)
+	m.off += int64(c)
+	k := m.g.WordCount(1)
+	if k < m.seen {
+		// A rotated generator counts again from the start once it laps.
+		m.seen = 0
+	}
+	for ; m.seen < k; m.seen++ {
+		log.Printf("reached the Lyndon words beginning with %d by byte %d", m.seen, m.off)
+	}
+	return c, err
+}
+
+// rateWriter is an io.Writer that passes writes through to w at an average of
+// at most rate bytes per second, in pieces of up to burst bytes. It is a token
+// bucket holding burst bytes, or the bytes of the time it can oversleep if
+// that's more, so that a burst goes through at once after a pause. It sleeps
+// until the bucket has refilled enough for each write rather than polling.
+type rateWriter struct {
+	w      io.Writer
+	rate   float64
+	burst  int
+	size   float64
+	tokens float64
+	last   time.Time
+}
+
+// now and sleep are the clock by which -rate paces the output and -duration
+// ends it. Tests replace them with a clock of their own.
+var (
+	now   = time.Now
+	sleep = time.Sleep
+)
+
+// sleepSlack bounds how much longer than asked a sleep takes, typically about
+// a millisecond beyond short ones. The bucket holds at least this much time's
+// worth, so that the tokens refilled while oversleeping aren't lost.
+const sleepSlack = 10 * time.Millisecond
+
+// newRateWriter returns a rateWriter whose bucket starts full.
+func newRateWriter(w io.Writer, rate int64, burst int) *rateWriter {
+	burst = max(burst, 1)
+	size := max(float64(burst), float64(rate)*sleepSlack.Seconds())
+	return &rateWriter{w: w, rate: float64(rate), burst: burst, size: size, tokens: size, last: now()}
+}
+
+func (r *rateWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		k := min(len(p), r.burst)
+		r.refill()
+		if need := float64(k) - r.tokens; need > 0 {
+			sleep(time.Duration(need / r.rate * float64(time.Second)))
+			r.refill()
+		}
+		r.tokens -= float64(k)
+		c, err := r.w.Write(p[:k])
+		n += c
+		if err != nil {
+			return n, err
+		}
+		p = p[k:]
+	}
+	return n, nil
+}
+
+// refill adds the tokens accrued since the last refill.
+func (r *rateWriter) refill() {
+	t := now()
+	r.tokens = min(r.size, r.tokens+t.Sub(r.last).Seconds()*r.rate)
+	r.last = t
+}
+
+// countWriter passes writes through to w, adding the number of bytes written
+// to n.
+type countWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	k, err := c.w.Write(p)
+	c.n.Add(int64(k))
+	return k, err
+}
+
+// WriteAt passes the write through to w, which must be an io.WriterAt.
+func (c *countWriter) WriteAt(p []byte, off int64) (int, error) {
+	k, err := c.w.(io.WriterAt).WriteAt(p, off)
+	c.n.Add(int64(k))
+	return k, err
+}
+
+// pipeWriter is an io.Writer that passes writes through to w until the reader
+// at the other end of w goes away, after which it discards them.
+type pipeWriter struct {
+	w      io.Writer
+	broken bool
+}
+
+func (p *pipeWriter) Write(b []byte) (int, error) {
+	if p.broken {
+		return len(b), nil
+	}
+	n, err := p.w.Write(b)
+	if errors.Is(err, syscall.EPIPE) {
+		p.broken = true
+		return len(b), nil
+	}
+	return n, err
+}
+
+// errHead is the error from a headWriter which has written all it should.
+var errHead = errors.New("reached the -head limit")
+
+// headWriter is an io.Writer that passes the first n bytes written to it
+// through to w, then fails with errHead.
+type headWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if h.n <= 0 {
+		return 0, errHead
+	}
+	k := int(min(int64(len(p)), h.n))
+	c, err := h.w.Write(p[:k])
+	h.n -= int64(c)
+	if err == nil && c < len(p) {
+		err = errHead
+	}
+	return c, err
+}
+
+// errClobber is the error with which -no-clobber refuses to overwrite the
+// named file.
+func errClobber(name string) error {
+	return fmt.Errorf("%s: %w; not overwriting it with -no-clobber", name, os.ErrExist)
+}
+
+// createOutput creates the named file for output, truncating it if it exists
+// unless noClobber is true, in which case it fails instead.
+func createOutput(name string, noClobber bool) (*os.File, error) {
+	if !noClobber {
+		return os.Create(name)
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+	if errors.Is(err, os.ErrExist) {
+		return nil, errClobber(name)
+	}
+	return f, err
+}
+
+// createTemp creates a temporary file in the same directory as the named
+// file, so that it can be renamed over it.
+func createTemp(name string) (*os.File, error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp makes the file private, unlike os.Create.
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// moveOutput renames the file tmp to name. If noClobber is true, it fails
+// instead if name exists, and tmp remains.
+func moveOutput(tmp, name string, noClobber bool) error {
+	if !noClobber {
+		return os.Rename(tmp, name)
+	}
+	// A hard link fails if the target exists, which a rename doesn't.
+	if err := os.Link(tmp, name); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return errClobber(name)
+		}
+		return err
+	}
+	return os.Remove(tmp)
+}
+
+// errSync wraps errors syncing the output to storage.
+var errSync = errors.New("output not synced to storage")
+
+// syncCloser is a file which can sync its contents to storage.
+type syncCloser interface {
+	io.Closer
+	Sync() error
+}
+
+// syncFile syncs f to storage for -fsync. Tests replace it to see when that
+// happens or to make it fail.
+var syncFile = func(f syncCloser) error {
+	return f.Sync()
+}
+
+// atomicFile is the temporary file to which -atomic writes the output until
+// commit renames it into place.
+type atomicFile struct {
+	mu sync.Mutex
+	// name is the name of the temporary file, or empty once it has been
+	// renamed or removed.
+	name             string
+	dest             string
+	noClobber, fsync bool
+}
+
+// remove removes the temporary file unless it has been committed. It does
+// nothing if a is nil.
+func (a *atomicFile) remove() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.name != "" {
+		os.Remove(a.name)
+		a.name = ""
+	}
+}
+
+// commit moves the complete output into place, then syncs the directory
+// holding it for -fsync. It does nothing if a is nil.
+func (a *atomicFile) commit() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.name == "" {
+		return nil
+	}
+	err := moveOutput(a.name, a.dest, a.noClobber)
+	if err != nil {
+		os.Remove(a.name)
+	}
+	a.name = ""
+	if err == nil && a.fsync {
+		err = syncDir(a.dest)
+	}
+	return err
+}
+
+// closeFile closes f, first syncing it to storage if fsync is true. It closes
+// f even if syncing fails.
+func closeFile(f syncCloser, fsync bool) error {
+	if fsync {
+		if err := syncFile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("%w: %w", errSync, err)
+		}
+	}
+	return f.Close()
+}
+
+// syncedFile is a file which syncs itself to storage when closed.
+type syncedFile struct {
+	*os.File
+}
+
+func (f syncedFile) Close() error {
+	return closeFile(f.File, true)
+}
+
+// syncDir syncs the directory containing the named file to storage, so that
+// a rename into it is durable.
+func syncDir(name string) error {
+	d, err := os.Open(filepath.Dir(name))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errSync, err)
+	}
+	return closeFile(d, true)
+}
+
+// fileTail returns the last n bytes of the named file, or all of it if it is
+// shorter, along with their offset in the file. If end is not negative, the
+// bytes are instead the last n before offset end, and the file must be at
+// least that long. If the file doesn't exist, it returns an offset of zero and
+// no bytes, or an error if end is positive.
+func fileTail(name string, n, end int64) (int64, []byte, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) && end <= 0 {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return 0, nil, err
+	}
+	if end < 0 {
+		end = st.Size()
+	}
+	if st.Size() < end {
+		return 0, nil, fmt.Errorf("%s has only %d bytes, fewer than the resume offset %d", name, st.Size(), end)
+	}
+	off := max(end-n, 0)
+	tail := make([]byte, end-off)
+	if _, err := f.ReadAt(tail, off); err != nil {
+		return 0, nil, err
+	}
+	return off, tail, nil
+}
+
+// errTail is the error from a tailWriter whose output differs from the file.
+var errTail = errors.New("the file does not end with the expected output")
+
+// tailWriter is an io.Writer that compares the first bytes written to it with
+// want, the bytes at offset off of the file being appended to, failing with
+// errTail if they differ, and passes the rest through to w.
+type tailWriter struct {
+	w    io.Writer
+	want []byte
+	off  int64
+	// truncate, if not nil, is called once all of want has matched, before
+	// anything is written, to cut off what follows it in the file.
+	truncate func() error
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	k := min(len(p), len(t.want))
+	for i := range k {
+		if p[i] != t.want[i] {
+			return i, fmt.Errorf("%w: byte %d is %q rather than %q", errTail, t.off+int64(i), t.want[i], p[i])
+		}
+	}
+	t.want = t.want[k:]
+	t.off += int64(k)
+	if k == len(p) {
+		return k, nil
+	}
+	if err := t.cut(); err != nil {
+		return k, err
+	}
+	n, err := t.w.Write(p[k:])
+	return k + n, err
+}
+
+// cut calls truncate the first time.
+func (t *tailWriter) cut() error {
+	f := t.truncate
+	t.truncate = nil
+	if f == nil {
+		return nil
+	}
+	return f()
+}
+
+// done reports an error if the output ended before all of want was
+// compared, which means the file is longer than the output.
+func (t *tailWriter) done() error {
+	if len(t.want) > 0 {
+		return fmt.Errorf("%w: the output ends at byte %d, before the end of the file", errTail, t.off)
+	}
+	return t.cut()
+}
+
+// checkFile reads back the file named name from offset off, through
+// decompress if it isn't nil, and logs whether it holds the output. If order
+// is positive, the file should be the complete binary encoding of B(256,
+// order), which checkFile verifies as the verify command does; otherwise, it
+// compares the file with what produce writes. With prog, it reports its
+// progress through the total bytes expected as report does. It returns
+// exitStatus(1) if the file doesn't hold the output or can't be read.
+func checkFile(name string, off int64, decompress func(io.Reader) (io.Reader, error), order int, produce func(io.Writer) error, total int64, prog, quiet bool) error {
+	f, err := os.Open(name)
+	if err != nil {
+		log.Printf("check failed: %v", err)
+		return exitStatus(1)
+	}
+	defer f.Close()
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		log.Printf("check failed: %v", err)
+		return exitStatus(1)
+	}
+	var r io.Reader = bufio.NewReaderSize(f, 1<<20)
+	if decompress != nil {
+		r, err = decompress(r)
+		if err != nil {
+			log.Printf("check failed: %v", err)
+			return exitStatus(1)
+		}
+	}
+	var n atomic.Int64
+	r = &countReader{r: r, n: &n}
+	// stop ends the progress report before the result.
+	stop := func() {}
+	if prog {
+		ch := make(chan struct{})
+		done := make(chan struct{})
+		go report("checked", &n, total, isTerminal(os.Stderr), ch, done)
+		stop = func() {
+			close(ch)
+			<-done
+		}
+	}
+	if order > 0 {
+		rep, err := debruijn.VerifyReader(r, order)
+		stop()
+		if err != nil {
+			log.Printf("check failed: reading %s failed after %d bytes: %v", name, rep.Length, err)
+			return exitStatus(1)
+		}
+		if !rep.OK() {
+			log.Printf("check failed: %d bytes, %d windows, %d missing, %d duplicates; first anomaly at offset %d", rep.Length, rep.Windows, rep.Missing, rep.Duplicates, rep.FirstAnomaly)
+			return exitStatus(1)
+		}
+		if !quiet {
+			log.Printf("check passed: %d bytes, %d windows, each exactly once", rep.Length, rep.Windows)
+		}
+		return nil
+	}
+	c := &compareWriter{r: r, off: off}
+	err = produce(c)
+	if err == nil || errors.Is(err, errHead) {
+		err = c.done()
+	}
+	stop()
+	if errors.Is(err, errDiffer) {
+		log.Printf("check failed: %s differs from the output at offset %d", name, c.off)
+		return exitStatus(1)
+	}
+	if err != nil {
+		log.Printf("check failed: %v", err)
+		return exitStatus(1)
+	}
+	if !quiet {
+		log.Printf("check passed: %d bytes match the output", n.Load())
+	}
+	return nil
+}
+
+// errDiffer is the error from a compareWriter whose output differs from what
+// it reads.
+var errDiffer = errors.New("the file differs from the output")
+
+// compareWriter is an io.Writer that compares the bytes written to it with
+// those read from r, failing with errDiffer at the first that differ or where
+// r ends. off is the offset of the next byte to compare.
+type compareWriter struct {
+	r   io.Reader
+	off int64
+	buf []byte
+}
+
+func (c *compareWriter) Write(p []byte) (int, error) {
+	if len(c.buf) < len(p) {
+		c.buf = make([]byte, len(p))
+	}
+	b := c.buf[:len(p)]
+	k, err := io.ReadFull(c.r, b)
+	for i := range k {
+		if b[i] != p[i] {
+			c.off += int64(i)
+			return i, errDiffer
+		}
+	}
+	c.off += int64(k)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// The file is shorter than the output.
+		err = errDiffer
+	}
+	return k, err
+}
+
+// done reports errDiffer if r holds more than was written.
+func (c *compareWriter) done() error {
+	var b [1]byte
+	k, err := io.ReadFull(c.r, b[:])
+	if k > 0 {
+		return errDiffer
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+// countReader passes reads through from r, adding the number of bytes read
+// to n.
+type countReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	k, err := c.r.Read(p)
+	c.n.Add(int64(k))
+	return k, err
+}
+
+// skipWriter is an io.Writer that discards the first skip bytes written to it
+// and passes the rest through to w.
+type skipWriter struct {
+	w    io.Writer
+	skip int64
+}
+
+func (s *skipWriter) Write(p []byte) (int, error) {
+	if s.skip >= int64(len(p)) {
+		s.skip -= int64(len(p))
+		return len(p), nil
+	}
+	k := s.skip
+	s.skip = 0
+	n, err := s.w.Write(p[k:])
+	return int(k) + n, err
+}
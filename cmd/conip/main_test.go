@@ -0,0 +1,2281 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/conip/debruijn"
+)
+
+// redirect points *std, os.Stdout or os.Stderr, at a new file for the rest of
+// the test and returns it.
+func redirect(t *testing.T, std **os.File) *os.File {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "std")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := *std
+	*std = f
+	t.Cleanup(func() {
+		*std = old
+		f.Close()
+	})
+	return f
+}
+
+// contents returns what has been written to f.
+func contents(t *testing.T, f *os.File) string {
+	t.Helper()
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// fakeTerminal makes isTerminal report that f, and nothing else, is a
+// terminal for the rest of the test, or that nothing is if f is nil.
+func fakeTerminal(t *testing.T, f *os.File) {
+	old := isTerminal
+	isTerminal = func(g *os.File) bool { return f != nil && g == f }
+	t.Cleanup(func() { isTerminal = old })
+}
+
+func TestValidateFlags(t *testing.T) {
+	// Each case breaks one of the rules of validate and nothing else, so
+	// that it's the rule we expect which refuses it. TestBinaryTerminal
+	// has the rule for terminals.
+	fakeTerminal(t, nil)
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-format", "dot", "-bin"}, "-format cannot be used"},
+		{[]string{"-encoding", "dot", "-hex"}, "-encoding cannot be used"},
+		{[]string{"-format", "nonexistent"}, "unknown -format"},
+		{[]string{"-encoding", "nonexistent"}, "unknown -encoding"},
+		{[]string{"-order", "0"}, "order must be at least 1"},
+		{[]string{"-alphabet", "0"}, "alphabet size must be at least 1"},
+		{[]string{"-resume", "-1"}, "resume offset must not be negative"},
+		{[]string{"-alphabet", "16", "-start", "0,0,0,1"}, "-alphabet other than 256"},
+		{[]string{"-nowrap", "-ipv4"}, "-nowrap cannot"},
+		{[]string{"-bin", "-ipv4"}, "-bin and -ipv4"},
+		{[]string{"-hex", "-bin"}, "-hex cannot"},
+		{[]string{"-n", "-ipv4"}, "-n cannot"},
+		{[]string{"-pad", "-hex"}, "-pad cannot"},
+		{[]string{"-crlf", "-n"}, "-crlf cannot"},
+		{[]string{"-json", "-hex"}, "-json cannot be used with -sep"},
+		{[]string{"-json", "-head", "10"}, "-json cannot be used with -resume"},
+		{[]string{"-sep", ",", "-n"}, "-sep cannot be used with -n"},
+		{[]string{"-sep=", "-bin"}, "an empty -sep"},
+		{[]string{"-sep=", "-encoding", "dot"}, "an empty -sep"},
+		{[]string{"-exclude", "10.0.0.0/8", "-size"}, "-size cannot account for -exclude"},
+		{[]string{"-prefix", "10.0.0.0/8", "-count"}, "-prefix cannot"},
+		{[]string{"-start-addr", "1.2.3.4", "-order", "3"}, "-start-addr requires"},
+		{[]string{"-start", "0,0,0,1", "-start-addr", "1.2.3.4"}, "-start requires"},
+		{[]string{"-print-seed"}, "-print-seed requires"},
+		{[]string{"-workers", "2", "-xor", "1"}, "-workers greater than 1 cannot"},
+		{[]string{"-reverse", "-shards", "2"}, "-reverse cannot"},
+		{[]string{"-gzip", "-zstd"}, "-gzip and -zstd"},
+		{[]string{"-from", "0.0.0.1", "-skip", "2"}, "-from and -until require"},
+		{[]string{"-until", "0.0.0.1", "-order", "3"}, "-from and -until require"},
+		{[]string{"-skip", "-1"}, "-skip must not be negative"},
+		{[]string{"-wrap", "-1"}, "-wrap must not be negative"},
+		{[]string{"-shuffle", "1", "-count"}, "-shuffle cannot"},
+		{[]string{"-append"}, "-append requires"},
+		{[]string{"-append", "-o", "out", "-append-check", "-1"}, "-append-check must not be negative"},
+		{[]string{"-no-clobber"}, "-no-clobber and -atomic require"},
+		{[]string{"-duration", "-1s"}, "-duration must not be negative"},
+		{[]string{"-check"}, "-check requires"},
+		{[]string{"-crc-interval", "10", "-mmap"}, "-crc-interval cannot"},
+		{[]string{"-progress-json", "events", "-progress-interval", "0"}, "-progress-interval must be positive"},
+		{[]string{"-progress-json", "1"}, "-progress-json 1 cannot"},
+		{[]string{"-q", "-v"}, "-q cannot"},
+		{[]string{"-print-hash", "sha256", "-o", "out"}, "-print-hash cannot"},
+		{[]string{"-print-hash", "nonexistent"}, "unknown -print-hash"},
+		{[]string{"-discard", "-o", "out"}, "-discard cannot"},
+		{[]string{"-fsync"}, "-fsync requires"},
+		{[]string{"-atomic", "-shards", "2", "-o", "out"}, "-atomic cannot"},
+		{[]string{"-rate", "1MB", "-mmap", "-bin", "-o", "out"}, "-rate must not be negative"},
+		{[]string{"-workers", "2"}, "-workers greater than 1 requires"},
+		{[]string{"-addr", ":0", "-o", "out"}, "-addr cannot"},
+		{[]string{"-limit", "5", "-resume", "1"}, "-limit and -limit-bytes cannot"},
+		{[]string{"-limit", "5", "-alphabet", "300", "-bin"}, "-limit and -limit-bytes cannot"},
+		{[]string{"-head", "5", "-size"}, "-head cannot"},
+		{[]string{"-mmap"}, "-mmap requires"},
+		{[]string{"-tee"}, "-tee requires"},
+		{[]string{"-sha256", "-workers", "2", "-bin", "-o", "out"}, "-sha256 cannot"},
+		{[]string{"-shards", "2"}, "-shards greater than 1 requires -o"},
+		{[]string{"-shards", "2", "-order", "8", "-o", "out"}, "-shards greater than 1 requires order at most 7"},
+		{[]string{"-dry-run", "-size"}, "-dry-run cannot"},
+		{[]string{"-count", "-o", "out"}, "-count cannot"},
+	}
+	for _, c := range cases {
+		f, err := parseGenerateFlags("generate", c.args)
+		if err != nil {
+			t.Fatalf("%v: %v", c.args, err)
+		}
+		err = f.validate()
+		if !errors.As(err, new(usageError)) || !strings.HasPrefix(err.Error(), c.want) {
+			t.Errorf("%v: got %v, want a usage error beginning %q", c.args, err, c.want)
+		}
+	}
+	for _, args := range [][]string{
+		{},
+		{"-bin", "-o", "out"},
+		{"-format", "quad", "-cidr", "24", "-shuffle", "1"},
+		{"-workers", "4", "-bin", "-o", "out"},
+		{"-json", "-limit", "10"},
+		{"-sep=", "-hex"},
+		{"-from", "10.0.0.0", "-until", "10.0.0.255", "-gzip"},
+	} {
+		f, err := parseGenerateFlags("generate", args)
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		if err := f.validate(); err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+}
+
+func TestLibraryRules(t *testing.T) {
+	// The rules for the options the library takes are its own, which
+	// validate leaves to Options.Validate, and whose errors are usage
+	// errors all the same.
+	fakeTerminal(t, nil)
+	cases := []struct {
+		args []string
+		opts debruijn.Options
+	}{
+		{[]string{"-format", "bin", "-sep", ","}, debruijn.Options{Format: debruijn.Binary, Separator: ","}},
+		{[]string{"-ipv4", "-order", "3"}, debruijn.Options{Format: debruijn.IPv4, Order: 3}},
+		{[]string{"-reverse", "-start-addr", "1.2.3.4"}, debruijn.Options{Reverse: true, Rotate: 1}},
+		{[]string{"-prefix", "10.0.0.0/8", "-shuffle", "1", "-ipv4"}, debruijn.Options{Format: debruijn.IPv4, Within: netip.MustParsePrefix("10.0.0.0/8"), Shuffle: true}},
+		{[]string{"-cidr", "24"}, debruijn.Options{CIDR: true, Bits: 24}},
+		{[]string{"-cidr", "33", "-ipv4"}, debruijn.Options{Format: debruijn.IPv4, CIDR: true, Bits: 33}},
+		{[]string{"-exclude", "10.0.0.0/8"}, debruijn.Options{Exclude: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}},
+		{[]string{"-alphabet", "300", "-bin", "-xor", "1"}, debruijn.Options{Alphabet: 300, Mask: 1}},
+		{[]string{"-alphabet", "70000"}, debruijn.Options{Alphabet: 70000}},
+		{[]string{"-wrap", "4", "-n"}, debruijn.Options{Format: debruijn.Lines, Wrap: 4}},
+		{[]string{"-level", "3"}, debruijn.Options{Level: 3}},
+	}
+	for _, c := range cases {
+		f, err := parseGenerateFlags("generate", c.args)
+		if err != nil {
+			t.Fatalf("%v: %v", c.args, err)
+		}
+		if err := f.validate(); err != nil {
+			t.Errorf("%v: validate refused it with %v", c.args, err)
+		}
+		want := c.opts.Validate()
+		if want == nil {
+			t.Fatalf("%v: the library accepts %+v", c.args, c.opts)
+		}
+		_, err = newJob(f)
+		if !errors.As(err, new(usageError)) || err.Error() != want.Error() {
+			t.Errorf("%v: got %v, want the usage error %v", c.args, err, want)
+		}
+	}
+}
+
+func TestBinaryTerminal(t *testing.T) {
+	cases := []struct {
+		name  string
+		tty   bool
+		force bool
+		ok    bool
+	}{
+		{"pipe", false, false, true},
+		{"pipe forced", false, true, true},
+		{"terminal", true, false, false},
+		{"terminal forced", true, true, true},
+	}
+	// Every way of asking for binary output is binary all the same.
+	for _, format := range [][]string{{"-bin"}, {"-format", "bin"}, {"-encoding", "binary"}, {"-encoding", "bin"}} {
+		for _, c := range cases {
+			t.Run(strings.Join(format, " ")+" "+c.name, func(t *testing.T) {
+				out := redirect(t, &os.Stdout)
+				if c.tty {
+					fakeTerminal(t, out)
+				} else {
+					fakeTerminal(t, nil)
+				}
+				args := append([]string{"-o", "-", "-limit", "10", "-q"}, format...)
+				if c.force {
+					args = append(args, "-force")
+				}
+				err := run(args)
+				if !c.ok {
+					if !errors.As(err, new(usageError)) {
+						t.Fatalf("got %v, want a usage error", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				st, err := out.Stat()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if st.Size() != 10 {
+					t.Errorf("wrote %d bytes, want 10", st.Size())
+				}
+			})
+		}
+	}
+}
+
+// fakeClock is a clock for now and sleep in which time passes only by step
+// at each reading and by sleeping.
+type fakeClock struct {
+	t    time.Time
+	step time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	t := c.t
+	c.t = c.t.Add(c.step)
+	return t
+}
+
+func (c *fakeClock) sleep(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// useClock makes now and sleep use c for the rest of the test.
+func useClock(t *testing.T, c *fakeClock) {
+	oldNow, oldSleep := now, sleep
+	now, sleep = c.now, c.sleep
+	t.Cleanup(func() { now, sleep = oldNow, oldSleep })
+}
+
+// clockWriter records the bytes written to it and when, by a fakeClock.
+type clockWriter struct {
+	c     *fakeClock
+	b     bytes.Buffer
+	times []time.Time
+	ns    []int
+}
+
+func (w *clockWriter) Write(p []byte) (int, error) {
+	w.times = append(w.times, w.c.t)
+	w.ns = append(w.ns, len(p))
+	return w.b.Write(p)
+}
+
+func TestRateWriter(t *testing.T) {
+	const rate = 1 << 20
+	c := &fakeClock{t: time.Unix(0, 0)}
+	useClock(t, c)
+	cw := &clockWriter{c: c}
+	w := newRateWriter(cw, rate, 4096)
+	want := make([]byte, 10*rate)
+	for i := range want {
+		want[i] = byte(i * 7)
+	}
+	start := c.t
+	for p := want; len(p) > 0; {
+		k := min(len(p), 1000)
+		if _, err := w.Write(p[:k]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[k:]
+	}
+	if !bytes.Equal(cw.b.Bytes(), want) {
+		t.Error("output differs from what was written")
+	}
+	// The bucket starts full, so its size goes through at once, and the
+	// rest at the rate.
+	ideal := time.Duration(float64(len(want)-int(w.size)) / rate * float64(time.Second))
+	if elapsed := c.t.Sub(start); elapsed < ideal*97/100 || elapsed > ideal*103/100 {
+		t.Errorf("took %v, want %v", elapsed, ideal)
+	}
+	// Never more than the bucket ahead of the rate.
+	var sent int
+	for i, at := range cw.times {
+		sent += cw.ns[i]
+		if limit := w.size + at.Sub(start).Seconds()*rate; float64(sent) > limit+1 {
+			t.Fatalf("%d bytes sent by %v, more than %.0f", sent, at.Sub(start), limit)
+		}
+		if cw.ns[i] > w.burst {
+			t.Fatalf("write of %d bytes, more than the burst of %d", cw.ns[i], w.burst)
+		}
+	}
+}
+
+func TestRateOutput(t *testing.T) {
+	useClock(t, &fakeClock{t: time.Unix(0, 0)})
+	dir := t.TempDir()
+	paced, plain := filepath.Join(dir, "paced"), filepath.Join(dir, "plain")
+	if err := run([]string{"-q", "-limit", "100000", "-rate", "64KiB/s", "-o", paced}); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"-q", "-limit", "100000", "-o", plain}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := os.ReadFile(paced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Errorf("-rate wrote %d bytes which differ from the %d without it", len(a), len(b))
+	}
+}
+
+// captureLog collects what is logged for the rest of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	var b bytes.Buffer
+	log.SetOutput(&b)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &b
+}
+
+// generateFile runs conip with args and -o writing to a new file in dir and
+// returns what it wrote.
+func generateFile(t *testing.T, dir string, args ...string) []byte {
+	t.Helper()
+	f, err := os.CreateTemp(dir, "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := run(append([]string{"-q", "-o", f.Name()}, args...)); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// runOutput runs conip with args, writing to stdout, and returns what it wrote
+// and its exit status.
+func runOutput(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	out := redirect(t, &os.Stdout)
+	redirect(t, &os.Stderr)
+	captureLog(t)
+	status := statusOf(run(append([]string{"-q", "-o", "-", "-force"}, args...)))
+	return contents(t, out), status
+}
+
+// decodeOutput runs conip decode with args on a file holding in and returns
+// what it wrote and its exit status.
+func decodeOutput(t *testing.T, in string, args ...string) (string, int) {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "in")
+	if err := os.WriteFile(name, []byte(in), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := redirect(t, &os.Stdout)
+	redirect(t, &os.Stderr)
+	captureLog(t)
+	status := statusOf(run(append(append([]string{"decode"}, args...), name)))
+	return contents(t, out), status
+}
+
+var stoppedRE = regexp.MustCompile(`stopped at the deadline after (\d+) bytes of output, before term (\d+); continue with the same flags and -resume (\d+)`)
+
+func TestDuration(t *testing.T) {
+	for _, format := range [][]string{{"-bin"}, {"-format", "dot"}, {"-hex", "-n"}} {
+		t.Run(strings.Join(format, " "), func(t *testing.T) {
+			dir := t.TempDir()
+			args := append([]string{"-order", "3"}, format...)
+			whole := generateFile(t, dir, args...)
+			// Each reading of the clock is 20 minutes after the last, so
+			// the hour is up after the clock has let the writer through
+			// twice.
+			useClock(t, &fakeClock{t: time.Unix(0, 0), step: 20 * time.Minute})
+			logged := captureLog(t)
+			got := generateFile(t, dir, append(args, "-duration", "1h")...)
+			m := stoppedRE.FindSubmatch(logged.Bytes())
+			if m == nil {
+				t.Fatalf("no resume point in the log %q", logged)
+			}
+			n, _ := strconv.Atoi(string(m[1]))
+			term, _ := strconv.Atoi(string(m[2]))
+			resume, _ := strconv.Atoi(string(m[3]))
+			if n != len(got) || resume != n {
+				t.Errorf("logged %d bytes and -resume %d for %d bytes of output", n, resume, len(got))
+			}
+			if n == 0 || n >= len(whole) || !bytes.Equal(got, whole[:n]) {
+				t.Fatalf("wrote %d bytes which aren't a prefix of the %d of the output", len(got), len(whole))
+			}
+			terms := n
+			if format[0] != "-bin" {
+				// Ended on a term boundary, just before a separator.
+				if c := whole[n]; c != '.' && c != '\n' {
+					t.Errorf("ended before %q, within a term", c)
+				}
+				terms = bytes.Count(got, []byte{whole[n]}) + 1
+			}
+			if term != terms {
+				t.Errorf("logged term %d as next after %d terms", term, terms)
+			}
+			// The rest comes by resuming without the deadline.
+			logged.Reset()
+			useClock(t, &fakeClock{t: time.Unix(0, 0)})
+			rest := generateFile(t, dir, append(args, "-resume", strconv.Itoa(resume))...)
+			if !bytes.Equal(append(got, rest...), whole) {
+				t.Errorf("output and its resumption of %d and %d bytes differ from the whole %d", len(got), len(rest), len(whole))
+			}
+		})
+	}
+}
+
+func TestDurationLimit(t *testing.T) {
+	dir := t.TempDir()
+	for _, limit := range []int{100000, 200000} {
+		want := generateFile(t, dir, "-limit", strconv.Itoa(limit))
+		useClock(t, &fakeClock{t: time.Unix(0, 0), step: 20 * time.Minute})
+		logged := captureLog(t)
+		got := generateFile(t, dir, "-limit", strconv.Itoa(limit), "-duration", "1h")
+		m := stoppedRE.FindSubmatch(logged.Bytes())
+		// The deadline comes after 2 chunks of 1<<16 terms, so a smaller
+		// limit comes first.
+		if stopped := limit > 2<<16; stopped != (m != nil) {
+			t.Errorf("-limit %d: stopped at the deadline is %t, want %t", limit, m != nil, stopped)
+		}
+		if m == nil {
+			if !bytes.Equal(got, want) {
+				t.Errorf("-limit %d: output of %d bytes differs from %d without -duration", limit, len(got), len(want))
+			}
+			continue
+		}
+		if term, _ := strconv.Atoi(string(m[2])); term != 2<<16 || !bytes.Equal(got, want[:len(got)]) {
+			t.Errorf("-limit %d: stopped before term %d after %d bytes which aren't a prefix of the output", limit, term, len(got))
+		}
+	}
+}
+
+// syncEvent is a call to syncFile: the name of the file synced, its size
+// then, and whether the output file existed then.
+type syncEvent struct {
+	name   string
+	size   int64
+	exists bool
+}
+
+// recordSyncs makes syncFile record its calls in the returned slice instead
+// of syncing for the rest of the test, failing with fail if it isn't nil.
+func recordSyncs(t *testing.T, out string, fail error) *[]syncEvent {
+	var events []syncEvent
+	old := syncFile
+	syncFile = func(f syncCloser) error {
+		file := f.(*os.File)
+		st, err := file.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = os.Stat(out)
+		events = append(events, syncEvent{name: file.Name(), size: st.Size(), exists: err == nil})
+		return fail
+	}
+	t.Cleanup(func() { syncFile = old })
+	return &events
+}
+
+func TestFsync(t *testing.T) {
+	dir := t.TempDir()
+	want := int64(len(generateFile(t, dir, "-limit", "100000")))
+	out := filepath.Join(dir, "synced")
+
+	events := recordSyncs(t, out, nil)
+	if err := run([]string{"-q", "-limit", "100000", "-fsync", "-o", out}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*events) != 1 || (*events)[0] != (syncEvent{out, want, true}) {
+		t.Errorf("-fsync synced %+v, want only the flushed output", *events)
+	}
+
+	os.Remove(out)
+	*events = nil
+	if err := run([]string{"-q", "-limit", "100000", "-fsync", "-atomic", "-o", out}); err != nil {
+		t.Fatal(err)
+	}
+	// The buffer is flushed before the temporary file is synced, which is
+	// before it is renamed to the output, which is before the directory is
+	// synced.
+	if len(*events) != 2 {
+		t.Fatalf("-fsync -atomic synced %+v, want the file and the directory", *events)
+	}
+	if e := (*events)[0]; filepath.Dir(e.name) != dir || e.name == out || e.size != want || e.exists {
+		t.Errorf("first synced %+v, want the temporary file of %d bytes before the rename", e, want)
+	}
+	if e := (*events)[1]; e.name != dir || !e.exists {
+		t.Errorf("then synced %+v, want the directory after the rename", e)
+	}
+}
+
+func TestFsyncError(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "synced")
+	recordSyncs(t, out, errors.New("no storage"))
+	err := run([]string{"-q", "-limit", "100000", "-fsync", "-o", out})
+	if !errors.Is(err, errSync) || !errors.As(err, new(outputError)) {
+		t.Fatalf("got %v, want an output error from syncing", err)
+	}
+	st, serr := os.Stat(out)
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	if msg := fmt.Sprintf("after %d bytes", st.Size()); !strings.Contains(err.Error(), msg) {
+		t.Errorf("error %q doesn't say %q", err, msg)
+	}
+}
+
+// files returns the names of the files in dir.
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestNoClobber(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	if err := run([]string{"-q", "-limit", "1000", "-no-clobber", "-o", out}); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"-no-clobber"}, {"-no-clobber", "-atomic"}} {
+		err := run(append([]string{"-q", "-limit", "2000", "-o", out}, args...))
+		if !errors.Is(err, os.ErrExist) {
+			t.Errorf("%v over an existing file: got %v, want it to refuse", args, err)
+		}
+		if status := statusOf(err); status != 3 {
+			t.Errorf("%v over an existing file: exit status %d, want 3", args, status)
+		}
+		if got, _ := os.ReadFile(out); !bytes.Equal(got, want) {
+			t.Errorf("%v changed the existing file", args)
+		}
+	}
+	if names := files(t, dir); len(names) != 1 {
+		t.Errorf("left behind %q", names)
+	}
+}
+
+func TestCreateOutputExists(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	if err := os.WriteFile(out, []byte("theirs"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	_, err := createOutput(out, true)
+	if !errors.Is(err, os.ErrExist) {
+		t.Errorf("got %v, want it to wrap os.ErrExist", err)
+	}
+	want := out + ": file already exists; not overwriting it with -no-clobber"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+	if err := moveOutput(out, out, true); !errors.Is(err, os.ErrExist) || err.Error() != want {
+		t.Errorf("moving onto it: got %v, want %q", err, want)
+	}
+}
+
+func TestNoClobberRace(t *testing.T) {
+	// The output appears while -atomic writes the temporary file, so the
+	// rename must not replace it.
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	old := syncFile
+	syncFile = func(f syncCloser) error {
+		if f.(*os.File).Name() != dir {
+			if err := os.WriteFile(out, []byte("theirs"), 0o666); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return old(f)
+	}
+	t.Cleanup(func() { syncFile = old })
+	err := run([]string{"-q", "-limit", "1000", "-no-clobber", "-atomic", "-fsync", "-o", out})
+	if !errors.Is(err, os.ErrExist) || !strings.Contains(err.Error(), out+": file already exists; not overwriting it with -no-clobber") {
+		t.Errorf("got %v, want it to refuse", err)
+	}
+	if status := statusOf(err); status != 3 {
+		t.Errorf("exit status %d, want 3", status)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "theirs" {
+		t.Errorf("replaced the file which appeared with %q", got)
+	}
+	if names := files(t, dir); len(names) != 1 {
+		t.Errorf("left behind %q", names)
+	}
+}
+
+func TestAtomic(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	want := generateFile(t, t.TempDir(), "-limit", "100000")
+	if err := os.WriteFile(out, []byte("old contents"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"-q", "-limit", "100000", "-atomic", "-o", out}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, want) {
+		t.Errorf("-atomic wrote %d bytes which differ from the %d of the output", len(got), len(want))
+	}
+	if names := files(t, dir); len(names) != 1 {
+		t.Errorf("left behind %q", names)
+	}
+}
+
+func TestAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	if err := os.WriteFile(out, []byte("old contents"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	recordSyncs(t, out, errors.New("no storage"))
+	if err := run([]string{"-q", "-limit", "100000", "-atomic", "-fsync", "-o", out}); !errors.Is(err, errSync) {
+		t.Errorf("got %v, want the error from syncing", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "old contents" {
+		t.Errorf("failed output replaced the file with %d bytes", len(got))
+	}
+	if names := files(t, dir); len(names) != 1 {
+		t.Errorf("left behind %q", names)
+	}
+}
+
+func TestAtomicInterrupt(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	if err := os.WriteFile(out, []byte("old contents"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	// -rate sleeps once it has written the first burst, which is when the
+	// signal arrives. Waiting for it for real lets the handler stop the
+	// output before the next write.
+	c := &fakeClock{t: time.Unix(0, 0)}
+	useClock(t, c)
+	var once sync.Once
+	sleep = func(d time.Duration) {
+		once.Do(func() {
+			if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(100 * time.Millisecond)
+		})
+		c.sleep(d)
+	}
+	captureLog(t)
+	err := run([]string{"-q", "-rate", "64KiB/s", "-atomic", "-o", out})
+	if err != interruptStatus(syscall.SIGTERM) {
+		t.Errorf("got %v, want the status for SIGTERM", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "old contents" {
+		t.Errorf("interrupted output replaced the file with %d bytes", len(got))
+	}
+	if names := files(t, dir); len(names) != 1 {
+		t.Errorf("left behind %q", names)
+	}
+}
+
+func TestSHA256(t *testing.T) {
+	// The digest is of the uncompressed output, whatever is written.
+	const want = "9955dbd1c520357e0883dbb26f3883fbc19b80e1211d35ff108c8586848ab044"
+	dir := t.TempDir()
+	for _, args := range [][]string{{"-discard"}, {"-o", "-"}, {"-gzip", "-o", filepath.Join(dir, "out.gz")}} {
+		redirect(t, &os.Stdout)
+		stderr := redirect(t, &os.Stderr)
+		if err := run(append([]string{"-q", "-bin", "-force", "-limit", "1000000", "-sha256"}, args...)); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		if got := contents(t, stderr); !strings.HasPrefix(got, want+"  ") {
+			t.Errorf("%v: printed %q, want the digest %s", args, got, want)
+		}
+	}
+}
+
+func TestPrintHash(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-bin", "-print-hash", "sha256"}, "9955dbd1c520357e0883dbb26f3883fbc19b80e1211d35ff108c8586848ab044  1000000 bytes\n"},
+		{[]string{"-print-hash", "sha256"}, "bbad612403ce8b11161ebc62acbef5508d12560572ccf6f83f0fce30fcf68c9b  2781867 bytes\n"},
+		{[]string{"-bin", "-print-hash", "blake2b"}, "a9973f7a629d59e0fb63e99152e003b7aaa853368f405b55089a07be1edefb18ae7d6a77b0a7243fbbcde8b104d9364854b54e60c5e981fec1376f077e1aca92  1000000 bytes\n"},
+		{[]string{"-print-hash", "blake2b"}, "6616c0be16be653f882d3a09fa8dbcea209b3ea65ad2f7e845146ad363b5d23bd08175b4f89fb3e1f04806a221ce3091df481dc6f9287aa01cbfd6cf80d92c04  2781867 bytes\n"},
+	}
+	for _, c := range cases {
+		stdout := redirect(t, &os.Stdout)
+		if err := run(append([]string{"-q", "-limit", "1000000"}, c.args...)); err != nil {
+			t.Fatalf("%v: %v", c.args, err)
+		}
+		if got := contents(t, stdout); got != c.want {
+			t.Errorf("%v: printed %q, want %q", c.args, got, c.want)
+		}
+	}
+	if err := run([]string{"-print-hash", "md5"}); !errors.As(err, new(usageError)) {
+		t.Errorf("-print-hash md5: got %v, want a usage error", err)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	logged := captureLog(t)
+	if err := run([]string{"-q", "-bin", "-discard", "-limit", "1000000"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logged.String(), "generated 1000000 bytes in ") {
+		t.Errorf("logged %q, want the bytes generated", logged)
+	}
+}
+
+func BenchmarkDiscard(b *testing.B) {
+	// The same sink as for -discard, through the whole of the command.
+	const n = 1 << 24
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+	b.SetBytes(n)
+	for range b.N {
+		if err := run([]string{"-q", "-bin", "-discard", "-limit", strconv.Itoa(n)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		args   []string
+		status int
+		stdout string
+		stderr string
+	}{
+		{[]string{"help"}, 0, "usage: conip [command]", ""},
+		{[]string{"bogus"}, 2, "", `unknown command "bogus"`},
+		{[]string{"-h"}, 0, "", "usage: conip [generate]"},
+		{[]string{"generate", "-bogus"}, 2, "", "-bogus"},
+		{[]string{"generate", "extra"}, 2, "", "usage: conip [generate]"},
+		{[]string{"generate", "-order", "0"}, 2, "", ""},
+		{[]string{"generate", "-alphabet", "0"}, 2, "", ""},
+		{[]string{"generate", "-ipv4", "-order", "3"}, 2, "", ""},
+		{[]string{"size"}, 0, "15334375429\n", ""},
+		{[]string{"size", "-order", "2", "-alphabet", "16", "-hex"}, 0, "770\n", ""},
+		{[]string{"size", "-bogus"}, 2, "", "-bogus"},
+		{[]string{"verify", "-h"}, 0, "", "usage: conip verify"},
+		{[]string{"verify", "-bogus"}, 2, "", "usage: conip verify"},
+		{[]string{"verify", "-order", "5"}, 2, "", "usage: conip verify"},
+		{[]string{"verify", "-self", "file"}, 2, "", "usage: conip verify"},
+		{[]string{"verify", "-self", "-order", "2"}, 0, "ok\n", ""},
+		{[]string{"index", "-h"}, 0, "", "usage: conip index"},
+		{[]string{"index"}, 2, "", "usage: conip index"},
+		{[]string{"index", "10.0.0.1", "1.2.3.4"}, 0, "1060\n66977790\n", ""},
+		{[]string{"index", "-offset", "0", "5"}, 0, "0.0.0.0\n0.0.0.2\n", ""},
+		{[]string{"index", "::1"}, 1, "", `bad address "::1"`},
+		{[]string{"index", "-offset", "x"}, 1, "", `bad offset "x"`},
+		{[]string{"decode", "-h"}, 0, "", "usage: conip decode"},
+		{[]string{"decode", "-bogus"}, 2, "", "usage: conip decode"},
+		{[]string{"decode", "a", "b"}, 2, "", "usage: conip decode"},
+		{[]string{"decode", "-format", "quad"}, 2, "", `unknown -format "quad"`},
+		{[]string{"serve", "-h"}, 0, "", "usage: conip serve"},
+		{[]string{"serve", "-bogus"}, 2, "", "usage: conip serve"},
+		{[]string{"serve", "extra"}, 2, "", "usage: conip serve"},
+	}
+	for _, c := range cases {
+		t.Run(strings.Join(c.args, " "), func(t *testing.T) {
+			stdout := redirect(t, &os.Stdout)
+			stderr := redirect(t, &os.Stderr)
+			logged := captureLog(t)
+			err := run(c.args)
+			if got := statusOf(err); got != c.status {
+				t.Errorf("exit status %d, want %d (%v)", got, c.status, err)
+			}
+			if got := contents(t, stdout); c.stdout == "" && got != "" || !strings.HasPrefix(got, c.stdout) {
+				t.Errorf("stdout %q, want %q", got, c.stdout)
+			}
+			got := contents(t, stderr) + logged.String()
+			if err != nil && !errors.As(err, new(exitStatus)) {
+				got += err.Error()
+			}
+			if !strings.Contains(got, c.stderr) {
+				t.Errorf("stderr %q, want it to contain %q", got, c.stderr)
+			}
+		})
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{nil, 0},
+		{exitStatus(2), 2},
+		{exitStatus(130), 130},
+		{fmt.Errorf("wrapped: %w", exitStatus(143)), 143},
+		{usageError{errors.New("bad flag")}, 2},
+		{fmt.Errorf("wrapped: %w", usageError{errors.New("bad flag")}), 2},
+		{outputError{off: 10, err: syscall.EPIPE}, 3},
+		{errors.New("other"), 1},
+	}
+	for _, c := range cases {
+		if got := statusOf(c.err); got != c.status {
+			t.Errorf("statusOf(%v) = %d, want %d", c.err, got, c.status)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	cases := []struct {
+		s   string
+		n   int64
+		err string
+	}{
+		{"0", 0, ""},
+		{"65536", 65536, ""},
+		{"10B", 10, ""},
+		{"64K", 64 << 10, ""},
+		{"4M", 4 << 20, ""},
+		{"2G", 2 << 30, ""},
+		{"3T", 3 << 40, ""},
+		{"64KiB", 64 << 10, ""},
+		{"4MiB", 4 << 20, ""},
+		{"2GiB", 2 << 30, ""},
+		{"3TiB", 3 << 40, ""},
+		{"500kB", 500e3, ""},
+		{"500KB", 500e3, ""},
+		{"1.5MB", 1.5e6, ""},
+		{"1.5GB", 1.5e9, ""},
+		{"2TB", 2e12, ""},
+		{"0.5K", 512, ""},
+		{"-1", 0, "negative"},
+		{"-1K", 0, "negative"},
+		{"", 0, "invalid number"},
+		{"K", 0, "invalid number"},
+		{".", 0, "invalid number"},
+		{"1.2.3", 0, "invalid number"},
+		{"10x", 0, `unknown unit "x"`},
+		{"10kiB", 0, `unknown unit "kiB"`},
+		{"10 K", 0, `unknown unit " K"`},
+		{"10M/s", 0, `unknown unit "M/s"`},
+		{"8388608T", 0, "too many bytes"},
+		{"9223372036854775808", 0, "too many bytes"},
+	}
+	for _, c := range cases {
+		n, err := parseBytes(c.s)
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%q: %v", c.s, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%q: got error %v, want one about %s", c.s, err, c.err)
+		case n != c.n:
+			t.Errorf("%q: got %d, want %d", c.s, n, c.n)
+		}
+	}
+}
+
+func TestByteFlags(t *testing.T) {
+	cases := []struct {
+		name string
+		v    interface {
+			Set(string) error
+			String() string
+		}
+		s    string
+		want string
+	}{
+		{"rate", new(byteRate), "10MiB/s", "10485760"},
+		{"rate", new(byteRate), "500KB/s", "500000"},
+		{"rate", new(byteRate), "1000000", "1000000"},
+		{"rate", new(byteRate), "0", "0"},
+		{"rate", new(byteRate), "5x/s", ""},
+		{"rate", new(byteRate), "-1/s", ""},
+		{"size", new(byteSize), "64KiB", "65536"},
+		{"size", new(byteSize), "1", "1"},
+		{"size", new(byteSize), "1GiB", "1073741824"},
+		{"size", new(byteSize), "0", ""},
+		{"size", new(byteSize), "-5", ""},
+		{"size", new(byteSize), "1073741825", ""},
+		{"size", new(byteSize), "2G", ""},
+		{"size", new(byteSize), "64K/s", ""},
+		{"count", new(byteCount), "1GiB", "1073741824"},
+		{"count", new(byteCount), "2TiB", "2199023255552"},
+		{"count", new(byteCount), "0", "0"},
+		{"count", new(byteCount), "1M/s", ""},
+		{"count", new(byteCount), "-1", ""},
+	}
+	for _, c := range cases {
+		err := c.v.Set(c.s)
+		switch {
+		case c.want == "" && err == nil:
+			t.Errorf("%s %q: accepted as %s", c.name, c.s, c.v)
+		case c.want != "" && err != nil:
+			t.Errorf("%s %q: %v", c.name, c.s, err)
+		case c.want != "" && c.v.String() != c.want:
+			t.Errorf("%s %q: got %s, want %s", c.name, c.s, c.v, c.want)
+		}
+	}
+}
+
+func TestByteFlagUsage(t *testing.T) {
+	cases := [][]string{
+		{"-buf", "0"},
+		{"-buf", "2G"},
+		{"-buf", "-1"},
+		{"-rate", "5x"},
+		{"-crc-interval", "1M/s"},
+		{"-n", "10", "-bin"},
+		{"-n", "10", "-ipv4"},
+	}
+	for _, args := range cases {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			out := redirect(t, &os.Stdout)
+			redirect(t, &os.Stderr)
+			captureLog(t)
+			err := run(append(args, "-q", "-o", "-"))
+			if got := statusOf(err); got != 2 {
+				t.Errorf("exit status %d, want 2 (%v)", got, err)
+			}
+			if got := contents(t, out); got != "" {
+				t.Errorf("wrote %d bytes", len(got))
+			}
+		})
+	}
+}
+
+func TestXOR(t *testing.T) {
+	plain, _ := runOutput(t, "-bin", "-limit", "1000")
+	for _, m := range []string{"0xff", "255", "0xFF"} {
+		got, status := runOutput(t, "-bin", "-limit", "1000", "-xor", m)
+		if status != 0 {
+			t.Fatalf("-xor %s: exit status %d", m, status)
+		}
+		for i := range plain {
+			if got[i] != ^plain[i] {
+				t.Fatalf("-xor %s: term %d is %d, want %d", m, i, got[i], ^plain[i])
+			}
+		}
+	}
+	if got, _ := runOutput(t, "-limit", "5", "-xor", "0x0f"); got != "15.15.15.15.14" {
+		t.Errorf("text with -xor 0x0f is %q, want %q", got, "15.15.15.15.14")
+	}
+	for _, m := range []string{"256", "-1", "ff", "x"} {
+		if _, status := runOutput(t, "-xor", m); status != 2 {
+			t.Errorf("-xor %s: exit status %d, want 2", m, status)
+		}
+	}
+}
+
+func TestPermuteSeed(t *testing.T) {
+	seedRE := regexp.MustCompile(`permute seed (\d+)\n`)
+	// seeded runs conip with -permute-seed seed and returns its output and
+	// the seed it printed.
+	seeded := func(seed string) (string, string) {
+		out := redirect(t, &os.Stdout)
+		stderr := redirect(t, &os.Stderr)
+		captureLog(t)
+		if err := run([]string{"-q", "-o", "-", "-force", "-bin", "-limit", "1000", "-permute-seed", seed, "-print-seed"}); err != nil {
+			t.Fatalf("-permute-seed %s: %v", seed, err)
+		}
+		m := seedRE.FindStringSubmatch(contents(t, stderr))
+		if m == nil {
+			t.Fatalf("-permute-seed %s: no seed printed", seed)
+		}
+		return contents(t, out), m[1]
+	}
+	a, seed := seeded("42")
+	if seed != "42" {
+		t.Errorf("-permute-seed 42 printed seed %s", seed)
+	}
+	if b, _ := seeded("42"); a != b {
+		t.Error("runs with the same seed differ")
+	}
+	p := debruijn.Permutation(42)
+	if a[0] != p[0] {
+		t.Errorf("first term %d, want %d", a[0], p[0])
+	}
+	r, seed := seeded("random")
+	if again, _ := seeded(seed); again != r {
+		t.Errorf("the printed seed %s doesn't reproduce the random run", seed)
+	}
+	for _, args := range [][]string{{"-permute-seed", "x"}, {"-permute-seed", "-1"}, {"-print-seed"}} {
+		if _, status := runOutput(t, args...); status != 2 {
+			t.Errorf("%v: exit status %d, want 2", args, status)
+		}
+	}
+}
+
+func TestSizeFlag(t *testing.T) {
+	// -size gives the length of the output it would write.
+	for _, args := range [][]string{{}, {"-bin"}, {"-n"}, {"-hex"}, {"-pad", "-n"}, {"-sep", ", "}, {"-crlf"}, {"-xor", "3"}, {"-alphabet", "10"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			args := append([]string{"-order", "2"}, args...)
+			out, status := runOutput(t, args...)
+			if status != 0 {
+				t.Fatalf("exit status %d", status)
+			}
+			size, _ := runOutput(t, append(args, "-size")...)
+			if want := strconv.Itoa(len(out)) + "\n"; size != want {
+				t.Errorf("-size printed %q, but wrote %d bytes", size, len(out))
+			}
+		})
+	}
+	cases := []struct {
+		args []string
+		size string
+	}{
+		{[]string{"-bin"}, "4294967299"},
+		{[]string{}, "15334375429"},
+		{[]string{"-n"}, "15334375429"},
+		{[]string{"-hex"}, "12884901896"},
+		{[]string{"-pad", "-n"}, "17179869195"},
+		{[]string{"-ipv4"}, "61337501696"},
+		{[]string{"-ipv4", "-cidr", "24"}, "74222403584"},
+	}
+	for _, c := range cases {
+		if got, status := runOutput(t, append([]string{"-size"}, c.args...)...); status != 0 || got != c.size+"\n" {
+			t.Errorf("-size %v printed %q with exit status %d, want %s", c.args, got, status, c.size)
+		}
+	}
+}
+
+func TestEncodingFlag(t *testing.T) {
+	for _, name := range debruijn.EncoderNames() {
+		format := name
+		if name == "binary" {
+			format = "bin"
+		}
+		want, _ := runOutput(t, "-format", format, "-limit", "1000")
+		got, status := runOutput(t, "-encoding", name, "-limit", "1000")
+		if status != 0 || got != want {
+			t.Errorf("-encoding %s: exit status %d, output %.20q..., want %.20q...", name, status, got, want)
+		}
+	}
+	if _, status := runOutput(t, "-encoding", "nonexistent"); status != 2 {
+		t.Errorf("unknown encoder: exit status %d, want 2", status)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	for _, format := range []string{"bin", "dot"} {
+		full, _ := runOutput(t, "-format", format, "-order", "2")
+		got, status := runOutput(t, "-format", format, "-order", "2", "-limit", "1000")
+		if status != 0 {
+			t.Fatalf("%s: exit status %d", format, status)
+		}
+		if len(got) >= len(full) || !strings.HasPrefix(full, got) {
+			t.Errorf("%s: -limit 1000 gave %d bytes which aren't a strict prefix of the %d in full", format, len(got), len(full))
+		}
+		terms := len(got)
+		if format == "dot" {
+			terms = strings.Count(got, ".") + 1
+		}
+		if terms != 1000 {
+			t.Errorf("%s: -limit 1000 gave %d terms", format, terms)
+		}
+		// A limit past the end, where the wrap-around terms are, gives the
+		// whole sequence.
+		if got, _ := runOutput(t, "-format", format, "-order", "2", "-limit", "100000"); got != full {
+			t.Errorf("%s: -limit past the end gave %d bytes, want %d", format, len(got), len(full))
+		}
+	}
+	if got, _ := runOutput(t, "-limit", "0"); got != "" {
+		t.Errorf("-limit 0 gave %q", got)
+	}
+	if got, _ := runOutput(t, "-ipv4", "-limit", "3"); got != "0.0.0.0\n0.0.0.1\n0.0.1.0\n" {
+		t.Errorf("-ipv4 -limit 3 gave %q", got)
+	}
+}
+
+func TestLimitBytes(t *testing.T) {
+	full, _ := runOutput(t, "-limit", "100")
+	for n := 1; n < 40; n++ {
+		got, status := runOutput(t, "-limit-bytes", strconv.Itoa(n))
+		if status != 0 {
+			t.Fatalf("-limit-bytes %d: exit status %d", n, status)
+		}
+		// The output ends with the last whole term which fits.
+		want := full[:strings.LastIndexByte(full[:n+1], '.')]
+		if got != want {
+			t.Errorf("-limit-bytes %d gave %q, want %q", n, got, want)
+		}
+	}
+	if got, _ := runOutput(t, "-bin", "-limit-bytes", "5"); got != "\x00\x00\x00\x00\x01" {
+		t.Errorf("-bin -limit-bytes 5 gave %q", got)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	// Slices of B(256, 2) stitched together make the whole.
+	for _, format := range []string{"bin", "dot"} {
+		full, _ := runOutput(t, "-format", format, "-order", "2")
+		sep := ""
+		if format == "dot" {
+			sep = "."
+		}
+		var parts []string
+		for skip := 0; skip < 1<<16+1; skip += 10000 {
+			got, status := runOutput(t, "-format", format, "-order", "2", "-skip", strconv.Itoa(skip), "-limit", "10000")
+			if status != 0 {
+				t.Fatalf("%s -skip %d: exit status %d", format, skip, status)
+			}
+			if strings.HasPrefix(got, ".") {
+				t.Errorf("%s -skip %d begins with a separator", format, skip)
+			}
+			parts = append(parts, got)
+		}
+		if got := strings.Join(parts, sep); got != full {
+			t.Errorf("%s: slices make %d bytes which differ from the %d in full", format, len(got), len(full))
+		}
+	}
+	// For order 4, the generator jumps ahead rather than generating the
+	// terms before.
+	var want bytes.Buffer
+	if _, err := debruijn.Write(&want, debruijn.Options{Skip: 1 << 31, Limit: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := runOutput(t, "-bin", "-skip", "2147483648", "-limit", "1000"); got != want.String() {
+		t.Errorf("-skip 2^31 gave %x..., want %x...", got[:8], want.Bytes()[:8])
+	}
+	if got, _ := runOutput(t, "-ipv4", "-skip", "2", "-limit", "2"); got != "0.0.1.0\n0.1.0.0\n" {
+		t.Errorf("-ipv4 -skip 2 gave %q", got)
+	}
+}
+
+func TestFromUntil(t *testing.T) {
+	cases := []struct{ from, until string }{
+		{"0.0.1.0", "0.3.0.0"},
+		{"10.0.0.0", "10.0.0.255"},
+		{"10.0.0.1", "10.0.1.0"},
+		{"10.0.0.10", "10.0.0.10"},
+		// The last window wraps around the end of the sequence.
+		{"254.255.255.255", "255.0.0.0"},
+	}
+	for _, c := range cases {
+		from, until := netip.MustParseAddr(c.from), netip.MustParseAddr(c.until)
+		start, end := debruijn.Rank(from), debruijn.Rank(until)+4
+		var want bytes.Buffer
+		if _, err := debruijn.Write(&want, debruijn.Options{Skip: uint64(start), Limit: end - start}); err != nil {
+			t.Fatal(err)
+		}
+		got, status := runOutput(t, "-bin", "-from", c.from, "-until", c.until)
+		if status != 0 {
+			t.Fatalf("-from %s -until %s: exit status %d", c.from, c.until, status)
+		}
+		if got != want.String() {
+			t.Errorf("-from %s -until %s gave %d bytes which differ from the %d at offset %d", c.from, c.until, len(got), want.Len(), start)
+		}
+		f, u := from.As4(), until.As4()
+		if !strings.HasPrefix(got, string(f[:])) || !strings.HasSuffix(got, string(u[:])) {
+			t.Errorf("-from %s -until %s gave %x...%x", c.from, c.until, got[:4], got[len(got)-4:])
+		}
+	}
+	if _, status := runOutput(t, "-from", "10.0.1.0", "-until", "10.0.0.1"); status != 2 {
+		t.Errorf("-from after -until: exit status %d, want 2", status)
+	}
+	if _, status := runOutput(t, "-from", "300.0.0.0"); status != 2 {
+		t.Errorf("bad -from: exit status %d, want 2", status)
+	}
+}
+
+func TestHead(t *testing.T) {
+	for f := debruijn.Binary; f <= debruijn.PaddedLines; f++ {
+		var want bytes.Buffer
+		if _, err := debruijn.Write(&want, debruijn.Options{Format: f, Limit: 2000}); err != nil {
+			t.Fatal(err)
+		}
+		// Lengths which end in the middle of terms as well as between them.
+		for _, n := range []int{0, 1, 2, 7, 13, 100, 1001} {
+			got, status := runOutput(t, "-format", f.String(), "-head", strconv.Itoa(n))
+			if status != 0 {
+				t.Fatalf("%v -head %d: exit status %d", f, n, status)
+			}
+			if got != want.String()[:n] {
+				t.Errorf("%v -head %d gave %q, want %q", f, n, got, want.String()[:n])
+			}
+		}
+	}
+	// Past the end of the sequence, -head gives all of it.
+	full, _ := runOutput(t, "-order", "2")
+	if got, _ := runOutput(t, "-order", "2", "-head", "10000000"); got != full {
+		t.Errorf("-head past the end gave %d bytes, want %d", len(got), len(full))
+	}
+	if _, status := runOutput(t, "-head", "10", "-size"); status != 2 {
+		t.Errorf("-head with -size: exit status %d, want 2", status)
+	}
+}
+
+func TestSep(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-sep", ","}, "0,0,0,0,1,0"},
+		{[]string{"-sep", ", "}, "0, 0, 0, 0, 1, 0"},
+		{[]string{"-sep", "→"}, "0→0→0→0→1→0"},
+		{[]string{"-sep", `\t`}, "0\t0\t0\t0\t1\t0"},
+		{[]string{"-sep", `\r\n`}, "0\r\n0\r\n0\r\n0\r\n1\r\n0"},
+		{[]string{"-sep", ""}, "000010"},
+		{[]string{"-sep", "", "-hex"}, "000000000100"},
+		{[]string{"-sep", ":", "-pad"}, "000:000:000:000:001:000"},
+	}
+	for _, c := range cases {
+		got, status := runOutput(t, append(c.args, "-limit", "6")...)
+		if status != 0 || got != c.want {
+			t.Errorf("%q gave %q with exit status %d, want %q", c.args, got, status, c.want)
+		}
+	}
+	// The default separators agree with the formats which have them.
+	same := [][2][]string{
+		{{"-sep", "."}, nil},
+		{{"-sep", `\n`}, {"-n"}},
+		{{"-sep", `\n`, "-hex"}, {"-hex", "-n"}},
+		{{"-sep", `\n`, "-pad"}, {"-format", "paddedlines"}},
+	}
+	for _, c := range same {
+		a, _ := runOutput(t, append(c[0], "-limit", "1000")...)
+		b, _ := runOutput(t, append(c[1], "-limit", "1000")...)
+		if a != b {
+			t.Errorf("%q gave %.20q..., but %q gave %.20q...", c[0], a, c[1], b)
+		}
+	}
+	// -size accounts for the length of the separator.
+	for _, sep := range []string{"", ",", ", ", "→", `\r\n`} {
+		out, _ := runOutput(t, "-order", "2", "-sep", sep)
+		size, _ := runOutput(t, "-order", "2", "-sep", sep, "-size")
+		if size != strconv.Itoa(len(out))+"\n" {
+			t.Errorf("-sep %q: -size says %q, but the output has %d bytes", sep, size, len(out))
+		}
+	}
+	bad := [][]string{
+		{"-sep", `\q`},
+		{"-sep", ",", "-n"},
+		{"-sep", ",", "-bin"},
+		{"-sep", "", "-bin"},
+		{"-sep", "", "-encoding", "padded"},
+	}
+	for _, args := range bad {
+		if _, status := runOutput(t, args...); status != 2 {
+			t.Errorf("%q: exit status %d, want 2", args, status)
+		}
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	crlf, status := runOutput(t, "-crlf", "-limit", "1000")
+	if status != 0 {
+		t.Fatalf("exit status %d", status)
+	}
+	if sep, _ := runOutput(t, "-sep", `\r\n`, "-limit", "1000"); crlf != sep {
+		t.Errorf("-crlf gave %.20q..., but -sep '\\r\\n' gave %.20q...", crlf, sep)
+	}
+	if strings.Count(crlf, "\r\n") != 999 || strings.Count(crlf, "\n") != 999 {
+		t.Errorf("-crlf -limit 1000 has %d CRLFs and %d newlines, want 999 of each", strings.Count(crlf, "\r\n"), strings.Count(crlf, "\n"))
+	}
+	for _, args := range [][]string{{"-order", "2"}, {"-order", "2", "-hex"}} {
+		out, _ := runOutput(t, append(args, "-crlf")...)
+		size, _ := runOutput(t, append(args, "-crlf", "-size")...)
+		if size != strconv.Itoa(len(out))+"\n" {
+			t.Errorf("%q: -size says %q, but the output has %d bytes", args, size, len(out))
+		}
+	}
+	// The output decodes to the same windows as the binary output, and a
+	// newline among CRLFs is an error.
+	bin, _ := runOutput(t, "-bin", "-limit", "1000")
+	want, _ := decodeOutput(t, bin)
+	if got, status := decodeOutput(t, crlf, "-format", "lines"); status != 0 || got != want {
+		t.Errorf("decoding -crlf output gave %.40q... with exit status %d, want %.40q...", got, status, want)
+	}
+	hex, _ := runOutput(t, "-crlf", "-hex", "-limit", "1000")
+	if got, status := decodeOutput(t, hex, "-format", "hexlines"); status != 0 || got != want {
+		t.Errorf("decoding -crlf -hex output gave %.40q... with exit status %d, want %.40q...", got, status, want)
+	}
+	if _, status := decodeOutput(t, "0\r\n0\n0\r\n0\r\n1", "-format", "lines"); status != 1 {
+		t.Errorf("decoding mixed line endings: exit status %d, want 1", status)
+	}
+	for _, args := range [][]string{{"-crlf", "-n"}, {"-crlf", "-sep", ","}, {"-crlf", "-bin"}} {
+		if _, status := runOutput(t, args...); status != 2 {
+			t.Errorf("%q: exit status %d, want 2", args, status)
+		}
+	}
+}
+
+func TestWrapFlag(t *testing.T) {
+	plain, _ := runOutput(t, "-order", "2")
+	for _, wrap := range []int{1, 16, 255} {
+		got, status := runOutput(t, "-order", "2", "-wrap", strconv.Itoa(wrap))
+		if status != 0 {
+			t.Fatalf("-wrap %d: exit status %d", wrap, status)
+		}
+		// As wc -l counts, each line but the last ends in a newline.
+		terms := 1<<16 + 1
+		if want := (terms+wrap-1)/wrap - 1; strings.Count(got, "\n") != want {
+			t.Errorf("-wrap %d: %d newlines, want %d", wrap, strings.Count(got, "\n"), want)
+		}
+		if strings.ReplaceAll(got, "\n", "") != plain {
+			t.Errorf("-wrap %d: without newlines, the output differs", wrap)
+		}
+		size, _ := runOutput(t, "-order", "2", "-wrap", strconv.Itoa(wrap), "-size")
+		if size != strconv.Itoa(len(got))+"\n" {
+			t.Errorf("-wrap %d: -size says %q, but the output has %d bytes", wrap, size, len(got))
+		}
+	}
+	for _, args := range [][]string{{"-wrap", "3", "-n"}, {"-wrap", "3", "-bin"}, {"-wrap", "3", "-ipv4"}, {"-wrap", "3", "-sep", ","}} {
+		if _, status := runOutput(t, args...); status != 2 {
+			t.Errorf("%q: exit status %d, want 2", args, status)
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, format := range []string{"bin", "dot", "hexlines", "padded"} {
+		dir := t.TempDir()
+		args := []string{"-order", "2", "-format", format}
+		whole := generateFile(t, dir, args...)
+		name := filepath.Join(dir, "part")
+		// Runs cut off anywhere, even in the middle of a term, continue to the
+		// same file as an uninterrupted run.
+		offs := []int{0, 1, 2, len(whole) - 1, len(whole)}
+		for range 10 {
+			offs = append(offs, rng.IntN(len(whole)))
+		}
+		for _, off := range offs {
+			if err := os.WriteFile(name, whole[:off], 0o644); err != nil {
+				t.Fatal(err)
+			}
+			captureLog(t)
+			if err := run(append([]string{"-q", "-o", name, "-append"}, args...)); err != nil {
+				t.Fatalf("%s at %d: %v", format, off, err)
+			}
+			got, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, whole) {
+				t.Errorf("%s at %d: appending gave %d bytes which differ from the %d of one run", format, off, len(got), len(whole))
+			}
+		}
+		// A file whose tail isn't the output there is left alone.
+		part := bytes.Clone(whole[:1000])
+		part[990] ^= 1
+		if err := os.WriteFile(name, part, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err := run(append([]string{"-q", "-o", name, "-append"}, args...))
+		if statusOf(err) != 1 || !strings.Contains(fmt.Sprint(err), "does not end with the expected output") {
+			t.Errorf("%s: appending to a mismatched file gave exit status %d with error %v", format, statusOf(err), err)
+		}
+		if got, _ := os.ReadFile(name); !bytes.Equal(got, part) {
+			t.Errorf("%s: refusing to append changed the file", format)
+		}
+		// Only as many bytes as -append-check says are compared.
+		if err := run(append([]string{"-q", "-o", name, "-append", "-append-check", "5"}, args...)); err != nil {
+			t.Errorf("%s: -append-check 5 with a mismatch 10 bytes from the end: %v", format, err)
+		}
+	}
+	if _, status := runOutput(t, "-append"); status != 2 {
+		t.Errorf("-append without -o: exit status %d, want 2", status)
+	}
+	name := filepath.Join(t.TempDir(), "out")
+	if err := run([]string{"-q", "-o", name, "-append", "-append-check", "-1"}); statusOf(err) != 2 {
+		t.Errorf("negative -append-check: exit status %d, want 2", statusOf(err))
+	}
+}
+
+func TestCreateError(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		path string
+		msg  string
+	}{
+		{"missing directory", filepath.Join(dir, "missing", "out"), "no such file or directory"},
+		{"directory", dir, "is a directory"},
+		{"under a file", filepath.Join(file, "out"), "not a directory"},
+	}
+	for _, c := range cases {
+		captureLog(t)
+		var err error
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("%s: panicked: %v", c.name, r)
+				}
+			}()
+			err = run([]string{"-q", "-order", "2", "-o", c.path})
+		}()
+		if statusOf(err) != 3 || !strings.Contains(fmt.Sprint(err), c.msg) {
+			t.Errorf("%s: exit status %d with error %v, want 3 and %q", c.name, statusOf(err), err, c.msg)
+		}
+	}
+}
+
+func TestEndian(t *testing.T) {
+	big, _ := runOutput(t, "-bin", "-alphabet", "300", "-order", "1")
+	if b, _ := runOutput(t, "-bin", "-alphabet", "300", "-order", "1", "-endian", "big"); b != big {
+		t.Errorf("-endian big differs from the default")
+	}
+	little, status := runOutput(t, "-bin", "-alphabet", "300", "-order", "1", "-endian", "little")
+	if status != 0 || len(big) != 600 || len(little) != 600 {
+		t.Fatalf("wrote %d bytes big-endian and %d little-endian with exit status %d, want 600", len(big), len(little), status)
+	}
+	for i := range 300 {
+		b, l := big[2*i:2*i+2], little[2*i:2*i+2]
+		if b != string([]byte{byte(i >> 8), byte(i)}) || l != string([]byte{byte(i), byte(i >> 8)}) {
+			t.Fatalf("term %d is %x big-endian and %x little-endian", i, b, l)
+		}
+	}
+	// Terms of a byte have no byte order.
+	plain, _ := runOutput(t, "-bin", "-order", "2")
+	if got, _ := runOutput(t, "-bin", "-order", "2", "-endian", "little"); got != plain {
+		t.Errorf("-endian little changed byte terms")
+	}
+	if _, status := runOutput(t, "-bin", "-alphabet", "300", "-order", "1", "-endian", "middle"); status != 2 {
+		t.Errorf("-endian middle: exit status %d, want 2", status)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	wouldRE := regexp.MustCompile(`^would write (\d+) bytes to (\S+)\n`)
+	dir := t.TempDir()
+	name := filepath.Join(dir, "out")
+	for f := debruijn.Binary; f <= debruijn.PaddedLines; f++ {
+		for _, order := range []int{2, 4} {
+			if f == debruijn.IPv4 && order != 4 {
+				continue
+			}
+			out := redirect(t, &os.Stdout)
+			redirect(t, &os.Stderr)
+			captureLog(t)
+			err := run([]string{"-dry-run", "-format", f.String(), "-order", strconv.Itoa(order), "-o", name})
+			if err != nil {
+				t.Fatalf("%v order %d: %v", f, order, err)
+			}
+			got := contents(t, out)
+			m := wouldRE.FindStringSubmatch(got)
+			if m == nil {
+				t.Fatalf("%v order %d: no size in %q", f, order, got)
+			}
+			want, err := debruijn.Size(f, order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m[1] != strconv.FormatInt(want, 10) || m[2] != name {
+				t.Errorf("%v order %d: would write %s bytes to %s, want %d to %s", f, order, m[1], m[2], want, name)
+			}
+			if !strings.Contains(got, "estimated ") {
+				t.Errorf("%v order %d: no estimate in %q", f, order, got)
+			}
+		}
+	}
+	out := redirect(t, &os.Stdout)
+	if err := run([]string{"-dry-run", "-order", "2", "-shards", "3", "-o", name}); err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(t, out); !strings.Contains(got, name+".002: 21845 terms\n") {
+		t.Errorf("no plan for the last shard in %q", got)
+	}
+	// Nothing is written to the destination.
+	if ents, _ := os.ReadDir(dir); len(ents) != 0 {
+		t.Errorf("-dry-run created %v", ents)
+	}
+	for _, flag := range []string{"-size", "-count"} {
+		if _, status := runOutput(t, "-dry-run", flag); status != 2 {
+			t.Errorf("-dry-run %s: exit status %d, want 2", flag, status)
+		}
+	}
+}
+
+func TestVerbosity(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out")
+	// logOf runs conip writing B(256, 3) to name and returns what it logs.
+	logOf := func(args ...string) string {
+		t.Helper()
+		redirect(t, &os.Stderr)
+		logged := captureLog(t)
+		log.SetFlags(0)
+		t.Cleanup(func() { log.SetFlags(log.LstdFlags) })
+		if err := run(append([]string{"-order", "3", "-o", name}, args...)); err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		return logged.String()
+	}
+	summaryRE := regexp.MustCompile(`^wrote 59899907 bytes in \S+\n$`)
+	if got := logOf(); !summaryRE.MatchString(got) {
+		t.Errorf("default log is %q, want one summary line", got)
+	}
+	if got := logOf("-q"); got != "" {
+		t.Errorf("-q logged %q", got)
+	}
+	lines := strings.Split(strings.TrimSuffix(logOf("-v"), "\n"), "\n")
+	if len(lines) != 256+3 {
+		t.Fatalf("-v logged %d lines, want %d", len(lines), 256+3)
+	}
+	// A milestone for each first term, at the end of the write which
+	// reached it, in order.
+	reachedRE := regexp.MustCompile(`^reached the Lyndon words beginning with (\d+) by byte (\d+)$`)
+	last := int64(0)
+	for i, l := range lines[:256] {
+		m := reachedRE.FindStringSubmatch(l)
+		if m == nil || m[1] != strconv.Itoa(i) {
+			t.Fatalf("line %d of -v is %q", i, l)
+		}
+		off, _ := strconv.ParseInt(m[2], 10, 64)
+		if off < last {
+			t.Errorf("milestone %d at byte %d is before the last at %d", i, off, last)
+		}
+		last = off
+	}
+	want := []string{"emitted 256 1-element Lyndon words", "emitted 5592320 3-element Lyndon words"}
+	if !slices.Equal(lines[256:258], want) || !summaryRE.MatchString(lines[258]+"\n") {
+		t.Errorf("-v ends with %q", lines[256:])
+	}
+	for _, args := range [][]string{{"-q", "-v"}, {"-q", "-verbose"}, {"-q", "-progress=always"}} {
+		if _, status := runOutput(t, append(args, "-order", "2")...); status != 2 {
+			t.Errorf("%q: exit status %d, want 2", args, status)
+		}
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	cases := []struct {
+		verb     string
+		n, total int64
+		elapsed  time.Duration
+		want     string
+	}{
+		{"wrote", 250e6, 1e9, time.Second, "wrote 250000000 of 1000000000 bytes (25.00%), 250.0 MB/s, ETA 3s"},
+		{"wrote", 1, 3, time.Second, "wrote 1 of 3 bytes (33.33%), 0.0 MB/s, ETA 2s"},
+		{"checked", 1e9, 1e9, 4 * time.Second, "checked 1000000000 of 1000000000 bytes (100.00%), 250.0 MB/s, ETA 0s"},
+		{"wrote", 6e7, 4294967299, time.Minute, "wrote 60000000 of 4294967299 bytes (1.40%), 1.0 MB/s, ETA 1h10m35s"},
+		{"wrote", 5e6, -1, time.Second, "wrote 5000000 bytes, 5.0 MB/s"},
+		// Nothing written yet gives no rate from which to estimate.
+		{"wrote", 0, 1000, time.Second, "wrote 0 bytes, 0.0 MB/s"},
+	}
+	for _, c := range cases {
+		if got := progressLine(c.verb, c.n, c.total, c.elapsed); got != c.want {
+			t.Errorf("progressLine(%q, %d, %d, %v) = %q, want %q", c.verb, c.n, c.total, c.elapsed, got, c.want)
+		}
+	}
+}
+
+func TestProgressMode(t *testing.T) {
+	cases := map[string]string{"always": "always", "never": "never", "auto": "auto", "true": "auto", "false": "never"}
+	for in, want := range cases {
+		var m progressMode
+		if err := m.Set(in); err != nil || m.String() != want {
+			t.Errorf("Set(%q) gives %q with error %v, want %q", in, m.String(), err, want)
+		}
+	}
+	var m progressMode
+	if err := m.Set("sometimes"); err == nil {
+		t.Errorf("Set(%q) succeeded", "sometimes")
+	}
+	// Whether each mode reports, with stderr a terminal or not. The report
+	// on a terminal redraws its line, which it clears before the summary.
+	name := filepath.Join(t.TempDir(), "out")
+	for _, c := range []struct {
+		mode string
+		tty  bool
+		want bool
+	}{
+		{"-progress", true, true},
+		{"-progress", false, false},
+		{"-progress=auto", true, true},
+		{"-progress=always", true, true},
+		{"-progress=never", true, false},
+		{"-progress=false", true, false},
+	} {
+		stderr := redirect(t, &os.Stderr)
+		logged := captureLog(t)
+		if c.tty {
+			fakeTerminal(t, stderr)
+		} else {
+			fakeTerminal(t, nil)
+		}
+		if err := run([]string{c.mode, "-order", "2", "-o", name}); err != nil {
+			t.Fatalf("%s: %v", c.mode, err)
+		}
+		if got := strings.Contains(contents(t, stderr), "\r\x1b[K"); got != c.want {
+			t.Errorf("%s with a terminal %t: reported %t, want %t", c.mode, c.tty, got, c.want)
+		}
+		if !strings.Contains(logged.String(), "wrote 233985 bytes in ") {
+			t.Errorf("%s with a terminal %t: no summary in %q", c.mode, c.tty, logged)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	// Every command taking -json prints JSON: generate the terms as an
+	// array, and size the length of that array as a number.
+	wouldRE := regexp.MustCompile(`^would write (\d+) bytes to (\S+)\n`)
+	for _, args := range [][]string{
+		{"-order", "1", "-alphabet", "3"},
+		{"-order", "2", "-alphabet", "5"},
+		{"-order", "2"},
+		{"-order", "2", "-xor", "0x80"},
+		{"-order", "2", "-permute-seed", "7"},
+	} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			out, status := runOutput(t, append([]string{"-json"}, args...)...)
+			if status != 0 {
+				t.Fatalf("exit status %d", status)
+			}
+			var terms []int
+			if err := json.Unmarshal([]byte(out), &terms); err != nil {
+				t.Fatalf("decoding %.40q...: %v", out, err)
+			}
+			bin, _ := runOutput(t, append([]string{"-bin"}, args...)...)
+			if len(terms) != len(bin) {
+				t.Fatalf("got %d terms, want %d", len(terms), len(bin))
+			}
+			for i, x := range terms {
+				if x != int(bin[i]) {
+					t.Fatalf("term %d is %d, want %d", i, x, bin[i])
+				}
+			}
+
+			stdout := redirect(t, &os.Stdout)
+			if err := run(append([]string{"size", "-json"}, args...)); err != nil {
+				t.Fatal(err)
+			}
+			var size int64
+			if err := json.Unmarshal([]byte(contents(t, stdout)), &size); err != nil {
+				t.Fatalf("decoding size %q: %v", contents(t, stdout), err)
+			}
+			if size != int64(len(out)) {
+				t.Errorf("size -json printed %d, but generate -json wrote %d bytes", size, len(out))
+			}
+
+			stdout = redirect(t, &os.Stdout)
+			if err := run(append([]string{"-dry-run", "-json"}, args...)); err != nil {
+				t.Fatal(err)
+			}
+			m := wouldRE.FindStringSubmatch(contents(t, stdout))
+			if m == nil {
+				t.Fatalf("-dry-run -json: no size in %q", contents(t, stdout))
+			}
+			if m[1] != strconv.Itoa(len(out)) {
+				t.Errorf("-dry-run -json would write %s bytes, but generate -json wrote %d", m[1], len(out))
+			}
+		})
+	}
+}
+
+// readEvents decodes the -progress-json events in the file named name.
+func readEvents(t *testing.T, name string) []finalEvent {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var evs []finalEvent
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for dec.More() {
+		var ev finalEvent
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatalf("decoding %q: %v", b, err)
+		}
+		evs = append(evs, ev)
+	}
+	if len(evs) == 0 {
+		t.Fatal("no events")
+	}
+	return evs
+}
+
+func TestProgressJSON(t *testing.T) {
+	dir := t.TempDir()
+	out, events := filepath.Join(dir, "out"), filepath.Join(dir, "events")
+	cases := []struct {
+		args  []string
+		terms int64
+		total int64
+	}{
+		{[]string{"-order", "3"}, 1<<24 + 2, 59899907},
+		{[]string{"-order", "3", "-bin"}, 1<<24 + 2, 1<<24 + 2},
+		// The total isn't known ahead with a limit.
+		{[]string{"-order", "3", "-limit", "1000"}, 1000, -1},
+	}
+	for _, c := range cases {
+		captureLog(t)
+		err := run(append([]string{"-q", "-o", out, "-progress-json", events, "-progress-interval", "1ms"}, c.args...))
+		if err != nil {
+			t.Fatalf("%q: %v", c.args, err)
+		}
+		evs := readEvents(t, events)
+		// The events count up to the final one, which alone is done.
+		for i, ev := range evs[:len(evs)-1] {
+			next := evs[i+1]
+			if ev.Done || next.Bytes < ev.Bytes || next.Terms < ev.Terms || next.ElapsedMS < ev.ElapsedMS {
+				t.Errorf("%q: event %d, %+v, is followed by %+v", c.args, i, ev, next)
+			}
+			if ev.Total != c.total {
+				t.Errorf("%q: event %d has total %d, want %d", c.args, i, ev.Total, c.total)
+			}
+		}
+		st, err := os.Stat(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := evs[len(evs)-1]
+		if !last.Done || last.Status != 0 || last.Bytes != st.Size() || last.Terms != c.terms || last.Error != "" {
+			t.Errorf("%q: final event is %+v, want %d bytes, %d terms, status 0", c.args, last, st.Size(), c.terms)
+		}
+	}
+	// A file descriptor works as well as a name.
+	f, err := os.Create(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"-q", "-order", "2", "-o", out, "-progress-json", strconv.Itoa(fd)}); err != nil {
+		t.Fatal(err)
+	}
+	if evs := readEvents(t, events); !evs[len(evs)-1].Done || evs[len(evs)-1].Bytes != 233985 {
+		t.Errorf("events to a file descriptor end with %+v", evs[len(evs)-1])
+	}
+	// The final event has the status conip exits with.
+	err = run([]string{"-q", "-order", "2", "-o", "/dev/full", "-progress-json", events})
+	if last := readEvents(t, events); statusOf(err) != 3 || last[len(last)-1].Status != 3 || last[len(last)-1].Error == "" {
+		t.Errorf("writing to /dev/full: exit status %d, final event %+v", statusOf(err), last[len(last)-1])
+	}
+}
+
+func TestFinalEventSignal(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "events")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count atomic.Int64
+	count.Store(4096)
+	e := &events{w: f, count: &count, width: 1, total: -1, start: time.Now()}
+	e.final(nil, syscall.SIGINT)
+	// Only the first final event is written, as when a signal arrives while
+	// the run ends.
+	e.final(errors.New("late"), nil)
+	evs := readEvents(t, name)
+	want := finalEvent{progressEvent: progressEvent{Bytes: 4096, Total: -1, Terms: 4096}, Done: true, Status: 130, Signal: "interrupt"}
+	evs[0].ElapsedMS = 0
+	if len(evs) != 1 || evs[0] != want {
+		t.Errorf("events after a signal are %+v, want %+v", evs, want)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cpu, mem := filepath.Join(dir, "cpu"), filepath.Join(dir, "mem")
+	// checkProfile fails the test unless name holds a profile, which pprof
+	// writes gzipped.
+	checkProfile := func(name string) {
+		t.Helper()
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if b, err := io.ReadAll(r); err != nil || len(b) == 0 {
+			t.Errorf("%s: %d bytes of profile with error %v", name, len(b), err)
+		}
+	}
+	// Profiles are written however the run ends.
+	for _, o := range []string{filepath.Join(dir, "out"), "/dev/full"} {
+		os.Remove(cpu)
+		os.Remove(mem)
+		captureLog(t)
+		run([]string{"-q", "-order", "3", "-o", o, "-cpuprofile", cpu, "-memprofile", mem})
+		checkProfile(cpu)
+		checkProfile(mem)
+	}
+	captureLog(t)
+	if err := run([]string{"-q", "-order", "2", "-o", filepath.Join(dir, "out"), "-cpuprofile", filepath.Join(dir, "missing", "cpu")}); err == nil {
+		t.Error("no error for a CPU profile which can't be created")
+	}
+	logged := captureLog(t)
+	if err := run([]string{"-q", "-order", "2", "-o", filepath.Join(dir, "out"), "-memprofile", filepath.Join(dir, "missing", "mem")}); err != nil {
+		t.Errorf("allocation profile which can't be created: %v", err)
+	}
+	if !strings.Contains(logged.String(), "writing allocation profile") {
+		t.Errorf("no error logged for an allocation profile which can't be created: %q", logged)
+	}
+}
+
+func TestOutputErrors(t *testing.T) {
+	logged := captureLog(t)
+	err := run([]string{"-q", "-order", "2", "-o", "/dev/full"})
+	if statusOf(err) != 3 || !errors.Is(err, syscall.ENOSPC) || err.Error() != "write /dev/full: no space left on device" {
+		t.Errorf("writing to /dev/full: exit status %d with error %v", statusOf(err), err)
+	}
+	// The message says how much output was written before the failure.
+	err = run([]string{"-q", "-order", "2", "-o", "/dev/full", "-bin", "-resume", "1000"})
+	if statusOf(err) != 3 || !errors.Is(err, syscall.ENOSPC) || !strings.HasPrefix(fmt.Sprint(err), "writing output failed after 1000 bytes: ") {
+		t.Errorf("writing to /dev/full after 1000 bytes: exit status %d with error %v", statusOf(err), err)
+	}
+	// A reader which goes away isn't a failure.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+	go func() {
+		io.CopyN(io.Discard, r, 100)
+		r.Close()
+	}()
+	logged.Reset()
+	err = run([]string{"-v", "-order", "3"})
+	w.Close()
+	if err != nil {
+		t.Errorf("writing to a closed pipe: %v", err)
+	}
+	// Nor does it end with a summary of output which wasn't all written.
+	if strings.Contains(logged.String(), "wrote ") {
+		t.Errorf("writing to a closed pipe logged %q", logged)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"-bin"},
+		{"-bin", "-limit", "1000"},
+		{"-format", "dot"},
+		{"-format", "hexlines", "-gzip"},
+		{"-ipv4", "-order", "4", "-limit", "1000"},
+	} {
+		logged := captureLog(t)
+		name := filepath.Join(dir, "out")
+		if err := run(append([]string{"-order", "2", "-o", name, "-check", "-force"}, args...)); err != nil {
+			t.Errorf("%q: %v", args, err)
+		}
+		if !strings.Contains(logged.String(), "check passed: ") {
+			t.Errorf("%q: log is %q", args, logged)
+		}
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out")
+	opts := debruijn.Options{Format: debruijn.Dot, Order: 2}
+	var whole bytes.Buffer
+	if _, err := debruijn.Write(&whole, opts); err != nil {
+		t.Fatal(err)
+	}
+	regenerate := func(w io.Writer) error {
+		_, err := debruijn.Write(w, opts)
+		return err
+	}
+	corrupt := bytes.Clone(whole.Bytes())
+	corrupt[1234] = '9'
+	cases := []struct {
+		name string
+		data []byte
+		msg  string
+	}{
+		{"intact", whole.Bytes(), "check passed: 233985 bytes match the output"},
+		{"corrupt", corrupt, "differs from the output at offset 1234"},
+		{"short", whole.Bytes()[:1000], "differs from the output at offset 1000"},
+		{"long", append(bytes.Clone(whole.Bytes()), '.'), "differs from the output at offset 233985"},
+	}
+	for _, c := range cases {
+		if err := os.WriteFile(name, c.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		logged := captureLog(t)
+		err := checkFile(name, 0, nil, 0, regenerate, int64(whole.Len()), false, false)
+		if (err == nil) != (c.name == "intact") || err != nil && statusOf(err) != 1 {
+			t.Errorf("%s: got error %v", c.name, err)
+		}
+		if !strings.Contains(logged.String(), c.msg) {
+			t.Errorf("%s: log is %q, want %q", c.name, logged, c.msg)
+		}
+	}
+	// The whole binary sequence is checked for itself, window by window.
+	var bin bytes.Buffer
+	if _, err := debruijn.Write(&bin, debruijn.Options{Order: 2}); err != nil {
+		t.Fatal(err)
+	}
+	b := bin.Bytes()
+	b[500] = b[499]
+	if err := os.WriteFile(name, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	logged := captureLog(t)
+	if err := checkFile(name, 0, nil, 2, nil, int64(len(b)), false, false); statusOf(err) != 1 {
+		t.Errorf("binary with a repeated window: got error %v", err)
+	}
+	if !strings.Contains(logged.String(), "check failed: 65537 bytes, 65536 windows, ") {
+		t.Errorf("binary with a repeated window: log is %q", logged)
+	}
+}
+
+func TestPad(t *testing.T) {
+	pad, status := runOutput(t, "-pad", "-order", "2")
+	if status != 0 || !strings.HasPrefix(pad, "000.000.001.000.002.") {
+		t.Fatalf("-pad gave %.20q... with exit status %d", pad, status)
+	}
+	if got, _ := runOutput(t, "-format", "padded", "-order", "2"); got != pad {
+		t.Error("-pad differs from -format padded")
+	}
+	lines, _ := runOutput(t, "-pad", "-n", "-order", "2")
+	if got, _ := runOutput(t, "-format", "paddedlines", "-order", "2"); got != lines || lines != strings.ReplaceAll(pad, ".", "\n") {
+		t.Error("-pad -n differs from -format paddedlines")
+	}
+	if size, _ := runOutput(t, "-pad", "-order", "2", "-size"); size != strconv.Itoa(len(pad))+"\n" || len(pad) != 4*(1<<16+1)-1 {
+		t.Errorf("-size says %q, but -pad wrote %d bytes", size, len(pad))
+	}
+	bin, _ := runOutput(t, "-bin", "-order", "2")
+	want, _ := decodeOutput(t, bin)
+	if got, status := decodeOutput(t, pad, "-format", "padded"); status != 0 || got != want {
+		t.Errorf("decoding -pad output gave %.40q... with exit status %d, want %.40q...", got, status, want)
+	}
+	for _, args := range [][]string{{"-pad", "-bin"}, {"-pad", "-hex"}, {"-pad", "-ipv4"}} {
+		if _, status := runOutput(t, args...); status != 2 {
+			t.Errorf("%q: exit status %d, want 2", args, status)
+		}
+	}
+}
+
+func TestFormatAliases(t *testing.T) {
+	cases := []struct{ alias, format []string }{
+		{[]string{"-bin"}, []string{"-format", "bin"}},
+		{[]string{"-n"}, []string{"-format", "lines"}},
+		{[]string{"-hex"}, []string{"-format", "hex"}},
+		{[]string{"-hex", "-n"}, []string{"-format", "hexlines"}},
+		{[]string{"-pad"}, []string{"-format", "padded"}},
+		{[]string{"-pad", "-n"}, []string{"-format", "paddedlines"}},
+		{[]string{"-ipv4"}, []string{"-format", "quad"}},
+		{nil, []string{"-format", "dot"}},
+		{[]string{"-format", ""}, []string{"-format", "dot"}},
+	}
+	for _, c := range cases {
+		a, _ := runOutput(t, append(c.alias, "-limit", "1000")...)
+		b, status := runOutput(t, append(c.format, "-limit", "1000")...)
+		if status != 0 || a != b || a == "" {
+			t.Errorf("%q gave %.20q..., but %q gave %.20q... with exit status %d", c.alias, a, c.format, b, status)
+		}
+	}
+	// Every format the library names is one of -format's.
+	for _, name := range debruijn.FormatNames() {
+		if _, status := runOutput(t, "-format", name, "-limit", "10"); status != 0 {
+			t.Errorf("-format %s: exit status %d", name, status)
+		}
+	}
+	stderr := redirect(t, &os.Stderr)
+	logged := captureLog(t)
+	err := run([]string{"-format", "csv", "-limit", "10"})
+	if statusOf(err) != 2 || !strings.Contains(fmt.Sprint(err)+logged.String()+contents(t, stderr), strings.Join(debruijn.FormatNames(), ", ")) {
+		t.Errorf("-format csv: exit status %d with error %v, want 2 and the list of formats", statusOf(err), err)
+	}
+	for _, args := range [][]string{{"-format", "dot", "-bin"}, {"-format", "hex", "-n"}} {
+		if _, status := runOutput(t, append(args, "-limit", "10")...); status != 2 {
+			t.Errorf("%q: exit status %d, want 2", args, status)
+		}
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	// The tail of the sequence from -resume, across several chunks of the
+	// parallel writer, is the same with any number of workers.
+	dir := t.TempDir()
+	resume := strconv.FormatInt(1<<32+3-9_000_000, 10)
+	want := generateFile(t, dir, "-bin", "-resume", resume)
+	if len(want) != 9_000_000 {
+		t.Fatalf("serial output is %d bytes", len(want))
+	}
+	for _, workers := range []string{"2", "5"} {
+		got := generateFile(t, dir, "-bin", "-resume", resume, "-workers", workers)
+		if !bytes.Equal(got, want) {
+			t.Errorf("-workers %s: %d bytes differing from serial", workers, len(got))
+		}
+	}
+	if err := run([]string{"-q", "-order", "2", "-format", "dot", "-workers", "2", "-o", filepath.Join(dir, "text")}); statusOf(err) != 2 {
+		t.Errorf("-workers with text output: got %v, want a usage error", err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	h := sequenceHandler()
+	tail := func(off int64, n int) []byte {
+		var b bytes.Buffer
+		if _, err := debruijn.Write(&b, debruijn.Options{Skip: uint64(off), Limit: int64(n)}); err != nil {
+			t.Fatal(err)
+		}
+		return b.Bytes()
+	}
+	const size = 1<<32 + 3
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+		status int
+		off    int64
+		n      int
+	}{
+		{"head", http.MethodHead, "/", nil, http.StatusOK, 0, 0},
+		{"head bin", http.MethodHead, "/bin", nil, http.StatusOK, 0, 0},
+		{"range", http.MethodGet, "/", map[string]string{"Range": "bytes=1000-1099"}, http.StatusPartialContent, 1000, 100},
+		{"range bin", http.MethodGet, "/bin", map[string]string{"Range": "bytes=3000000000-3000000009"}, http.StatusPartialContent, 3000000000, 10},
+		{"suffix", http.MethodGet, "/", map[string]string{"Range": "bytes=-7"}, http.StatusPartialContent, size - 7, 7},
+		{"if-range", http.MethodGet, "/", map[string]string{"Range": "bytes=5-9", "If-Range": `"B(256,4)"`}, http.StatusPartialContent, 5, 5},
+		{"past the end", http.MethodGet, "/", map[string]string{"Range": "bytes=4294967299-"}, http.StatusRequestedRangeNotSatisfiable, 0, -1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.method, c.path, nil)
+			for k, v := range c.header {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.status {
+				t.Fatalf("status %d, want %d", w.Code, c.status)
+			}
+			if c.n < 0 {
+				return
+			}
+			want := strconv.Itoa(size)
+			if c.method == http.MethodGet {
+				want = strconv.Itoa(c.n)
+			}
+			if got := w.Header().Get("Content-Length"); got != want {
+				t.Errorf("Content-Length %s, want %s", got, want)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/octet-stream" {
+				t.Errorf("Content-Type %q", got)
+			}
+			if c.method == http.MethodGet && !bytes.Equal(w.Body.Bytes(), tail(c.off, c.n)) {
+				t.Errorf("body %x, want %x", w.Body.Bytes(), tail(c.off, c.n))
+			}
+		})
+	}
+	// A mismatched If-Range gets the whole sequence rather than the range;
+	// HEAD shows it without generating it.
+	r := httptest.NewRequest(http.MethodHead, "/", nil)
+	r.Header.Set("Range", "bytes=5-9")
+	r.Header.Set("If-Range", `"other"`)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("mismatched If-Range: status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bogus", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "hexlines") {
+		t.Errorf("unknown format: status %d, body %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServeText(t *testing.T) {
+	srv := httptest.NewServer(sequenceHandler())
+	defer srv.Close()
+	for _, format := range []string{"dot", "hexlines", "padded"} {
+		f, _ := debruijn.ParseFormat(format)
+		var want bytes.Buffer
+		if _, err := debruijn.Write(&want, debruijn.Options{Format: f, Limit: 100000}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Get(srv.URL + "/" + format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The text streams from the start with no length, and the client
+		// hanging up stops it.
+		got := make([]byte, want.Len())
+		_, err = io.ReadFull(resp.Body, got)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.ContentLength != -1 || resp.Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+			t.Errorf("%s: status %d, length %d, type %q", format, resp.StatusCode, resp.ContentLength, resp.Header.Get("Content-Type"))
+		}
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("%s: body differs from generate", format)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"short", "\x01\x02\x03", ""},
+		{"one window", "\x01\x02\x03\x04", "1.2.3.4\n"},
+		{"windows", "\x00\x00\x00\x00\x01\xff", "0.0.0.0\n0.0.0.1\n0.0.1.255\n"},
+	}
+	for _, c := range cases {
+		got, status := decodeOutput(t, c.in)
+		if status != 0 || got != c.want {
+			t.Errorf("%s: decoded to %q with status %d, want %q", c.name, got, status, c.want)
+		}
+	}
+	// Each window of the output is the address at its offset.
+	bin, _ := runOutput(t, "-bin", "-limit", "1000")
+	got, _ := decodeOutput(t, bin)
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 997 {
+		t.Fatalf("%d addresses from 1000 bytes", len(lines))
+	}
+	for i, line := range lines {
+		if addr, _ := debruijn.AddrAt(int64(i)); line != addr.String() {
+			t.Fatalf("address %d is %s, want %s", i, line, addr)
+		}
+	}
+	// Text formats decode to the same addresses as the binary.
+	for _, format := range []string{"dot", "lines", "hex", "hexlines", "padded", "paddedlines"} {
+		text, _ := runOutput(t, "-format", format, "-limit", "1000")
+		if s, status := decodeOutput(t, text, "-format", format); status != 0 || s != got {
+			t.Errorf("-format %s: status %d, and addresses differ from the binary's", format, status)
+		}
+	}
+	// Reading from stdin.
+	in := redirect(t, &os.Stdin)
+	if _, err := in.WriteString(bin); err != nil {
+		t.Fatal(err)
+	}
+	in.Seek(0, io.SeekStart)
+	out := redirect(t, &os.Stdout)
+	if err := run([]string{"decode", "-"}); err != nil || contents(t, out) != got {
+		t.Errorf("decode -: got error %v, and addresses differ from the file's", err)
+	}
+	// Errors in the stream and the file.
+	if _, status := decodeOutput(t, "0.1.x.3", "-format", "dot"); status != 1 {
+		t.Errorf("bad text: status %d, want 1", status)
+	}
+	logged := captureLog(t)
+	if err := run([]string{"decode", filepath.Join(t.TempDir(), "missing")}); statusOf(err) != 1 || !strings.Contains(logged.String(), "no such file") {
+		t.Errorf("missing file: got status %d, logged %q", statusOf(err), logged.String())
+	}
+}
+
+func TestVerifyCommand(t *testing.T) {
+	dir := t.TempDir()
+	bin := generateFile(t, dir, "-bin", "-order", "2")
+	bad := slices.Clone(bin)
+	bad[100] ^= 1
+	cases := []struct {
+		name   string
+		in     []byte
+		status int
+		want   string
+	}{
+		{"whole", bin, 0, "65537 bytes, 65536 windows, 0 missing, 0 duplicates\nok\n"},
+		{"corrupt", bad, 1, "65537 bytes, 65536 windows, 2 missing, 2 duplicates\nfirst anomaly at offset"},
+		{"short", bin[:1000], 1, "1000 bytes, 999 windows, 64537 missing, 0 duplicates\nfirst anomaly at offset 1000\n"},
+	}
+	for _, c := range cases {
+		name := filepath.Join(dir, c.name)
+		if err := os.WriteFile(name, c.in, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Read from the file and from stdin.
+		for _, arg := range []string{name, "-"} {
+			in := redirect(t, &os.Stdin)
+			in.Write(c.in)
+			in.Seek(0, io.SeekStart)
+			out := redirect(t, &os.Stdout)
+			status := statusOf(run([]string{"verify", "-order", "2", arg}))
+			if got := contents(t, out); status != c.status || !strings.HasPrefix(got, c.want) {
+				t.Errorf("%s from %s: status %d with %q, want %d with %q", c.name, arg, status, got, c.status, c.want)
+			}
+		}
+	}
+	logged := captureLog(t)
+	if err := run([]string{"verify", filepath.Join(dir, "missing")}); statusOf(err) != 1 || !strings.Contains(logged.String(), "no such file") {
+		t.Errorf("missing file: got status %d, logged %q", statusOf(err), logged.String())
+	}
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	// Offsets of random addresses convert back to the same addresses.
+	rng := rand.New(rand.NewPCG(1, 2))
+	addrs := []string{"0.0.0.0", "255.255.255.255", "::ffff:10.0.0.1"}
+	for range 20 {
+		addrs = append(addrs, netip.AddrFrom4([4]byte{byte(rng.Uint32()), byte(rng.Uint32()), byte(rng.Uint32()), byte(rng.Uint32())}).String())
+	}
+	out := redirect(t, &os.Stdout)
+	if err := run(append([]string{"index"}, addrs...)); err != nil {
+		t.Fatal(err)
+	}
+	offsets := strings.Fields(contents(t, out))
+	if len(offsets) != len(addrs) {
+		t.Fatalf("%d offsets for %d addresses", len(offsets), len(addrs))
+	}
+	out = redirect(t, &os.Stdout)
+	if err := run(append([]string{"index", "-offset"}, offsets...)); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Fields(contents(t, out))
+	if len(got) != len(addrs) {
+		t.Fatalf("%d addresses for %d offsets", len(got), len(addrs))
+	}
+	for i, addr := range addrs {
+		if want := netip.MustParseAddr(addr).Unmap().String(); got[i] != want {
+			t.Errorf("%s: offset %s converts back to %s", addr, offsets[i], got[i])
+		}
+	}
+	// Offsets parse with a base prefix, and those beginning no window fail
+	// without stopping the rest.
+	out = redirect(t, &os.Stdout)
+	logged := captureLog(t)
+	err := run([]string{"index", "-offset", "4294967295", "4294967296", "-1", "0x10"})
+	if statusOf(err) != 1 || contents(t, out) != "255.0.0.0\n4.0.0.0\n" || strings.Count(logged.String(), "no window begins") != 2 {
+		t.Errorf("got status %d, output %q, logged %q", statusOf(err), contents(t, out), logged.String())
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapFile sizes f to size bytes and maps it into memory for writing. The
+// returned function syncs the mapping to the file and unmaps it.
+func mapFile(f *os.File, size int64) ([]byte, func() error, error) {
+	if err := f.Truncate(size); err != nil {
+		return nil, nil, err
+	}
+	if size == 0 {
+		// There is nothing to map, and mmap rejects empty mappings.
+		return nil, func() error { return nil }, nil
+	}
+	if size != int64(int(size)) {
+		return nil, nil, syscall.EFBIG
+	}
+	b, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	unmap := func() error {
+		_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), syscall.MS_SYNC)
+		if err := syscall.Munmap(b); err != nil {
+			return err
+		}
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	}
+	return b, unmap, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// mapFile reports that mapping files is unsupported on this platform, so that
+// -mmap falls back to ordinary writes.
+func mapFile(f *os.File, size int64) ([]byte, func() error, error) {
+	return nil, nil, errors.New("memory-mapped output is not supported on this platform")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zephyrtronium/conip/debruijn"
+)
+
+// serve runs the serve command and returns the exit status: 1 if the server
+// can't listen or fails, and 2 for bad arguments. It doesn't return
+// otherwise.
+func serve(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `usage: conip serve [flags]
+
+Serve the sequence over HTTP. GET / or /bin gives the binary output, with its
+Content-Length, and honors Range requests by seeking straight to the offset,
+so that an interrupted download can resume, as with curl -C -. GET /name for
+any other format of conip generate -format streams that format with chunked
+encoding, from the start every time.
+
+`)
+		fs.PrintDefaults()
+	}
+	addr := ":8080"
+	fs.StringVar(&addr, "http", ":8080", "address on which to listen, host:port")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	log.Printf("serving on http://%s/", l.Addr())
+	srv := &http.Server{
+		Handler:           sequenceHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	err = srv.Serve(l)
+	log.Print(err)
+	return 1
+}
+
+// sequenceHandler returns the handler for the serve command.
+func sequenceHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", serveBinary)
+	mux.HandleFunc("GET /{format}", func(w http.ResponseWriter, r *http.Request) {
+		format, err := debruijn.ParseFormat(r.PathValue("format"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unknown format; choose from %s", strings.Join(debruijn.FormatNames(), ", ")), http.StatusNotFound)
+			return
+		}
+		if format == debruijn.Binary {
+			serveBinary(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if r.Method == http.MethodHead {
+			return
+		}
+		// Without a Content-Length, the response is chunked, and the
+		// client can stop reading whenever it likes.
+		_, err = debruijn.Write(w, debruijn.Options{Format: format, BufferSize: 64 << 10})
+		if err != nil && r.Context().Err() == nil {
+			// Not just the client going away.
+			log.Printf("serving %s to %s: %v", r.URL.Path, r.RemoteAddr, err)
+		}
+	})
+	return mux
+}
+
+// serveBinary serves the binary output. ServeContent handles Range and HEAD
+// requests, seeking the Reader to each range rather than generating the
+// bytes before it.
+func serveBinary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	// The content never changes, so a fixed tag lets If-Range work.
+	w.Header().Set("ETag", `"B(256,4)"`)
+	http.ServeContent(w, r, "", time.Time{}, debruijn.NewReader())
+}
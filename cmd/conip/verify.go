@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/zephyrtronium/conip/debruijn"
+)
+
+// verify runs the verify command and returns the exit status: 0 if the stream
+// is a complete de Bruijn sequence, 1 if it isn't or can't be read, and 2 for
+// bad arguments.
+func verify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `usage: conip verify [flags] [file]
+
+Check that the binary output of conip generate -bin in file, or stdin if file
+is absent or -, contains every string of order bytes exactly once. The check
+keeps a bitset of 256^order bits, 512 MiB for order 4.
+
+`)
+		fs.PrintDefaults()
+	}
+	order := 4
+	self := false
+	fs.IntVar(&order, "order", 4, "order of the sequence, at most 4")
+	fs.BoolVar(&self, "self", false, "generate the sequence and check it instead of reading a stream")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if order < 1 || order > 4 || fs.NArg() > 1 || self && fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	if self {
+		if err := debruijn.Verify(order); err != nil {
+			log.Print(err)
+			return 1
+		}
+		fmt.Println("ok")
+		return 0
+	}
+	var r io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	rep, err := debruijn.VerifyReader(r, order)
+	if err != nil {
+		if errors.Is(err, debruijn.ErrOrder) {
+			fs.Usage()
+			return 2
+		}
+		log.Printf("reading failed after %d bytes: %v", rep.Length, err)
+		return 1
+	}
+	fmt.Printf("%d bytes, %d windows, %d missing, %d duplicates\n", rep.Length, rep.Windows, rep.Missing, rep.Duplicates)
+	if !rep.OK() {
+		fmt.Printf("first anomaly at offset %d\n", rep.FirstAnomaly)
+		return 1
+	}
+	fmt.Println("ok")
+	return 0
+}
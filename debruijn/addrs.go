@@ -0,0 +1,135 @@
+package debruijn
+
+import (
+	"iter"
+	"net/netip"
+	"slices"
+)
+
+// Addrs returns an iterator over every IPv4 address, each exactly once, in the
+// order in which its window of four consecutive terms completes in B(256, 4).
+// Each range over the iterator starts again from the beginning.
+func Addrs() iter.Seq[netip.Addr] {
+	return func(yield func(netip.Addr) bool) {
+		var g Generator
+		g.Addrs()(yield)
+	}
+}
+
+// Addrs returns an iterator over the IPv4 addresses formed by each window of
+// four consecutive terms among the remaining terms of g, the first window
+// beginning with g's next term. If g does not generate B(256, 4), the iterator
+// yields nothing.
+func (g *Generator) Addrs() iter.Seq[netip.Addr] {
+	return func(yield func(netip.Addr) bool) {
+		if !g.standard() {
+			return
+		}
+		for win := range g.Windows() {
+			if !yield(netip.AddrFrom4(win)) {
+				return
+			}
+		}
+	}
+}
+
+// rankedBits is the least prefix length for which AddrsIn ranks each address
+// of the prefix rather than scanning the sequence for them, so that it sorts
+// at most 2^20 offsets.
+const rankedBits = 12
+
+// AddrsIn returns an iterator over the IPv4 addresses in the prefix p, each
+// exactly once, in the order Addrs yields them. For prefixes of at least 12
+// bits, it finds each address's offset in the sequence directly with Rank;
+// for shorter ones, it scans the sequence for them, stopping once it has
+// found them all. If p is not an IPv4 prefix, the iterator yields nothing.
+func AddrsIn(p netip.Prefix) iter.Seq[netip.Addr] {
+	return func(yield func(netip.Addr) bool) {
+		if !p.IsValid() || !p.Addr().Is4() {
+			return
+		}
+		p = p.Masked()
+		if p.Bits() < rankedBits {
+			left := uint64(1) << (32 - p.Bits())
+			for addr := range Addrs() {
+				if !p.Contains(addr) {
+					continue
+				}
+				if !yield(addr) {
+					return
+				}
+				left--
+				if left == 0 {
+					return
+				}
+			}
+			return
+		}
+		// Pair each offset with its address to sort them together.
+		base := p.Addr().As4()
+		lo := uint64(base[0])<<24 | uint64(base[1])<<16 | uint64(base[2])<<8 | uint64(base[3])
+		ranked := make([]uint64, 1<<(32-p.Bits()))
+		for i := range ranked {
+			a := lo + uint64(i)
+			addr := netip.AddrFrom4([4]byte{byte(a >> 24), byte(a >> 16), byte(a >> 8), byte(a)})
+			ranked[i] = uint64(Rank(addr))<<32 | a
+		}
+		slices.Sort(ranked)
+		for _, r := range ranked {
+			a := uint32(r)
+			if !yield(netip.AddrFrom4([4]byte{byte(a >> 24), byte(a >> 16), byte(a >> 8), byte(a)})) {
+				return
+			}
+		}
+	}
+}
+
+// Windows returns an iterator over the 2^32 windows of four consecutive terms
+// of B(256, 4), including the three which wrap around the end of the cycle.
+// Each range over the iterator starts again from the beginning.
+func Windows() iter.Seq[[4]byte] {
+	return func(yield func([4]byte) bool) {
+		var g Generator
+		g.Windows()(yield)
+	}
+}
+
+// WindowsFrom returns an iterator over the windows of B(256, 4) beginning at
+// offset off and later, so that the first window is the one AddrAt(off)
+// gives. Windows begin only at offsets in [0, 2^32); WindowsFrom returns an
+// error for any other.
+func WindowsFrom(off int64) (iter.Seq[[4]byte], error) {
+	if off < 0 || off >= int64(blocks[256]) {
+		return nil, errNoWindow
+	}
+	return func(yield func([4]byte) bool) {
+		var g Generator
+		g.Skip(uint64(off))
+		g.Windows()(yield)
+	}, nil
+}
+
+// Windows returns an iterator over each window of four consecutive terms
+// among the remaining terms of g, the first beginning with g's next term. For
+// B(k, 4), a range over the entire sequence yields each of the k^4 strings of
+// four terms exactly once. If g's order is not 4, the iterator yields
+// nothing.
+func (g *Generator) Windows() iter.Seq[[4]byte] {
+	return func(yield func([4]byte) bool) {
+		if g.Order() != 4 {
+			return
+		}
+		var win [4]byte
+		primed := 0
+		for term := range g.Terms() {
+			win[0], win[1], win[2], win[3] = win[1], win[2], win[3], term
+			if primed < 3 {
+				primed++
+				continue
+			}
+			if !yield(win) {
+				return
+			}
+		}
+	}
+}
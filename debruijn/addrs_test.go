@@ -0,0 +1,171 @@
+package debruijn
+
+import (
+	"errors"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestAddrs(t *testing.T) {
+	const n = 100000
+	var win [4]byte
+	var want []netip.Addr
+	k := 0
+	for term := range Terms() {
+		win[0], win[1], win[2], win[3] = win[1], win[2], win[3], term
+		if k++; k < 4 {
+			continue
+		}
+		want = append(want, netip.AddrFrom4(win))
+		if len(want) == n {
+			break
+		}
+	}
+	seen := make(map[netip.Addr]bool, n)
+	i := 0
+	for addr := range Addrs() {
+		if addr != want[i] {
+			t.Fatalf("address %d is %v, want %v", i, addr, want[i])
+		}
+		if seen[addr] {
+			t.Fatalf("address %d, %v, appeared earlier", i, addr)
+		}
+		seen[addr] = true
+		if i++; i == n {
+			break
+		}
+	}
+	if i != n {
+		t.Errorf("only %d addresses", i)
+	}
+}
+
+func TestAddrsAllocs(t *testing.T) {
+	var g Generator
+	allocs := func(n int) float64 {
+		return testing.AllocsPerRun(10, func() {
+			k := 0
+			for range g.Addrs() {
+				if k++; k == n {
+					break
+				}
+			}
+		})
+	}
+	// Each range allocates only its closures, nothing per address.
+	if few, many := allocs(10), allocs(100000); many != few {
+		t.Errorf("%v allocations for 100000 addresses but %v for 10", many, few)
+	}
+}
+
+func TestAddrsOrder(t *testing.T) {
+	g, _ := New(3)
+	for addr := range g.Addrs() {
+		t.Fatalf("B(256, 3) gave address %v", addr)
+	}
+}
+
+func TestWindowsPrefix(t *testing.T) {
+	want := [][4]byte{
+		{0, 0, 0, 0}, {0, 0, 0, 1}, {0, 0, 1, 0}, {0, 1, 0, 0}, {1, 0, 0, 0},
+		{0, 0, 0, 2}, {0, 0, 2, 0}, {0, 2, 0, 0}, {2, 0, 0, 0},
+		{0, 0, 0, 3}, {0, 0, 3, 0}, {0, 3, 0, 0},
+	}
+	var got [][4]byte
+	for w := range Windows() {
+		if got = append(got, w); len(got) == len(want) {
+			break
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("first windows are %v, want %v", got, want)
+	}
+	const n = 1 << 20
+	seen := make(map[[4]byte]bool, n)
+	for w := range Windows() {
+		if seen[w] {
+			t.Fatalf("window %v repeats after %d", w, len(seen))
+		}
+		if seen[w] = true; len(seen) == n {
+			break
+		}
+	}
+}
+
+func TestWindowsEnd(t *testing.T) {
+	// The last windows include the three which wrap around to the start.
+	const n = 1000
+	seq, err := WindowsFrom(1<<32 - n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][4]byte
+	for w := range seq {
+		got = append(got, w)
+	}
+	if len(got) != n {
+		t.Fatalf("%d windows from offset 2^32 - %d", len(got), n)
+	}
+	wrap := [][4]byte{{255, 255, 255, 0}, {255, 255, 0, 0}, {255, 0, 0, 0}}
+	if !slices.Equal(got[n-3:], wrap) {
+		t.Errorf("last windows are %v, want %v", got[n-3:], wrap)
+	}
+	seen := make(map[[4]byte]bool, n)
+	for _, w := range got {
+		if seen[w] {
+			t.Errorf("window %v repeats", w)
+		}
+		seen[w] = true
+	}
+}
+
+func TestWindowsFrom(t *testing.T) {
+	for _, off := range []int64{0, 1, 4, 1000, 1 << 24, 1<<32 - 4, 1<<32 - 1} {
+		seq, err := WindowsFrom(off)
+		if err != nil {
+			t.Fatalf("offset %d: %v", off, err)
+		}
+		want, err := AddrAt(off)
+		if err != nil {
+			t.Fatalf("offset %d: %v", off, err)
+		}
+		for w := range seq {
+			if netip.AddrFrom4(w) != want {
+				t.Errorf("offset %d: first window is %v, want %v", off, w, want)
+			}
+			break
+		}
+	}
+	for _, off := range []int64{-1, 1 << 32, 1<<32 + 2} {
+		if _, err := WindowsFrom(off); !errors.Is(err, errNoWindow) {
+			t.Errorf("offset %d: got error %v, want %v", off, err, errNoWindow)
+		}
+	}
+}
+
+func TestGeneratorWindows(t *testing.T) {
+	// For B(k, 4), the windows are each string of four terms exactly once.
+	for _, k := range []int{1, 2, 3, 10} {
+		g, err := DeBruijn(k, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := make(map[[4]byte]bool)
+		count := 0
+		for w := range g.Windows() {
+			count++
+			if seen[w] {
+				t.Errorf("k=%d: window %v repeats", k, w)
+			}
+			seen[w] = true
+		}
+		if count != k*k*k*k {
+			t.Errorf("k=%d: %d windows, want %d", k, count, k*k*k*k)
+		}
+	}
+	g, _ := New(3)
+	for w := range g.Windows() {
+		t.Fatalf("B(256, 3) gave window %v", w)
+	}
+}
@@ -0,0 +1,49 @@
+package debruijn
+
+import (
+	"iter"
+	"sync"
+)
+
+// slabs holds buffers for Chunks, as *[]byte.
+var slabs sync.Pool
+
+// Chunks returns an iterator over B(256, 4) in slabs of size bytes, the last
+// possibly shorter, in the binary encoding. Each range over the iterator
+// starts again from the beginning of the sequence. See Generator.Chunks.
+func Chunks(size int) iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		var g Generator
+		g.Chunks(size)(yield)
+	}
+}
+
+// Chunks returns an iterator over the remaining terms of g in slabs of size
+// bytes in the binary encoding, generated directly as WriteTo does. Every slab
+// is full except possibly the last. If size is not positive, the slabs are
+// 64 KiB.
+//
+// Each slab is valid only until the next iteration, when its memory is
+// reused; the buffer returns to a pool shared by all calls once the range
+// ends. A caller which retains a slab, e.g. to hand it to another goroutine,
+// must copy it.
+func (g *Generator) Chunks(size int) iter.Seq[[]byte] {
+	if size <= 0 {
+		size = slabSize
+	}
+	return func(yield func([]byte) bool) {
+		bp, _ := slabs.Get().(*[]byte)
+		if bp == nil || cap(*bp) < size {
+			b := make([]byte, size)
+			bp = &b
+		}
+		defer slabs.Put(bp)
+		buf := (*bp)[:size]
+		for {
+			k := g.fill(buf)
+			if k == 0 || !yield(buf[:k]) || k < size {
+				return
+			}
+		}
+	}
+}
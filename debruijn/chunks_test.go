@@ -0,0 +1,73 @@
+package debruijn
+
+import (
+	"bytes"
+	"testing"
+)
+
+// prefix returns the first n bytes of the binary encoding of B(256, 4).
+func prefix(t testing.TB, n int64) []byte {
+	var b bytes.Buffer
+	if _, err := Write(&b, Options{Limit: n}); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestChunks(t *testing.T) {
+	want := prefix(t, 1<<20+5)
+	for _, size := range []int{1, 7, 4096, 0, 1 << 20} {
+		var got []byte
+		for c := range Chunks(size) {
+			if size > 0 && len(c) != size || size <= 0 && len(c) != slabSize {
+				t.Fatalf("size %d: slab of %d bytes", size, len(c))
+			}
+			got = append(got, c...)
+			if len(got) >= len(want) {
+				break
+			}
+		}
+		if !bytes.Equal(got[:len(want)], want) {
+			t.Errorf("size %d: slabs differ from the sequence", size)
+		}
+	}
+}
+
+func TestChunksEnd(t *testing.T) {
+	var want bytes.Buffer
+	g, err := DeBruijn(4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.WriteTo(&want); err != nil {
+		t.Fatal(err)
+	}
+	g, _ = DeBruijn(4, 3)
+	var got []byte
+	var last []byte
+	for c := range g.Chunks(5) {
+		got = append(got, c...)
+		last = c
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("slabs give %x, want %x", got, want.Bytes())
+	}
+	if len(last) != want.Len()%5 {
+		t.Errorf("last slab has %d bytes, want %d", len(last), want.Len()%5)
+	}
+	for range g.Chunks(5) {
+		t.Fatal("slab after the end")
+	}
+}
+
+func BenchmarkChunks(b *testing.B) {
+	b.SetBytes(slabSize)
+	// Each range starts over at the end of the sequence.
+	for n := 0; n < b.N; {
+		for range Chunks(slabSize) {
+			if n++; n == b.N {
+				break
+			}
+		}
+	}
+}
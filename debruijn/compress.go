@@ -0,0 +1,103 @@
+package debruijn
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is a compression of the output.
+type Compression int
+
+const (
+	// NoCompression writes the output as it is.
+	NoCompression Compression = iota
+	// Gzip compresses the output with gzip.
+	Gzip
+	// Zstd compresses the output with Zstandard.
+	Zstd
+)
+
+// MaxLevel returns the highest compression level c supports, or 0 for
+// NoCompression. Levels run from 1, the fastest, through MaxLevel, the
+// smallest, with 0 standing for the default.
+func (c Compression) MaxLevel() int {
+	switch c {
+	case Gzip:
+		return gzip.BestCompression
+	case Zstd:
+		return 22
+	}
+	return 0
+}
+
+// NewWriter returns a writer which compresses what is written to it at the
+// given level and writes it to w. Closing it writes the end of the stream but
+// does not close w. For NoCompression, it writes to w directly and closing it
+// does nothing.
+func (c Compression) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if err := c.check(level); err != nil {
+		return nil, err
+	}
+	switch c {
+	case Gzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case Zstd:
+		l := zstd.SpeedDefault
+		if level != 0 {
+			l = zstd.EncoderLevelFromZstd(level)
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(l))
+	}
+	return nopCloser{w}, nil
+}
+
+// NewReader returns a reader which decompresses what it reads from r as c
+// compressed it. Closing it releases its resources but does not close r.
+func (c Compression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case NoCompression:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return nil, errCompression
+}
+
+// check returns an error if c isn't a Compression or level isn't one of its
+// levels.
+func (c Compression) check(level int) error {
+	switch {
+	case c < NoCompression || c > Zstd:
+		return errCompression
+	case c == NoCompression && level != 0:
+		return errCompressionLevel
+	case level < 0 || level > c.MaxLevel():
+		return fmt.Errorf("debruijn: compression level must be between 1 and %d, got %d", c.MaxLevel(), level)
+	}
+	return nil
+}
+
+// nopCloser is a WriteCloser whose Close does nothing.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+var (
+	errCompression      = errors.New("debruijn: invalid compression")
+	errCompressionLevel = errors.New("debruijn: a compression level requires a compression")
+)
@@ -0,0 +1,620 @@
+// Package debruijn generates a minimal-size string containing every IPv4
+// address.
+//
+// The particular sequence generated is a de Bruijn sequence B(256, 4)
+// beginning with four zeros, followed by its first three terms again so that
+// every 4-term window of the cycle appears in the linear string. In total,
+// there are exactly 4 GiB plus three terms.
+//
+// More generally, the package can generate B(256, n) for any order n ≥ 1,
+// which contains every string of n bytes. It has 256^n + n - 1 terms. It can
+// also generate B(k, n) over the smaller alphabet {0, 1, ..., k-1}, which has
+// k^n + n - 1 terms.
+package debruijn
+
+import (
+	"errors"
+	"iter"
+)
+
+// ErrOrder is the error returned when requesting a de Bruijn sequence of an
+// order the generator does not support.
+var ErrOrder = errors.New("debruijn: unsupported order")
+
+// ErrAlphabet is the error returned when requesting a de Bruijn sequence over
+// an alphabet the generator does not support.
+var ErrAlphabet = errors.New("debruijn: unsupported alphabet size")
+
+// New returns a Generator positioned at the start of B(256, order). The order
+// must be at least 1; New returns ErrOrder otherwise.
+func New(order int) (*Generator, error) {
+	return DeBruijn(256, order)
+}
+
+// DeBruijn returns a Generator positioned at the start of B(k, n), the
+// lexicographically least de Bruijn sequence of order n over the alphabet
+// {0, 1, ..., k-1}. k must be between 1 and 256, and n must be at least 1;
+// DeBruijn returns ErrAlphabet or ErrOrder otherwise.
+func DeBruijn(k, n int) (*Generator, error) {
+	if k < 1 || k > 256 {
+		return nil, ErrAlphabet
+	}
+	if n < 1 {
+		return nil, ErrOrder
+	}
+	g := &Generator{order: n}
+	if k != 256 {
+		g.k = k
+	}
+	g.init()
+	return g, nil
+}
+
+// Terms returns an iterator over the terms of B(256, 4). Each range over the
+// iterator starts again from the beginning of the sequence.
+func Terms() iter.Seq[byte] {
+	return func(yield func(byte) bool) {
+		var g Generator
+		g.Terms()(yield)
+	}
+}
+
+// All returns an iterator over the terms of B(256, order), generated directly
+// in the range loop. Each range over the iterator starts again from the
+// beginning of the sequence, and breaking out of it leaves nothing running.
+// If order is less than 1, the iterator yields nothing.
+func All(order int) iter.Seq[byte] {
+	return func(yield func(byte) bool) {
+		g, err := New(order)
+		if err != nil {
+			return
+		}
+		g.Terms()(yield)
+	}
+}
+
+// Words returns an iterator over the Lyndon words of length 1, 2, and 4 over
+// the bytes, in lexicographic order. Their concatenation is B(256, 4) less the
+// three wrap-around terms. The yielded slice is reused for each word, so it
+// must be copied to be retained.
+func Words() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		var g Generator
+		g.Words()(yield)
+	}
+}
+
+// LyndonWords returns an iterator over every Lyndon word of length at most
+// maxLen over the symbols 0 through alphabet-1, in lexicographic order, by
+// Duval's algorithm. The words whose lengths divide n are those which make up
+// B(alphabet, n), as Words yields them for B(256, 4). The yielded slice is
+// reused for each word, so it must be copied to be retained. If alphabet is
+// not between 1 and 256 or maxLen is less than 1, the iterator yields nothing.
+func LyndonWords(alphabet, maxLen int) iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		if alphabet < 1 || alphabet > 256 || maxLen < 1 {
+			return
+		}
+		top := byte(alphabet - 1)
+		w := make([]byte, 1, maxLen)
+		for {
+			if !yield(w) {
+				return
+			}
+			// The next word is the current one repeated to maxLen, with
+			// the trailing maximal symbols removed and the last symbol
+			// left incremented.
+			l := len(w)
+			for len(w) < maxLen {
+				w = append(w, w[len(w)-l])
+			}
+			for len(w) > 0 && w[len(w)-1] == top {
+				w = w[:len(w)-1]
+			}
+			if len(w) == 0 {
+				return
+			}
+			w[len(w)-1]++
+		}
+	}
+}
+
+// Generator produces the successive terms of B(k, n). The zero value is a
+// Generator positioned at the start of B(256, 4).
+//
+// To find the terms of the de Bruijn sequence, we concatenate the symbols of
+// each lexicographically succeeding Lyndon word whose length divides n. A
+// string is a Lyndon word if it is lexicographically the unique minimum of its
+// rotations. Duval provides an algorithm to produce the lexicographically
+// succeeding Lyndon word of length at most n given a current Lyndon word u
+// other than the maximum one:
+//
+//  1. Repeat the symbols of u until it has length n.
+//  2. Remove trailing maximal symbols.
+//  3. Increment the last symbol.
+//
+// We skip words whose lengths don't divide n by applying the algorithm again.
+//
+// For n = 4, which covers IPv4 addresses, we can do better. Each single symbol
+// is trivially a Lyndon word. A pair of symbols is a Lyndon word iff its first
+// symbol is less than its second. So, the interesting case is a word of
+// length 4, u = αβγδ:
+//
+//  1. If α > β or α > γ or α > δ, then u is not a Lyndon word.
+//  2. If α = δ, then u is not a Lyndon word.
+//  3. If α = γ, then u is a Lyndon word iff β < δ.
+//  4. Otherwise, u is a Lyndon word.
+//
+// It is straightforward to unroll Duval's algorithm with these rules to skip
+// words of length 3 without generating them.
+type Generator struct {
+	// order is n, or 0 for a zero Generator not yet initialized.
+	order int
+	// k is the size of the alphabet, or 0 for 256.
+	k int
+	// u is the current Lyndon word, repeated to length n as in the first step
+	// of Duval's algorithm. The word is u[:n], of which u[i:n] is yet to be
+	// emitted.
+	u    []byte
+	i, n int
+	// stage records how far through the sequence the generator is.
+	stage stage
+	// terms is the number of terms emitted so far.
+	terms uint64
+	// words[l] counts the l-element Lyndon words begun.
+	words []int64
+	// rot is the offset in the sequence of the first term of a rotated
+	// generator, or 0 if it isn't rotated. Once a rotated generator reaches
+	// the end of the sequence, it laps back to the start, after which end is
+	// the offset at which it stops.
+	rot, end uint64
+	lapped   bool
+	// rev holds the state of a reversed generator, or nil if it isn't
+	// reversed.
+	rev *reverse
+	// mask is XORed with each term as it is emitted.
+	mask byte
+	// perm, if not nil, substitutes each term after the mask.
+	perm *[256]byte
+}
+
+// stage is a phase of generation.
+type stage uint8
+
+const (
+	// stageStart is the stage before the first word is emitted.
+	stageStart stage = iota
+	// stageWords is the stage during which Lyndon words are emitted.
+	stageWords
+	// stageWrap is the stage after the wrap-around terms are queued.
+	stageWrap
+)
+
+// init allocates g's buffers if it hasn't been already.
+func (g *Generator) init() {
+	if g.u != nil {
+		return
+	}
+	if g.order == 0 {
+		g.order = 4
+	}
+	g.u = make([]byte, g.order)
+	g.words = make([]int64, g.order+1)
+}
+
+// Order returns the order of the sequence g generates.
+func (g *Generator) Order() int {
+	if g.order == 0 {
+		return 4
+	}
+	return g.order
+}
+
+// Alphabet returns the number of distinct terms in the sequence g generates.
+func (g *Generator) Alphabet() int {
+	if g.k == 0 {
+		return 256
+	}
+	return g.k
+}
+
+// standard reports whether g generates B(256, 4), for which there are
+// specialized algorithms.
+func (g *Generator) standard() bool {
+	return g.Order() == 4 && g.k == 0
+}
+
+// Next returns the next term of the sequence. If the sequence is exhausted,
+// ok is false.
+func (g *Generator) Next() (term byte, ok bool) {
+	term, ok = g.next()
+	if !ok {
+		return 0, false
+	}
+	return g.sub(term), true
+}
+
+// next is Next without the mask or permutation.
+func (g *Generator) next() (term byte, ok bool) {
+	if g.rev != nil {
+		return g.revNext()
+	}
+	if g.end > 0 && g.terms >= g.end {
+		return 0, false
+	}
+	if g.i == g.n && !g.advance() && !g.lap() {
+		return 0, false
+	}
+	term = g.u[g.i]
+	g.i++
+	g.terms++
+	return term, true
+}
+
+// Terms returns an iterator over the remaining terms of g. Breaking out of a
+// range over the iterator leaves g positioned just after the last term it
+// yielded.
+func (g *Generator) Terms() iter.Seq[byte] {
+	return func(yield func(byte) bool) {
+		if g.rev != nil {
+			for {
+				term, ok := g.revNext()
+				if !ok || !yield(g.sub(term)) {
+					return
+				}
+			}
+		}
+		for g.i < g.n || g.advance() || g.lap() {
+			for g.i < g.n {
+				if g.end > 0 && g.terms >= g.end {
+					return
+				}
+				term := g.u[g.i]
+				g.i++
+				g.terms++
+				if !yield(g.sub(term)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Words returns an iterator over the remaining Lyndon words of g, those whose
+// lengths divide the order, in lexicographic order. If g is partway through a
+// word, the rest of it is skipped. The iterator ends before the wrap-around
+// terms, which remain to be generated. The yielded slice is reused for each
+// word, so it must be copied to be retained. If g is reversed, the iterator
+// yields nothing.
+func (g *Generator) Words() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		if g.rev != nil {
+			return
+		}
+		g.terms += uint64(g.n - g.i)
+		g.i = g.n
+		var w []byte
+		for g.stage != stageWrap && g.advance() && g.stage != stageWrap {
+			w = append(w[:0], g.u[:g.n]...)
+			g.terms += uint64(g.n)
+			g.i = g.n
+			if !yield(w) {
+				return
+			}
+		}
+	}
+}
+
+// Skip advances g past its next n terms without emitting them, or to the end
+// of the sequence if fewer remain. For B(256, 4), Skip takes constant time.
+// Otherwise, it generates and discards the terms.
+func (g *Generator) Skip(n uint64) {
+	if g.rev != nil {
+		g.revSkip(n)
+		return
+	}
+	if g.standard() {
+		for n > 0 {
+			limit := uint64(seqLen)
+			if g.end > 0 {
+				limit = g.end
+			}
+			if g.terms >= limit {
+				if g.stage == stageWrap && g.lap() {
+					continue
+				}
+				return
+			}
+			k := min(n, limit-g.terms)
+			g.seek(g.terms + k)
+			n -= k
+		}
+		return
+	}
+	var buf [4096]byte
+	for n > 0 {
+		k := g.fill(buf[:min(n, uint64(len(buf)))])
+		if k == 0 {
+			return
+		}
+		n -= uint64(k)
+	}
+}
+
+// WordCount returns the number of Lyndon words of the given length that g has
+// begun to emit. It is zero for lengths which do not divide the order. For a
+// rotated generator, the counts are the same as if it had begun at the start
+// of the sequence, and they begin again when it laps around to the start.
+func (g *Generator) WordCount(length int) int64 {
+	if length < 0 || length >= len(g.words) {
+		return 0
+	}
+	return g.words[length]
+}
+
+// Offset returns the offset in g's output of the next term it will emit,
+// which is the number of terms it has emitted or skipped.
+func (g *Generator) Offset() uint64 {
+	if !g.lapped {
+		return g.terms - g.rot
+	}
+	// A lapped generator has emitted the cycle from rot to its end, then the
+	// wrap-around terms, which are the first terms of the cycle, and its
+	// count began again from the start of the cycle.
+	cycle := uint64(1)
+	for range g.Order() {
+		cycle *= uint64(g.Alphabet())
+	}
+	return cycle - g.rot + g.terms
+}
+
+// fill copies as many terms as fit into p and returns the number copied. It
+// returns less than len(p) only once the sequence is exhausted.
+func (g *Generator) fill(p []byte) int {
+	var k int
+	if g.rev != nil {
+		k = g.revFillAll(p)
+	} else {
+		k = g.fillRot(p)
+	}
+	if g.mask != 0 || g.perm != nil {
+		for i, t := range p[:k] {
+			p[i] = g.sub(t)
+		}
+	}
+	return k
+}
+
+// fillRot fills p like fill, without the mask or permutation.
+func (g *Generator) fillRot(p []byte) int {
+	k := 0
+	for {
+		k += g.fillLap(p[k:])
+		if k == len(p) || !g.lap() {
+			return k
+		}
+	}
+}
+
+// fillLap fills p like fill, but stops if a rotated generator needs to lap.
+func (g *Generator) fillLap(p []byte) int {
+	if g.end > 0 {
+		p = p[:min(uint64(len(p)), g.end-min(g.terms, g.end))]
+	}
+	k := 0
+	for k < len(p) {
+		if g.i == g.n {
+			if g.standard() && g.stage == stageWords {
+				// In all but one of every 256 words of B(256, 4), the next
+				// word just increments the last symbol of the current one.
+				// Handle those in a tight loop.
+				u := (*[4]byte)(g.u)
+				c := 0
+				for u[3] != 0xff && len(p)-k >= 4 {
+					u[3]++
+					*(*[4]byte)(p[k:]) = *u
+					k += 4
+					c++
+				}
+				if c > 0 {
+					g.i, g.n = 4, 4
+					g.words[4] += int64(c)
+					continue
+				}
+			}
+			if !g.advance() {
+				break
+			}
+			// Nearly every word of B(256, 4) has four elements, so store
+			// those directly when they fit.
+			if g.n == 4 && len(p)-k >= 4 {
+				*(*[4]byte)(p[k:]) = [4]byte(g.u)
+				g.i = 4
+				k += 4
+				continue
+			}
+		}
+		c := copy(p[k:], g.u[g.i:g.n])
+		g.i += c
+		k += c
+	}
+	g.terms += uint64(k)
+	return k
+}
+
+// advance queues the terms of the next Lyndon word, or the wrap-around terms
+// once the words are exhausted. It returns false if there are no more terms.
+func (g *Generator) advance() bool {
+	switch g.stage {
+	case stageStart:
+		g.init()
+		g.stage = stageWords
+		g.i, g.n = 0, 1
+		g.words[1]++
+		return true
+	case stageWrap:
+		return false
+	}
+	var ok bool
+	if g.standard() {
+		ok = g.advance4()
+	} else {
+		ok = g.advanceN()
+	}
+	if !ok {
+		// When the words are exhausted, we repeat the first n-1 terms of the
+		// de Bruijn sequence, which are all zeros, to finish the cycle.
+		g.stage = stageWrap
+		clear(g.u)
+		g.i, g.n = 0, len(g.u)-1
+		return g.n > 0
+	}
+	return true
+}
+
+// Mask makes g XOR each term it emits with m. Since XOR with a constant is a
+// bijection on bytes, the result is still a de Bruijn sequence containing every
+// window, in a different order. The mask applies to the terms g emits after
+// the call, but not to the words Words yields. A mask of 0 has no effect.
+func (g *Generator) Mask(m byte) {
+	g.mask = m
+}
+
+// Rotate makes g generate the rotation of the sequence which begins with the
+// term at offset off of the cycle, for off less than the length of the cycle.
+// It is a de Bruijn sequence of the same length, containing every window that
+// the sequence does. In place of the wrap-around terms, the rotation ends with
+// the terms of the cycle just before off followed by its first n-1 terms
+// again. Rotate must be called before g has generated any terms; it returns
+// an error otherwise or if off is too large.
+func (g *Generator) Rotate(off uint64) error {
+	if g.stage != stageStart || g.terms != 0 || g.rev != nil {
+		return errRotateStarted
+	}
+	cycle, k := uint64(1), uint64(g.Alphabet())
+	for range g.Order() {
+		if cycle > off/k {
+			// The cycle is longer than off.
+			cycle = 0
+			break
+		}
+		cycle *= k
+	}
+	if cycle != 0 && off >= cycle {
+		return errRotateOffset
+	}
+	g.init()
+	g.Skip(off)
+	g.rot = off
+	return nil
+}
+
+var (
+	errRotateStarted = errors.New("debruijn: rotating a generator which has begun or is reversed")
+	errRotateOffset  = errors.New("debruijn: rotation beyond the end of the cycle")
+)
+
+// lap moves a rotated generator which has exhausted the sequence back to the
+// start of the sequence, and sets it to stop at the end of the rotation. It
+// reports whether there are more terms.
+func (g *Generator) lap() bool {
+	if g.rot == 0 || g.lapped {
+		return false
+	}
+	g.lapped = true
+	// The wrap-around terms which the generator has just emitted are the
+	// first n-1 terms, so continue just after them.
+	skip := uint64(g.Order() - 1)
+	g.end = g.rot + skip
+	g.stage = stageStart
+	g.i, g.n = 0, 0
+	g.terms = 0
+	clear(g.u)
+	clear(g.words)
+	g.Skip(skip)
+	return g.i < g.n || g.advance()
+}
+
+// first reports whether g has not yet emitted the first term of its output.
+func (g *Generator) first() bool {
+	return g.terms == g.rot && !g.lapped
+}
+
+// advanceN moves to the next Lyndon word whose length divides the order using
+// Duval's algorithm. It returns false if the current word is the last.
+func (g *Generator) advanceN() bool {
+	u := g.u
+	top := byte(g.Alphabet() - 1)
+	for {
+		j := len(u) - 1
+		for j >= 0 && u[j] == top {
+			j--
+		}
+		if j < 0 {
+			return false
+		}
+		u[j]++
+		l := j + 1
+		for k := l; k < len(u); k++ {
+			u[k] = u[k-l]
+		}
+		if len(u)%l == 0 {
+			g.i, g.n = 0, l
+			g.words[l]++
+			return true
+		}
+	}
+}
+
+// advance4 moves to the next Lyndon word of length 1, 2, or 4 using the
+// unrolled algorithm for B(256, 4). It returns false if the current word is the
+// last.
+//
+// Termination rests on one invariant: every branch increments only a symbol
+// which is below 0xff, so no symbol ever wraps and u strictly increases from
+// one word to the next. The last word is the single symbol 0xff, which leaves
+// u as ff ff ff ff, and the check on u[0] at the top is then the only way
+// out. An increment which wrapped would send generation back to the first
+// words and run forever, so the 1-element branch, the only one to increment
+// u[0], checks it again rather than rely on the order of the branches.
+func (g *Generator) advance4() bool {
+	u := (*[4]byte)(g.u)
+	if u[0] == 0xff {
+		return false
+	}
+	if u[3] == 0xff {
+		// If the last symbol is currently the maximal one, then Duval's
+		// generation algorithm would lead us to send a 3-element word. We
+		// don't want that, so just check whether we'd send a 2- or 1-element
+		// word, and otherwise skip to the next 4-element one.
+		if u[2] == 0xff {
+			if u[1] == 0xff {
+				// 1-element Lyndon word. The check at the top means u[0] is
+				// below 0xff here, but an increment past it would wrap and
+				// start the words over, so refuse it here as well.
+				if u[0] == 0xff {
+					return false
+				}
+				u[0]++
+				u[1], u[2], u[3] = u[0], u[0], u[0]
+				g.i, g.n = 0, 1
+				g.words[1]++
+				return true
+			}
+			// 2-element Lyndon word.
+			u[1]++
+			u[2], u[3] = u[0], u[1]
+			g.i, g.n = 0, 2
+			g.words[2]++
+			return true
+		}
+		// Would-be 3-element.
+		u[2]++
+		u[3] = u[0]
+	}
+	// 4-element Lyndon word.
+	u[3]++
+	g.i, g.n = 0, 4
+	g.words[4]++
+	return true
+}
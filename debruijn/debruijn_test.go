@@ -0,0 +1,988 @@
+package debruijn
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/netip"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		k, n int
+		err  error
+	}{
+		{256, 4, nil},
+		{256, 1, nil},
+		{2, 3, nil},
+		{1, 1, nil},
+		{256, 0, ErrOrder},
+		{256, -1, ErrOrder},
+		{0, 4, ErrAlphabet},
+		{257, 4, ErrAlphabet},
+	}
+	for _, c := range cases {
+		g, err := DeBruijn(c.k, c.n)
+		if !errors.Is(err, c.err) {
+			t.Errorf("DeBruijn(%d, %d): got error %v, want %v", c.k, c.n, err, c.err)
+			continue
+		}
+		if err == nil && (g.Alphabet() != c.k || g.Order() != c.n) {
+			t.Errorf("DeBruijn(%d, %d) gives B(%d, %d)", c.k, c.n, g.Alphabet(), g.Order())
+		}
+	}
+	var g Generator
+	if g.Alphabet() != 256 || g.Order() != 4 {
+		t.Errorf("zero Generator gives B(%d, %d), want B(256, 4)", g.Alphabet(), g.Order())
+	}
+}
+
+func TestFirstMegabyte(t *testing.T) {
+	var b bytes.Buffer
+	n, err := Write(&b, Options{Limit: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1<<20 || b.Len() != 1<<20 {
+		t.Fatalf("wrote %d bytes, counted %d, want %d", b.Len(), n, 1<<20)
+	}
+	// The words begin 0, 0001, 0002, ....
+	head := []byte{0, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3}
+	if !bytes.HasPrefix(b.Bytes(), head) {
+		t.Errorf("sequence begins %x, want %x", b.Bytes()[:len(head)], head)
+	}
+	var g Generator
+	for i, want := range b.Bytes() {
+		if got, _ := g.Next(); got != want {
+			t.Fatalf("term %d is %d, but Write wrote %d", i, got, want)
+		}
+	}
+}
+
+func TestAdvance4End(t *testing.T) {
+	// The last 4-element word is fe ff ff ff, after which come the 1-element
+	// word ff and then the end of the words.
+	var g Generator
+	g.init()
+	g.stage = stageWords
+	copy(g.u, []byte{0xfe, 0xff, 0xff, 0xff})
+	g.i, g.n = 4, 4
+	if !g.advance4() {
+		t.Fatal("no word after fe ff ff ff")
+	}
+	if g.n != 1 || g.u[0] != 0xff {
+		t.Fatalf("word after fe ff ff ff is %x, want ff", g.u[:g.n])
+	}
+	// Stepping past the end again and again stays there.
+	for i := range 1000 {
+		if g.advance4() {
+			t.Fatalf("advance %d past ff gave %x", i+1, g.u[:g.n])
+		}
+		if g.u[0] != 0xff {
+			t.Fatalf("advance %d past ff wrapped u to %x", i+1, g.u)
+		}
+	}
+}
+
+func TestTermination(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generates all of B(256, 4)")
+	}
+	var g Generator
+	n, err := g.WriteTo(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1<<32+3 {
+		t.Errorf("wrote %d bytes, want %d", n, int64(1<<32+3))
+	}
+	words := map[int]int64{1: 256, 2: 256 * 255 / 2, 3: 0, 4: (1<<32 - 1<<16) / 4}
+	for l, want := range words {
+		if got := g.WordCount(l); got != want {
+			t.Errorf("%d-element words: got %d, want %d", l, got, want)
+		}
+	}
+	for range 1000 {
+		if term, ok := g.Next(); ok {
+			t.Fatalf("exhausted generator gave another term %d", term)
+		}
+	}
+}
+
+// lyndon4 reports whether the string of 4 terms x, most significant first, is
+// a Lyndon word: strictly less than each of its rotations.
+func lyndon4(x uint32) bool {
+	return x < x<<8|x>>24 && x < x<<16|x>>16 && x < x<<24|x>>8
+}
+
+func TestLyndonWords(t *testing.T) {
+	if testing.Short() {
+		t.Skip("checks all 256^4 strings of 4 terms")
+	}
+	var g Generator
+	var prev []byte
+	var counts [5]int
+	// next is the next string of 4 terms to check, counting one past
+	// ff ff ff ff once all have been.
+	var next uint64
+	for w := range g.Words() {
+		if bytes.Compare(prev, w) >= 0 {
+			t.Fatalf("word %x after %x is out of order", w, prev)
+		}
+		prev = append(prev[:0], w...)
+		counts[len(w)]++
+		switch len(w) {
+		case 1:
+			if int(w[0]) != counts[1]-1 {
+				t.Fatalf("1-element word %x, want %x", w, counts[1]-1)
+			}
+		case 2:
+			if w[0] >= w[1] {
+				t.Fatalf("2-element word %x is not a Lyndon word", w)
+			}
+		case 4:
+			for next < 1<<32 && !lyndon4(uint32(next)) {
+				next++
+			}
+			if x := binary.BigEndian.Uint32(w); uint64(x) != next {
+				t.Fatalf("4-element word %08x, want %08x", x, next)
+			}
+			next++
+		default:
+			t.Fatalf("word %x of length %d", w, len(w))
+		}
+	}
+	for ; next < 1<<32; next++ {
+		if lyndon4(uint32(next)) {
+			t.Fatalf("missing 4-element word %08x", next)
+		}
+	}
+	// Strictly increasing Lyndon words of two terms, as many as there are,
+	// are all of them.
+	if counts[1] != 256 || counts[2] != 256*255/2 || counts[3] != 0 {
+		t.Errorf("%d 1-element and %d 2-element words, want 256 and %d", counts[1], counts[2], 256*255/2)
+	}
+}
+
+// lyndonCount returns the number of Lyndon words of length n over k symbols,
+// by Moreau's necklace-counting formula: (1/n) Σ μ(d) k^(n/d) over the
+// divisors d of n.
+func lyndonCount(k, n int) int64 {
+	var sum int64
+	for d := 1; d <= n; d++ {
+		if n%d != 0 {
+			continue
+		}
+		// μ(d) is 0 if a square divides d, else -1 to the number of
+		// prime factors.
+		mu, m := int64(1), d
+		for p := 2; p <= m; p++ {
+			if m%p != 0 {
+				continue
+			}
+			m /= p
+			if m%p == 0 {
+				mu = 0
+				break
+			}
+			mu = -mu
+		}
+		pow := int64(1)
+		for range n / d {
+			pow *= int64(k)
+		}
+		sum += mu * pow
+	}
+	return sum / int64(n)
+}
+
+func TestWordCounts(t *testing.T) {
+	cases := []struct{ k, n int }{{2, 8}, {3, 6}, {10, 4}, {16, 3}, {256, 2}}
+	for _, c := range cases {
+		g, _ := DeBruijn(c.k, c.n)
+		var seq bytes.Buffer
+		counts := make([]int64, c.n+1)
+		for w := range g.Words() {
+			counts[len(w)]++
+			seq.Write(w)
+		}
+		for l := 1; l <= c.n; l++ {
+			want := int64(0)
+			if c.n%l == 0 {
+				want = lyndonCount(c.k, l)
+			}
+			if counts[l] != want || g.WordCount(l) != want {
+				t.Errorf("B(%d, %d): %d words of length %d, counted %d, want %d", c.k, c.n, counts[l], l, g.WordCount(l), want)
+			}
+		}
+		// The words are the sequence without its wrap-around terms.
+		var want bytes.Buffer
+		g, _ = DeBruijn(c.k, c.n)
+		g.WriteTo(&want)
+		if !bytes.Equal(seq.Bytes(), want.Bytes()[:want.Len()-c.n+1]) {
+			t.Errorf("B(%d, %d): words differ from the sequence", c.k, c.n)
+		}
+	}
+	if got, want := lyndonCount(256, 4), int64(1<<32-1<<16)/4; got != want {
+		t.Errorf("%d Lyndon words of length 4 over the bytes, want %d", got, want)
+	}
+}
+
+// isLyndon reports whether w is strictly less than each of its other
+// rotations.
+func isLyndon(w []byte) bool {
+	for i := 1; i < len(w); i++ {
+		r := append(slices.Clone(w[i:]), w[:i]...)
+		if bytes.Compare(w, r) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLyndonWordCounts(t *testing.T) {
+	// The binary Lyndon words of each length, OEIS A001037.
+	a001037 := []int64{2, 1, 2, 3, 6, 9, 18, 30, 56, 99, 186, 335}
+	counts := make([]int64, len(a001037)+1)
+	for w := range LyndonWords(2, len(a001037)) {
+		counts[len(w)]++
+	}
+	if !slices.Equal(counts[1:], a001037) {
+		t.Errorf("binary Lyndon words of each length: %v, want %v", counts[1:], a001037)
+	}
+	cases := []struct{ k, n int }{{1, 5}, {3, 7}, {4, 6}, {26, 3}, {256, 2}}
+	for _, c := range cases {
+		counts := make([]int64, c.n+1)
+		var prev []byte
+		for w := range LyndonWords(c.k, c.n) {
+			if prev != nil && bytes.Compare(prev, w) >= 0 {
+				t.Fatalf("LyndonWords(%d, %d): %v after %v", c.k, c.n, w, prev)
+			}
+			prev = append(prev[:0], w...)
+			counts[len(w)]++
+		}
+		for l := 1; l <= c.n; l++ {
+			if want := lyndonCount(c.k, l); counts[l] != want {
+				t.Errorf("LyndonWords(%d, %d): %d words of length %d, want %d", c.k, c.n, counts[l], l, want)
+			}
+		}
+	}
+}
+
+func TestLyndonWordsBrute(t *testing.T) {
+	// Every string of up to 6 ternary symbols which is a Lyndon word is
+	// yielded, in lexicographic order, and nothing else is.
+	const k, n = 3, 6
+	var want [][]byte
+	var gen func(w []byte)
+	gen = func(w []byte) {
+		if len(w) > 0 && isLyndon(w) {
+			want = append(want, slices.Clone(w))
+		}
+		if len(w) == n {
+			return
+		}
+		for s := range byte(k) {
+			gen(append(w, s))
+		}
+	}
+	gen(nil)
+	var got [][]byte
+	for w := range LyndonWords(k, n) {
+		got = append(got, slices.Clone(w))
+	}
+	if !slices.EqualFunc(got, want, bytes.Equal) {
+		t.Errorf("LyndonWords(%d, %d) gave %d words, want the %d found by brute force", k, n, len(got), len(want))
+	}
+	for _, c := range []struct{ k, n int }{{0, 3}, {257, 3}, {2, 0}} {
+		for w := range LyndonWords(c.k, c.n) {
+			t.Fatalf("LyndonWords(%d, %d) yielded %v", c.k, c.n, w)
+		}
+	}
+	// Each range starts over from the first word.
+	for w := range LyndonWords(2, 4) {
+		if !bytes.Equal(w, []byte{0}) {
+			t.Errorf("first word is %v", w)
+		}
+		break
+	}
+}
+
+func TestWordsPrefix(t *testing.T) {
+	want := prefix(t, 1<<20)
+	var got []byte
+	for w := range Words() {
+		got = append(got, w...)
+		if len(got) >= len(want) {
+			break
+		}
+	}
+	if !bytes.Equal(got[:len(want)], want) {
+		t.Error("words differ from the sequence")
+	}
+}
+
+// leastDeBruijn returns the lexicographically least string of k^n + n - 1
+// symbols less than k which begins with n zeros and contains every string of
+// n symbols, by a depth-first search trying smaller symbols first.
+func leastDeBruijn(k, n int) []byte {
+	total := 1
+	for range n {
+		total *= k
+	}
+	seen := make([]bool, total)
+	s := make([]byte, n, total+n-1)
+	seen[0] = true
+	var search func(v int) bool
+	search = func(v int) bool {
+		if len(s) == cap(s) {
+			return true
+		}
+		for b := range k {
+			w := (v*k + b) % total
+			if seen[w] {
+				continue
+			}
+			seen[w] = true
+			s = append(s, byte(b))
+			if search(w) {
+				return true
+			}
+			s = s[:len(s)-1]
+			seen[w] = false
+		}
+		return false
+	}
+	search(0)
+	return s
+}
+
+func TestDeBruijnLeast(t *testing.T) {
+	cases := []struct{ k, n int }{{2, 4}, {3, 3}, {10, 2}, {2, 6}, {3, 4}, {4, 3}, {1, 3}, {5, 1}}
+	for _, c := range cases {
+		g, err := DeBruijn(c.k, c.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got bytes.Buffer
+		if _, err := g.WriteTo(&got); err != nil {
+			t.Fatal(err)
+		}
+		if want := leastDeBruijn(c.k, c.n); !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("B(%d, %d) is %x, want %x", c.k, c.n, got.Bytes(), want)
+		}
+	}
+}
+
+func TestDeBruijnVerify(t *testing.T) {
+	cases := []struct{ k, n int }{{2, 16}, {3, 9}, {7, 5}, {6, 6}, {255, 2}, {16, 5}}
+	for _, c := range cases {
+		if err := verify(c.k, c.n); err != nil {
+			t.Errorf("B(%d, %d): %v", c.k, c.n, err)
+		}
+	}
+}
+
+// checkCovers fails the test unless s has the k^n + n - 1 terms of B(k, n)
+// and contains every string of n terms exactly once, as a rotation or
+// relabeling of B(k, n) does.
+func checkCovers(t *testing.T, name string, s []byte, k, n int) {
+	t.Helper()
+	c := newChecker(k, n)
+	c.write(s)
+	if c.off != c.total+uint64(n)-1 {
+		t.Errorf("%s: %d terms, want %d", name, c.off, c.total+uint64(n)-1)
+	}
+	if c.dups != 0 {
+		t.Errorf("%s: %x repeats at offset %d", name, tuple(c.dup, k, n), c.firstDup)
+	}
+	if missing, first := c.missing(); missing != 0 {
+		t.Errorf("%s: %d strings missing, the first %x", name, missing, tuple(first, k, n))
+	}
+}
+
+func TestRotate(t *testing.T) {
+	cases := []struct {
+		k, n int
+		offs []uint64
+	}{
+		{4, 3, nil},
+		{3, 4, nil},
+		{2, 5, nil},
+		{256, 2, []uint64{1, 255, 256, 4097, 65535}},
+	}
+	for _, c := range cases {
+		var b bytes.Buffer
+		g, _ := DeBruijn(c.k, c.n)
+		g.WriteTo(&b)
+		cycle := b.Bytes()[:b.Len()-c.n+1]
+		offs := c.offs
+		if offs == nil {
+			for off := range uint64(len(cycle)) {
+				offs = append(offs, off)
+			}
+		}
+		for _, off := range offs {
+			name := fmt.Sprintf("B(%d, %d) rotated by %d", c.k, c.n, off)
+			g, _ := DeBruijn(c.k, c.n)
+			if err := g.Rotate(off); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			var got bytes.Buffer
+			g.WriteTo(&got)
+			// The seam repeats the first terms of the rotation.
+			want := append(slices.Clone(cycle[off:]), cycle[:off]...)
+			want = append(want, want[:c.n-1]...)
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s: got %x, want %x", name, got.Bytes(), want)
+			}
+			checkCovers(t, name, got.Bytes(), c.k, c.n)
+		}
+	}
+}
+
+func TestRotateAddr(t *testing.T) {
+	addr := netip.MustParseAddr("203.0.113.7")
+	off := uint64(Rank(addr))
+	var g Generator
+	if err := g.Rotate(off); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 1<<16)
+	io.ReadFull(&g, got)
+	want := make([]byte, len(got))
+	ReaderAt{}.ReadAt(want, int64(off))
+	if !bytes.Equal(got, want) {
+		t.Errorf("rotation to %v begins %x, want %x", addr, got[:8], want[:8])
+	}
+	if size, err := g.Size(Binary); err != nil || size != seqLen {
+		t.Errorf("rotation has size %d with error %v, want %d", size, err, int64(seqLen))
+	}
+	if err := g.Rotate(0); err == nil {
+		t.Error("rotated a generator which had begun")
+	}
+	g2, _ := DeBruijn(4, 3)
+	if err := g2.Rotate(64); err == nil {
+		t.Error("rotated B(4, 3) by its whole length")
+	}
+}
+
+func TestMask(t *testing.T) {
+	for _, m := range []byte{0x01, 0x5a, 0xff} {
+		var plain, masked bytes.Buffer
+		g, _ := New(2)
+		g.WriteTo(&plain)
+		g, _ = New(2)
+		g.Mask(m)
+		g.WriteTo(&masked)
+		name := fmt.Sprintf("mask %#02x", m)
+		checkCovers(t, name, masked.Bytes(), 256, 2)
+		for i, b := range plain.Bytes() {
+			if masked.Bytes()[i] != b^m {
+				t.Fatalf("%s: term %d is %d, want %d", name, i, masked.Bytes()[i], b^m)
+			}
+		}
+	}
+	// The text formats show the masked terms, and the words are unmasked.
+	var b bytes.Buffer
+	if _, err := Write(&b, Options{Format: Dot, Mask: 0xff, Limit: 6}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "255.255.255.255.254.255"; got != want {
+		t.Errorf("masked text is %q, want %q", got, want)
+	}
+	var g Generator
+	g.Mask(0xff)
+	for w := range g.Words() {
+		if !bytes.Equal(w, []byte{0}) {
+			t.Errorf("first word is %x, want 00", w)
+		}
+		break
+	}
+}
+
+// windowWriter records in seen each window of 4 bytes written through it and
+// the offset of the first which was already seen, or -1.
+type windowWriter struct {
+	seen []uint64
+	x    uint32
+	n    int64
+	dup  int64
+}
+
+func (w *windowWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		w.x = w.x<<8 | uint32(b)
+		w.n++
+		if w.n < 4 {
+			continue
+		}
+		word, bit := w.x>>6, uint64(1)<<(w.x&63)
+		if w.seen[word]&bit != 0 && w.dup < 0 {
+			w.dup = w.n - 4
+		}
+		w.seen[word] |= bit
+	}
+	return len(p), nil
+}
+
+func TestWindows(t *testing.T) {
+	if testing.Short() {
+		t.Skip("checks all 256^4 windows, with 512 MiB to note them")
+	}
+	w := &windowWriter{seen: make([]uint64, 1<<26), dup: -1}
+	var g Generator
+	if _, err := g.WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.dup >= 0 {
+		t.Errorf("window at offset %d appears earlier", w.dup)
+	}
+	if w.n != 1<<32+3 {
+		t.Errorf("%d bytes, want %d", w.n, int64(1<<32+3))
+	}
+	for i, m := range w.seen {
+		if m != ^uint64(0) {
+			t.Fatalf("window %08x is missing", i<<6+bits.TrailingZeros64(^m))
+		}
+	}
+}
+
+// checkGoroutines fails the test if more goroutines are running at the end of
+// it than at the start, once those still exiting have had a moment to.
+func checkGoroutines(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		n := runtime.NumGoroutine()
+		for i := 0; i < 100 && n > before; i++ {
+			time.Sleep(10 * time.Millisecond)
+			n = runtime.NumGoroutine()
+		}
+		if n > before {
+			t.Errorf("%d goroutines running, up from %d", n, before)
+		}
+	})
+}
+
+func TestIteratorBreak(t *testing.T) {
+	checkGoroutines(t)
+	const n = 1000
+	var g Generator
+	var want []byte
+	for range n {
+		b, _ := g.Next()
+		want = append(want, b)
+	}
+	got := make([]byte, 0, n)
+	for b := range Terms() {
+		if len(got) == n {
+			break
+		}
+		got = append(got, b)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Terms began %x, want %x", got[:8], want[:8])
+	}
+	got = got[:0]
+	for b := range All(4) {
+		if len(got) == n {
+			break
+		}
+		got = append(got, b)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("All(4) began %x, want %x", got[:8], want[:8])
+	}
+	got = got[:0]
+	for c := range Chunks(100) {
+		if len(got) == n {
+			break
+		}
+		got = append(got, c...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Chunks began %x, want %x", got[:8], want[:8])
+	}
+	k := 0
+	for range Words() {
+		if k++; k == n {
+			break
+		}
+	}
+	k = 0
+	for range Addrs() {
+		if k++; k == n {
+			break
+		}
+	}
+}
+
+// cancelWriter cancels its context after the first write to it and counts
+// the bytes written.
+type cancelWriter struct {
+	cancel context.CancelFunc
+	n      atomic.Int64
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	w.n.Add(int64(len(p)))
+	return len(p), nil
+}
+
+func (w *cancelWriter) WriteAt(p []byte, off int64) (int, error) {
+	return w.Write(p)
+}
+
+func TestAll(t *testing.T) {
+	checkGoroutines(t)
+	for order := 1; order <= 3; order++ {
+		g, _ := New(order)
+		var want bytes.Buffer
+		if _, err := g.WriteTo(&want); err != nil {
+			t.Fatal(err)
+		}
+		// Each range starts over and yields the whole sequence.
+		for range 2 {
+			got := make([]byte, 0, want.Len())
+			for b := range All(order) {
+				got = append(got, b)
+			}
+			if !bytes.Equal(got, want.Bytes()) {
+				t.Errorf("order %d: All gave %d terms which differ from the %d written", order, len(got), want.Len())
+			}
+		}
+		for b := range All(order) {
+			if b != 0 {
+				t.Errorf("order %d: first term is %d", order, b)
+			}
+			break
+		}
+	}
+	for _, order := range []int{0, -1} {
+		for b := range All(order) {
+			t.Fatalf("order %d yielded %d", order, b)
+		}
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	checkGoroutines(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &cancelWriter{cancel: cancel}
+	var g Generator
+	n, err := g.WriteToContext(ctx, w)
+	if !errors.Is(err, context.Canceled) || n != slabSize || n != w.n.Load() {
+		t.Errorf("WriteToContext wrote %d bytes, counted %d, with error %v; want one slab and cancellation", w.n.Load(), n, err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	w = &cancelWriter{cancel: cancel}
+	n, err = WriteParallelContext(ctx, w, 0, 4)
+	if !errors.Is(err, context.Canceled) || n != w.n.Load() || n > 4*chunkSize {
+		t.Errorf("WriteParallelContext wrote %d bytes, counted %d, with error %v; want at most a chunk per worker and cancellation", w.n.Load(), n, err)
+	}
+}
+
+// bufferAt is an io.WriterAt into a fixed buffer.
+type bufferAt []byte
+
+func (b bufferAt) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(p)) > int64(len(b)) {
+		return 0, fmt.Errorf("write of %d bytes at %d out of range", len(p), off)
+	}
+	return copy(b[off:], p), nil
+}
+
+func TestWriteParallel(t *testing.T) {
+	checkGoroutines(t)
+	// Writing the tail of the sequence, across several chunks and from an
+	// offset which isn't at the start of one, gives the serial output.
+	for _, off := range []int64{seqLen - 5*chunkSize + 12345, seqLen - 2*chunkSize, seqLen - 10} {
+		var g Generator
+		g.Skip(uint64(off))
+		var want bytes.Buffer
+		if _, err := g.WriteTo(&want); err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{0, 1, 3, 8} {
+			got := make(bufferAt, want.Len())
+			n, err := WriteParallel(got, off, workers)
+			if err != nil || n != int64(want.Len()) {
+				t.Fatalf("from %d with %d workers: wrote %d bytes, want %d, with error %v", off, workers, n, want.Len(), err)
+			}
+			if !bytes.Equal(got, want.Bytes()) {
+				t.Errorf("from %d with %d workers: output differs from serial", off, workers)
+			}
+		}
+	}
+	if n, err := WriteParallel(bufferAt(nil), seqLen, 4); n != 0 || err != nil {
+		t.Errorf("from the end: wrote %d bytes with error %v", n, err)
+	}
+	if _, err := WriteParallel(bufferAt(nil), -1, 4); err == nil {
+		t.Error("no error from a negative offset")
+	}
+}
+
+func TestContextWriter(t *testing.T) {
+	checkGoroutines(t)
+	cases := []struct {
+		name  string
+		write func(w io.Writer, g *Generator) (int64, error)
+	}{
+		{"WriteText", func(w io.Writer, g *Generator) (int64, error) { return WriteText(w, g, '.', -1) }},
+		{"WriteHex", func(w io.Writer, g *Generator) (int64, error) { return WriteHex(w, g, '\n', -1) }},
+		{"WriteBinary", func(w io.Writer, g *Generator) (int64, error) { return WriteBinary(w, g, -1) }},
+		{"WriteIPv4", func(w io.Writer, g *Generator) (int64, error) { return WriteIPv4(w, g, -1) }},
+		{"Encode", func(w io.Writer, g *Generator) (int64, error) { return Encode(w, g, PaddedDotEncoder, -1) }},
+	}
+	for _, c := range cases {
+		ctx, cancel := context.WithCancel(context.Background())
+		cw := &cancelWriter{cancel: cancel}
+		var g Generator
+		n, err := c.write(ContextWriter(ctx, cw), &g)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: got error %v, want cancellation", c.name, err)
+		}
+		// Only the write which cancelled gets through, and the count is
+		// of what got through rather than what was buffered.
+		if w := cw.n.Load(); w == 0 || w > slabSize || n != w {
+			t.Errorf("%s: wrote %d bytes, counted %d, want one buffer", c.name, w, n)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n, err := ContextWriter(ctx, io.Discard).Write([]byte{1}); n != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("write after cancellation wrote %d bytes with error %v", n, err)
+	}
+}
+
+// signalWriter closes reached once it has counted at least at bytes, then
+// waits for ctx to be done before each write.
+type signalWriter struct {
+	ctx     context.Context
+	at      int64
+	n       int64
+	reached chan struct{}
+}
+
+func (w *signalWriter) Write(p []byte) (int, error) {
+	if w.n >= w.at {
+		if w.n-int64(len(p)) < w.at {
+			close(w.reached)
+		}
+		<-w.ctx.Done()
+	}
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func TestWriteToContextMidStream(t *testing.T) {
+	checkGoroutines(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &signalWriter{ctx: ctx, at: 10 * slabSize, reached: make(chan struct{})}
+	go func() {
+		<-w.reached
+		cancel()
+	}()
+	var g Generator
+	n, err := g.WriteToContext(ctx, w)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want cancellation", err)
+	}
+	// The slab being written when the context was cancelled finishes, and
+	// no other follows.
+	if n != w.n || n > w.at+2*slabSize {
+		t.Errorf("wrote %d bytes, counted %d, after cancelling at %d", w.n, n, w.at)
+	}
+	if g.Offset() != uint64(n) {
+		t.Errorf("generator is at %d after writing %d", g.Offset(), n)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if n, err := g.WriteToContext(ctx, io.Discard); n != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled context: wrote %d bytes with error %v", n, err)
+	}
+}
+
+// shortWriter takes up to limit bytes and fails the write which would exceed
+// it.
+type shortWriter struct {
+	n, limit int64
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if w.n+int64(len(p)) > w.limit {
+		return 0, io.ErrShortWrite
+	}
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func TestWriteFromCount(t *testing.T) {
+	// A write which fails partway counts only what got through the buffer.
+	for _, f := range []Format{Binary, Dot, IPv4} {
+		for _, size := range []int{0, 1000} {
+			w := &shortWriter{limit: 3 * slabSize / 2}
+			n, err := Write(w, Options{Format: f, BufferSize: size})
+			if err == nil || n != w.n {
+				t.Errorf("%v with buffer %d: wrote %d bytes, counted %d with error %v", f, size, w.n, n, err)
+			}
+		}
+	}
+}
+
+func TestDigests(t *testing.T) {
+	// The canonical digests, as conip -sha256 prints them, which the README
+	// lists too.
+	cases := []struct {
+		name string
+		opts Options
+		size int64
+		sum  string
+	}{
+		{"binary prefix", Options{Limit: 1000000}, 1000000, "9955dbd1c520357e0883dbb26f3883fbc19b80e1211d35ff108c8586848ab044"},
+		{"dot prefix", Options{Format: Dot, Limit: 1000000}, 2781867, "bbad612403ce8b11161ebc62acbef5508d12560572ccf6f83f0fce30fcf68c9b"},
+		{"binary", Options{}, 1<<32 + 3, "9f1df3cd369f063d47647bca2995bc4e6b98cc4bc4cc18536b7e5e1e56d26e9f"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if testing.Short() && c.opts.Limit == 0 {
+				t.Skip("hashes all of B(256, 4)")
+			}
+			h := sha256.New()
+			n, err := Write(h, c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != c.size {
+				t.Errorf("wrote %d bytes, want %d", n, c.size)
+			}
+			if got := hex.EncodeToString(h.Sum(nil)); got != c.sum {
+				t.Errorf("digest %s, want %s", got, c.sum)
+			}
+		})
+	}
+}
+
+func TestTermsBatches(t *testing.T) {
+	// Terms yields term by term what WriteTo writes in slabs.
+	want := prefix(t, 3*slabSize+5)
+	got := make([]byte, 0, len(want))
+	for b := range Terms() {
+		got = append(got, b)
+		if len(got) == len(want) {
+			break
+		}
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("Terms differs from WriteTo")
+	}
+}
+
+func BenchmarkTerms(b *testing.B) {
+	b.SetBytes(1)
+	for n := 0; n < b.N; {
+		for range Terms() {
+			if n++; n == b.N {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	b.Run("generator", func(b *testing.B) {
+		b.SetBytes(slabSize)
+		w := &limitWriter{}
+		for w.n < b.N {
+			var g Generator
+			w.stop = b.N
+			g.WriteTo(w)
+		}
+	})
+	// Through Write, each op writes 16 MiB of binary output with the
+	// buffer, where 0 is the default of none beyond the generator's own.
+	const n = 16 << 20
+	for _, size := range []int{0, 512, 4 << 10, 64 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("buffer=%d", size), func(b *testing.B) {
+			b.SetBytes(n)
+			opts := Options{Format: Binary, Limit: n, BufferSize: size}
+			for range b.N {
+				if _, err := Write(io.Discard, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// limitWriter counts the writes to it and fails once it has counted stop.
+type limitWriter struct {
+	n, stop int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if w.n == w.stop {
+		return 0, io.ErrShortWrite
+	}
+	w.n++
+	return len(p), nil
+}
+
+func BenchmarkWriteParallel(b *testing.B) {
+	// Each op writes the last 64 chunks of the sequence, enough to keep
+	// every worker busy for a while.
+	const n = 64 * chunkSize
+	workers := []int{}
+	for k := 1; k < runtime.GOMAXPROCS(0); k *= 2 {
+		workers = append(workers, k)
+	}
+	workers = append(workers, runtime.GOMAXPROCS(0))
+	for _, k := range workers {
+		b.Run(fmt.Sprintf("workers=%d", k), func(b *testing.B) {
+			b.SetBytes(n)
+			for range b.N {
+				if _, err := WriteParallel(discardAt{}, seqLen-n, k); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// discardAt is an io.WriterAt which discards what is written to it.
+type discardAt struct{}
+
+func (discardAt) WriteAt(p []byte, off int64) (int, error) {
+	return len(p), nil
+}
+
+func BenchmarkAdvance4(b *testing.B) {
+	// Start at the first word of 4 terms, 00 00 00 01, and start again
+	// after the last.
+	var g Generator
+	start := func() {
+		g.init()
+		g.stage = stageWords
+		copy(g.u, []byte{0, 0, 0, 1})
+		g.i, g.n = 4, 4
+	}
+	start()
+	for range b.N {
+		if !g.advance4() {
+			start()
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package debruijn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Decoder reads terms back from the text encodings that WriteText, WriteHex,
+// and WritePadded produce, terms separated by '.', by newlines, or by CRLF line
+// endings, as TextEncoder and HexEncoder write with a "\r\n" separator. Every
+// separator must be the same. The first term has no separator before it, and
+// the input may end with a single line ending. A
+// Decoder reads the terms as bytes, so that reading a text encoding through
+// it gives the binary encoding.
+type Decoder struct {
+	r   *bufio.Reader
+	hex bool
+	// pad is whether decimal terms have exactly three digits.
+	pad bool
+	// off is the offset in the input of the next byte to read.
+	off int64
+	// sep is the separator between terms, or empty until the first one.
+	sep string
+	// started is whether a term has been read.
+	started bool
+	// err is the error which ended decoding, if any.
+	err error
+}
+
+// NewDecoder returns a Decoder reading terms in decimal from r, as WriteText
+// writes them. A term with a leading zero other than 0 itself is malformed.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// NewHexDecoder returns a Decoder reading terms as two lowercase hexadecimal
+// digits from r, as WriteHex writes them.
+func NewHexDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), hex: true}
+}
+
+// NewPaddedDecoder returns a Decoder reading terms as three zero-padded
+// decimal digits from r, as WritePadded writes them.
+func NewPaddedDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), pad: true}
+}
+
+// SyntaxError describes malformed input to a Decoder.
+type SyntaxError struct {
+	// Offset is the offset in the input of the byte or term which is
+	// malformed.
+	Offset int64
+	msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("debruijn: offset %d: %s", e.Offset, e.msg)
+}
+
+// ReadByte returns the next term. At the end of the input, it returns io.EOF.
+// If the input is malformed, the error is a *SyntaxError, and if reading it
+// fails, the error is the one the reader returned. After any error, ReadByte
+// returns the same error on every call.
+func (d *Decoder) ReadByte() (byte, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	term, err := d.term()
+	if err != nil {
+		d.err = err
+	}
+	return term, err
+}
+
+// Read reads terms into p and returns the number read, so that a Decoder can
+// convert a text encoding to the binary one with io.Copy. It returns an error
+// only if it reads no terms, holding any other until the next call.
+func (d *Decoder) Read(p []byte) (int, error) {
+	for k := range p {
+		term, err := d.ReadByte()
+		if err != nil {
+			if k > 0 {
+				return k, nil
+			}
+			return 0, err
+		}
+		p[k] = term
+		// Return what we have rather than wait for input that isn't
+		// buffered yet.
+		if d.r.Buffered() == 0 {
+			return k + 1, nil
+		}
+	}
+	return len(p), nil
+}
+
+// term reads the separator, if any, and the digits of the next term.
+func (d *Decoder) term() (byte, error) {
+	if d.started {
+		start := d.off
+		sep, err := d.separator()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case d.sep == "":
+			d.sep = sep
+		case sep != d.sep:
+			return 0, syntaxError(start, fmt.Sprintf("separator %q after %q separators", sep, d.sep))
+		}
+	} else if _, err := d.r.Peek(1); err != nil {
+		return 0, err
+	}
+	start := d.off
+	var v, digits int
+	for {
+		b, err := d.r.Peek(1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		c := b[0]
+		if c == '.' || c == '\n' || c == '\r' {
+			break
+		}
+		x, ok := d.digit(c)
+		if !ok {
+			return 0, syntaxError(d.off, fmt.Sprintf("invalid character %q", c))
+		}
+		d.r.ReadByte()
+		d.off++
+		if d.hex {
+			v = v*16 + x
+		} else {
+			if digits == 1 && v == 0 && !d.pad {
+				return 0, syntaxError(start, "term with leading zero")
+			}
+			v = v*10 + x
+		}
+		digits++
+		if v > 255 || d.hex && digits > 2 || d.pad && digits > 3 {
+			return 0, syntaxError(start, "term out of range")
+		}
+	}
+	switch {
+	case digits == 0:
+		return 0, syntaxError(start, "empty term")
+	case d.hex && digits != 2:
+		return 0, syntaxError(start, "hexadecimal term with one digit")
+	case d.pad && digits != 3:
+		return 0, syntaxError(start, "padded term with fewer than three digits")
+	}
+	d.started = true
+	return byte(v), nil
+}
+
+// separator reads the separator before the next term. A line ending which
+// ends the input is a terminator rather than a separator, for which separator
+// returns io.EOF.
+func (d *Decoder) separator() (string, error) {
+	start := d.off
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	d.off++
+	var sep string
+	switch c {
+	case '.':
+		if _, err := d.r.Peek(1); err == io.EOF {
+			return "", syntaxError(start, "input ends with a separator")
+		}
+		return ".", nil
+	case '\n':
+		sep = "\n"
+	case '\r':
+		if c, err := d.r.ReadByte(); err != nil || c != '\n' {
+			return "", syntaxError(start, "carriage return without newline")
+		}
+		d.off++
+		sep = "\r\n"
+	default:
+		return "", syntaxError(start, fmt.Sprintf("expected separator, got %q", c))
+	}
+	if _, err := d.r.Peek(1); err == io.EOF {
+		return "", io.EOF
+	}
+	return sep, nil
+}
+
+// digit returns the value of c as a digit of a term.
+func (d *Decoder) digit(c byte) (int, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0'), true
+	case d.hex && 'a' <= c && c <= 'f':
+		return int(c-'a') + 10, true
+	}
+	return 0, false
+}
+
+func syntaxError(off int64, msg string) error {
+	return &SyntaxError{Offset: off, msg: msg}
+}
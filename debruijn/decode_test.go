@@ -0,0 +1,100 @@
+package debruijn
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	cases := []struct {
+		name string
+		dec  func(io.Reader) *Decoder
+		in   string
+		want []byte
+	}{
+		{"empty", NewDecoder, "", nil},
+		{"one", NewDecoder, "7", []byte{7}},
+		{"dots", NewDecoder, "0.1.10.255", []byte{0, 1, 10, 255}},
+		{"lines", NewDecoder, "0\n1\n10\n255", []byte{0, 1, 10, 255}},
+		{"trailing newline", NewDecoder, "0\n1\n", []byte{0, 1}},
+		{"dots trailing newline", NewDecoder, "0.1\n", []byte{0, 1}},
+		{"crlf", NewDecoder, "3\r\n4\r\n", []byte{3, 4}},
+		{"hex", NewHexDecoder, "00.0a.ff", []byte{0, 10, 255}},
+		{"hex lines", NewHexDecoder, "00\n0a\nff\n", []byte{0, 10, 255}},
+		{"padded", NewPaddedDecoder, "000.007.042.255", []byte{0, 7, 42, 255}},
+	}
+	for _, c := range cases {
+		got, err := io.ReadAll(c.dec(strings.NewReader(c.in)))
+		if err != nil || !bytes.Equal(got, c.want) {
+			t.Errorf("%s: got %v with error %v, want %v", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		dec  func(io.Reader) *Decoder
+		in   string
+		off  int64
+	}{
+		{"out of range", NewDecoder, "1.256.3", 2},
+		{"leading zero", NewDecoder, "1.01", 2},
+		{"empty field", NewDecoder, "1..2", 2},
+		{"empty first field", NewDecoder, ".1", 0},
+		{"trailing separator", NewDecoder, "1.2.", 3},
+		{"two trailing newlines", NewDecoder, "1\n\n", 2},
+		{"mixed separators", NewDecoder, "1.2\n3", 3},
+		{"newline after crlf", NewDecoder, "0\r\n0\n0", 4},
+		{"crlf after newline", NewDecoder, "0\n0\r\n0", 3},
+		{"hex newline after crlf", NewHexDecoder, "00\r\n00\n00", 6},
+		{"bare carriage return", NewDecoder, "1\r2", 1},
+		{"letter", NewDecoder, "1.x", 2},
+		{"space", NewDecoder, "1, 2", 1},
+		{"hex one digit", NewHexDecoder, "00.a", 3},
+		{"hex three digits", NewHexDecoder, "00.abc", 3},
+		{"hex uppercase", NewHexDecoder, "0A", 1},
+		{"padded short", NewPaddedDecoder, "001.07", 4},
+		{"padded out of range", NewPaddedDecoder, "256", 0},
+	}
+	for _, c := range cases {
+		d := c.dec(strings.NewReader(c.in))
+		_, err := io.ReadAll(d)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%s: got error %v, want a SyntaxError", c.name, err)
+			continue
+		}
+		if se.Offset != c.off {
+			t.Errorf("%s: error at offset %d, want %d: %v", c.name, se.Offset, c.off, err)
+		}
+		// The error sticks.
+		if _, again := d.ReadByte(); again != err {
+			t.Errorf("%s: next read gave %v, want %v", c.name, again, err)
+		}
+	}
+}
+
+func TestDecoderSequence(t *testing.T) {
+	// Decoding each format gives back the binary encoding.
+	want := prefix(t, 100000)
+	for _, f := range textFormats[1:] {
+		var b bytes.Buffer
+		if _, err := Write(&b, Options{Format: f, Limit: int64(len(want))}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(f.NewDecoder(&b))
+		if err != nil {
+			t.Fatalf("%v: %v", f, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%v: decoded %d terms which differ from the %d written", f, len(got), len(want))
+		}
+	}
+	if Binary.NewDecoder(nil) != nil || IPv4.NewDecoder(nil) != nil {
+		t.Error("a decoder for bin or quad")
+	}
+}
@@ -0,0 +1,353 @@
+package debruijn
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/netip"
+	"strconv"
+)
+
+// slabSize is the size of the chunks in which WriteTo generates terms.
+const slabSize = 1 << 16
+
+// WriteTo writes the binary encoding of the remaining terms of g to w,
+// generating them directly into large slabs. It returns the number of bytes
+// written. If a write fails, the returned error wraps it along with the
+// offset in the sequence of the first byte which wasn't written; g is then
+// positioned after the entire slab containing that byte.
+func (g *Generator) WriteTo(w io.Writer) (int64, error) {
+	return g.WriteToContext(context.Background(), w)
+}
+
+// WriteToContext is like WriteTo, but stops once ctx is done, returning the
+// number of bytes written and the context's error. It checks ctx between
+// slabs, so it writes at most one more slab after ctx is done.
+func (g *Generator) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	buf := make([]byte, slabSize)
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		start := g.terms
+		k := g.fill(buf)
+		if k == 0 {
+			return written, nil
+		}
+		c, err := w.Write(buf[:k])
+		written += int64(c)
+		if err == nil && c < k {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return written, fmt.Errorf("debruijn: writing at offset %d: %w", start+uint64(c), err)
+		}
+	}
+}
+
+// WriteBinary writes up to n terms from g to w, each as a single byte with no
+// separating characters. If n is negative, WriteBinary writes all remaining
+// terms. It returns the number of bytes written and the first error
+// encountered.
+func WriteBinary(w io.Writer, g *Generator, n int64) (int64, error) {
+	var buf [4096]byte
+	var written int64
+	for n != 0 {
+		p := buf[:]
+		if n > 0 && n < int64(len(p)) {
+			p = p[:n]
+		}
+		k := g.fill(p)
+		if k == 0 {
+			break
+		}
+		c, err := w.Write(p[:k])
+		written += int64(c)
+		if err != nil {
+			return written, err
+		}
+		if n > 0 {
+			n -= int64(k)
+		}
+	}
+	return written, nil
+}
+
+// WriteText writes up to n terms from g to w in decimal, separated by sep,
+// which must be '.' or '\n'. If n is negative, WriteText writes all remaining
+// terms. No separator precedes the first term of the sequence. It returns the
+// number of bytes written and the first error encountered.
+//
+// If w is a *bufio.Writer, WriteText writes to it directly, and the caller is
+// responsible for flushing it. Otherwise, WriteText buffers its output
+// internally.
+func WriteText(w io.Writer, g *Generator, sep byte, n int64) (int64, error) {
+	switch sep {
+	case '.':
+		return writeTerms(w, g, &encd, n, g.first())
+	case '\n':
+		return writeTerms(w, g, &encn, n, g.first())
+	}
+	return 0, errUnsupportedSep
+}
+
+// WriteHex is like WriteText, but writes each term as two lowercase
+// hexadecimal digits, making the output easy to compare with hex dumps of the
+// binary encoding.
+func WriteHex(w io.Writer, g *Generator, sep byte, n int64) (int64, error) {
+	switch sep {
+	case '.':
+		return writeTerms(w, g, &hexd, n, g.first())
+	case '\n':
+		return writeTerms(w, g, &hexn, n, g.first())
+	}
+	return 0, errUnsupportedSep
+}
+
+// WritePadded is like WriteText, but writes each term as three decimal
+// digits, zero-padded like 007, so that every term takes the same width and
+// the term at any index is at a known offset.
+func WritePadded(w io.Writer, g *Generator, sep byte, n int64) (int64, error) {
+	switch sep {
+	case '.':
+		return writeTerms(w, g, &padd, n, g.first())
+	case '\n':
+		return writeTerms(w, g, &padn, n, g.first())
+	}
+	return 0, errUnsupportedSep
+}
+
+// writeTerms writes up to n terms from g to w using the encodings in encs,
+// each of which begins with a one-byte separator, omitted from the first term
+// if first is true.
+func writeTerms(w io.Writer, g *Generator, encs *[256]string, n int64, first bool) (int64, error) {
+	bw, buffered := w.(*bufio.Writer)
+	if !buffered {
+		bw = bufio.NewWriter(w)
+	}
+	var written int64
+	if n == 0 {
+		return 0, nil
+	}
+	for term := range g.Terms() {
+		s := encs[term]
+		if first {
+			s = s[1:]
+			first = false
+		}
+		c, err := bw.WriteString(s)
+		written += int64(c)
+		if err != nil {
+			return flushed(bw, buffered, written, err)
+		}
+		n--
+		if n == 0 {
+			break
+		}
+	}
+	return flushed(bw, buffered, written, nil)
+}
+
+// flushed finishes writing through bw, which has taken written bytes, and
+// returns the number which reached the writer beneath it along with err. If
+// buffered is false, bw is the function's own, so it flushes bw if err is nil
+// and leaves out of the count whatever remains in bw. Otherwise, bw is the
+// caller's, for which what it has taken counts as written.
+func flushed(bw *bufio.Writer, buffered bool, written int64, err error) (int64, error) {
+	if buffered {
+		return written, err
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	return written - int64(bw.Buffered()), err
+}
+
+// writeWrapped is like writeTerms, but ends a line after every wrap terms by
+// putting a newline after the separator which follows them. The encodings
+// with the newline are computed once, so that each term is still a single
+// string, and the count of terms to the next line break is all that changes
+// per term.
+func writeWrapped(w io.Writer, g *Generator, encs *[256]string, wrap, n int64, first bool) (int64, error) {
+	bw, buffered := w.(*bufio.Writer)
+	if !buffered {
+		bw = bufio.NewWriter(w)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	var broken [256]string
+	for i, s := range encs {
+		broken[i] = s[:1] + "\n" + s[1:]
+	}
+	var written int64
+	// left is the number of terms before the next line break.
+	left := wrap
+	for term := range g.Terms() {
+		s := encs[term]
+		switch {
+		case first:
+			s = s[1:]
+			first = false
+		case left == 0:
+			s = broken[term]
+			left = wrap
+		}
+		left--
+		c, err := bw.WriteString(s)
+		written += int64(c)
+		if err != nil {
+			return flushed(bw, buffered, written, err)
+		}
+		n--
+		if n == 0 {
+			break
+		}
+	}
+	return flushed(bw, buffered, written, nil)
+}
+
+// ContextWriter returns a writer which writes to w until ctx is done, after
+// which its writes fail with the context's error. This makes any of the
+// functions which write the sequence stop once ctx is done, having written at
+// most one more buffer of output. In particular, wrapping the writer passed
+// to WriteText, WriteHex, WriteIPv4, WriteAddrs, or Encode in a ContextWriter
+// makes it stop within a few kilobytes of cancellation.
+func ContextWriter(ctx context.Context, w io.Writer) io.Writer {
+	return &ctxWriter{ctx: ctx, w: w}
+}
+
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w *ctxWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+// WriteIPv4 writes up to n IPv4 addresses from g to w, one per line in
+// dotted-quad form. Each address is a window of four consecutive terms, and
+// successive windows overlap by three terms, so that writing the entire
+// sequence produces every one of the 2^32 addresses exactly once, including
+// the three which wrap around the end of the cycle. That is 2^32 lines totaling
+// exactly 61337501696 bytes, or about 57.1 GiB. If n is negative, WriteIPv4
+// writes every remaining window.
+//
+// The first window begins with the next term of g, which must generate
+// B(256, 4). WriteIPv4 returns the number of bytes written and the
+// first error encountered. It treats w the same way as WriteText.
+func WriteIPv4(w io.Writer, g *Generator, n int64) (int64, error) {
+	if !g.standard() {
+		return 0, errIPv4Order
+	}
+	return WriteAddrs(w, g.Addrs(), n)
+}
+
+// WriteAddrs writes up to n IPv4 addresses from addrs to w in dotted-quad
+// form, one per line, in the same format as WriteIPv4. This allows writing a
+// filtered selection of the windows of a Generator. If n is negative,
+// WriteAddrs writes every address. Addresses other than IPv4 addresses are
+// skipped without counting toward n.
+//
+// WriteAddrs returns the number of bytes written and the first error
+// encountered. It treats w the same way as WriteText.
+func WriteAddrs(w io.Writer, addrs iter.Seq[netip.Addr], n int64) (int64, error) {
+	return writeAddrs(w, addrs, "\n", n)
+}
+
+// WriteCIDR is like WriteAddrs, but writes each address in CIDR notation with
+// a prefix length of bits, such as 10.1.2.3/24, for tools which expect it.
+// The address is written as it is, without masking off the host bits. bits
+// must be between 0 and 32.
+func WriteCIDR(w io.Writer, addrs iter.Seq[netip.Addr], bits int, n int64) (int64, error) {
+	if bits < 0 || bits > 32 {
+		return 0, errCIDRBits
+	}
+	return writeAddrs(w, addrs, "/"+strconv.Itoa(bits)+"\n", n)
+}
+
+// writeAddrs writes up to n IPv4 addresses from addrs to w in dotted-quad
+// form, each followed by end.
+func writeAddrs(w io.Writer, addrs iter.Seq[netip.Addr], end string, n int64) (int64, error) {
+	bw, buffered := w.(*bufio.Writer)
+	if !buffered {
+		bw = bufio.NewWriter(w)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	var written int64
+	for addr := range addrs {
+		if !addr.Is4() {
+			continue
+		}
+		win := addr.As4()
+		for _, s := range [...]string{encd[win[0]][1:], encd[win[1]], encd[win[2]], encd[win[3]], end} {
+			c, err := bw.WriteString(s)
+			written += int64(c)
+			if err != nil {
+				return flushed(bw, buffered, written, err)
+			}
+		}
+		n--
+		if n == 0 {
+			break
+		}
+	}
+	return flushed(bw, buffered, written, nil)
+}
+
+var (
+	errUnsupportedSep = errors.New("debruijn: unsupported separator")
+	errIPv4Order      = errors.New("debruijn: IPv4 addresses require B(256, 4)")
+	errCIDRBits       = errors.New("debruijn: IPv4 prefix length must be between 0 and 32")
+)
+
+// The encodings of the text formats, each term preceded by its separator.
+var (
+	encd = buildEncoding(".")
+	encn = buildEncoding("\n")
+	hexd = buildHexEncoding(".")
+	hexn = buildHexEncoding("\n")
+	padd = buildPaddedEncoding(".")
+	padn = buildPaddedEncoding("\n")
+)
+
+// buildEncoding returns the decimal encoding of each term, preceded by sep.
+func buildEncoding(sep string) [256]string {
+	var encs [256]string
+	for t := range encs {
+		encs[t] = sep + strconv.Itoa(t)
+	}
+	return encs
+}
+
+// buildHexEncoding returns the encoding of each term as two lowercase
+// hexadecimal digits, preceded by sep.
+func buildHexEncoding(sep string) [256]string {
+	var encs [256]string
+	for t := range encs {
+		// The extra high digit pads the term to two digits.
+		encs[t] = sep + strconv.FormatUint(uint64(t)|0x100, 16)[1:]
+	}
+	return encs
+}
+
+// buildPaddedEncoding returns the encoding of each term as three decimal
+// digits, preceded by sep.
+func buildPaddedEncoding(sep string) [256]string {
+	var encs [256]string
+	for t := range encs {
+		// As for hexadecimal, the extra high digit pads the term.
+		encs[t] = sep + strconv.Itoa(t + 1000)[1:]
+	}
+	return encs
+}
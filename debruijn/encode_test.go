@@ -0,0 +1,216 @@
+package debruijn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// The encodings of the dot, lines, hex, and hexlines formats as they were
+// written out before buildEncoding and buildHexEncoding made them.
+var literalDot = [256]string{
+	".0", ".1", ".2", ".3", ".4", ".5", ".6", ".7", ".8", ".9", ".10", ".11", ".12", ".13", ".14", ".15",
+	".16", ".17", ".18", ".19", ".20", ".21", ".22", ".23", ".24", ".25", ".26", ".27", ".28", ".29", ".30", ".31",
+	".32", ".33", ".34", ".35", ".36", ".37", ".38", ".39", ".40", ".41", ".42", ".43", ".44", ".45", ".46", ".47",
+	".48", ".49", ".50", ".51", ".52", ".53", ".54", ".55", ".56", ".57", ".58", ".59", ".60", ".61", ".62", ".63",
+	".64", ".65", ".66", ".67", ".68", ".69", ".70", ".71", ".72", ".73", ".74", ".75", ".76", ".77", ".78", ".79",
+	".80", ".81", ".82", ".83", ".84", ".85", ".86", ".87", ".88", ".89", ".90", ".91", ".92", ".93", ".94", ".95",
+	".96", ".97", ".98", ".99", ".100", ".101", ".102", ".103", ".104", ".105", ".106", ".107", ".108", ".109", ".110", ".111",
+	".112", ".113", ".114", ".115", ".116", ".117", ".118", ".119", ".120", ".121", ".122", ".123", ".124", ".125", ".126", ".127",
+	".128", ".129", ".130", ".131", ".132", ".133", ".134", ".135", ".136", ".137", ".138", ".139", ".140", ".141", ".142", ".143",
+	".144", ".145", ".146", ".147", ".148", ".149", ".150", ".151", ".152", ".153", ".154", ".155", ".156", ".157", ".158", ".159",
+	".160", ".161", ".162", ".163", ".164", ".165", ".166", ".167", ".168", ".169", ".170", ".171", ".172", ".173", ".174", ".175",
+	".176", ".177", ".178", ".179", ".180", ".181", ".182", ".183", ".184", ".185", ".186", ".187", ".188", ".189", ".190", ".191",
+	".192", ".193", ".194", ".195", ".196", ".197", ".198", ".199", ".200", ".201", ".202", ".203", ".204", ".205", ".206", ".207",
+	".208", ".209", ".210", ".211", ".212", ".213", ".214", ".215", ".216", ".217", ".218", ".219", ".220", ".221", ".222", ".223",
+	".224", ".225", ".226", ".227", ".228", ".229", ".230", ".231", ".232", ".233", ".234", ".235", ".236", ".237", ".238", ".239",
+	".240", ".241", ".242", ".243", ".244", ".245", ".246", ".247", ".248", ".249", ".250", ".251", ".252", ".253", ".254", ".255",
+}
+
+var literalLines = [256]string{
+	"\n0", "\n1", "\n2", "\n3", "\n4", "\n5", "\n6", "\n7", "\n8", "\n9", "\n10", "\n11", "\n12", "\n13", "\n14", "\n15",
+	"\n16", "\n17", "\n18", "\n19", "\n20", "\n21", "\n22", "\n23", "\n24", "\n25", "\n26", "\n27", "\n28", "\n29", "\n30", "\n31",
+	"\n32", "\n33", "\n34", "\n35", "\n36", "\n37", "\n38", "\n39", "\n40", "\n41", "\n42", "\n43", "\n44", "\n45", "\n46", "\n47",
+	"\n48", "\n49", "\n50", "\n51", "\n52", "\n53", "\n54", "\n55", "\n56", "\n57", "\n58", "\n59", "\n60", "\n61", "\n62", "\n63",
+	"\n64", "\n65", "\n66", "\n67", "\n68", "\n69", "\n70", "\n71", "\n72", "\n73", "\n74", "\n75", "\n76", "\n77", "\n78", "\n79",
+	"\n80", "\n81", "\n82", "\n83", "\n84", "\n85", "\n86", "\n87", "\n88", "\n89", "\n90", "\n91", "\n92", "\n93", "\n94", "\n95",
+	"\n96", "\n97", "\n98", "\n99", "\n100", "\n101", "\n102", "\n103", "\n104", "\n105", "\n106", "\n107", "\n108", "\n109", "\n110", "\n111",
+	"\n112", "\n113", "\n114", "\n115", "\n116", "\n117", "\n118", "\n119", "\n120", "\n121", "\n122", "\n123", "\n124", "\n125", "\n126", "\n127",
+	"\n128", "\n129", "\n130", "\n131", "\n132", "\n133", "\n134", "\n135", "\n136", "\n137", "\n138", "\n139", "\n140", "\n141", "\n142", "\n143",
+	"\n144", "\n145", "\n146", "\n147", "\n148", "\n149", "\n150", "\n151", "\n152", "\n153", "\n154", "\n155", "\n156", "\n157", "\n158", "\n159",
+	"\n160", "\n161", "\n162", "\n163", "\n164", "\n165", "\n166", "\n167", "\n168", "\n169", "\n170", "\n171", "\n172", "\n173", "\n174", "\n175",
+	"\n176", "\n177", "\n178", "\n179", "\n180", "\n181", "\n182", "\n183", "\n184", "\n185", "\n186", "\n187", "\n188", "\n189", "\n190", "\n191",
+	"\n192", "\n193", "\n194", "\n195", "\n196", "\n197", "\n198", "\n199", "\n200", "\n201", "\n202", "\n203", "\n204", "\n205", "\n206", "\n207",
+	"\n208", "\n209", "\n210", "\n211", "\n212", "\n213", "\n214", "\n215", "\n216", "\n217", "\n218", "\n219", "\n220", "\n221", "\n222", "\n223",
+	"\n224", "\n225", "\n226", "\n227", "\n228", "\n229", "\n230", "\n231", "\n232", "\n233", "\n234", "\n235", "\n236", "\n237", "\n238", "\n239",
+	"\n240", "\n241", "\n242", "\n243", "\n244", "\n245", "\n246", "\n247", "\n248", "\n249", "\n250", "\n251", "\n252", "\n253", "\n254", "\n255",
+}
+
+var literalHexDot = [256]string{
+	".00", ".01", ".02", ".03", ".04", ".05", ".06", ".07", ".08", ".09", ".0a", ".0b", ".0c", ".0d", ".0e", ".0f",
+	".10", ".11", ".12", ".13", ".14", ".15", ".16", ".17", ".18", ".19", ".1a", ".1b", ".1c", ".1d", ".1e", ".1f",
+	".20", ".21", ".22", ".23", ".24", ".25", ".26", ".27", ".28", ".29", ".2a", ".2b", ".2c", ".2d", ".2e", ".2f",
+	".30", ".31", ".32", ".33", ".34", ".35", ".36", ".37", ".38", ".39", ".3a", ".3b", ".3c", ".3d", ".3e", ".3f",
+	".40", ".41", ".42", ".43", ".44", ".45", ".46", ".47", ".48", ".49", ".4a", ".4b", ".4c", ".4d", ".4e", ".4f",
+	".50", ".51", ".52", ".53", ".54", ".55", ".56", ".57", ".58", ".59", ".5a", ".5b", ".5c", ".5d", ".5e", ".5f",
+	".60", ".61", ".62", ".63", ".64", ".65", ".66", ".67", ".68", ".69", ".6a", ".6b", ".6c", ".6d", ".6e", ".6f",
+	".70", ".71", ".72", ".73", ".74", ".75", ".76", ".77", ".78", ".79", ".7a", ".7b", ".7c", ".7d", ".7e", ".7f",
+	".80", ".81", ".82", ".83", ".84", ".85", ".86", ".87", ".88", ".89", ".8a", ".8b", ".8c", ".8d", ".8e", ".8f",
+	".90", ".91", ".92", ".93", ".94", ".95", ".96", ".97", ".98", ".99", ".9a", ".9b", ".9c", ".9d", ".9e", ".9f",
+	".a0", ".a1", ".a2", ".a3", ".a4", ".a5", ".a6", ".a7", ".a8", ".a9", ".aa", ".ab", ".ac", ".ad", ".ae", ".af",
+	".b0", ".b1", ".b2", ".b3", ".b4", ".b5", ".b6", ".b7", ".b8", ".b9", ".ba", ".bb", ".bc", ".bd", ".be", ".bf",
+	".c0", ".c1", ".c2", ".c3", ".c4", ".c5", ".c6", ".c7", ".c8", ".c9", ".ca", ".cb", ".cc", ".cd", ".ce", ".cf",
+	".d0", ".d1", ".d2", ".d3", ".d4", ".d5", ".d6", ".d7", ".d8", ".d9", ".da", ".db", ".dc", ".dd", ".de", ".df",
+	".e0", ".e1", ".e2", ".e3", ".e4", ".e5", ".e6", ".e7", ".e8", ".e9", ".ea", ".eb", ".ec", ".ed", ".ee", ".ef",
+	".f0", ".f1", ".f2", ".f3", ".f4", ".f5", ".f6", ".f7", ".f8", ".f9", ".fa", ".fb", ".fc", ".fd", ".fe", ".ff",
+}
+
+var literalHexLines = [256]string{
+	"\n00", "\n01", "\n02", "\n03", "\n04", "\n05", "\n06", "\n07", "\n08", "\n09", "\n0a", "\n0b", "\n0c", "\n0d", "\n0e", "\n0f",
+	"\n10", "\n11", "\n12", "\n13", "\n14", "\n15", "\n16", "\n17", "\n18", "\n19", "\n1a", "\n1b", "\n1c", "\n1d", "\n1e", "\n1f",
+	"\n20", "\n21", "\n22", "\n23", "\n24", "\n25", "\n26", "\n27", "\n28", "\n29", "\n2a", "\n2b", "\n2c", "\n2d", "\n2e", "\n2f",
+	"\n30", "\n31", "\n32", "\n33", "\n34", "\n35", "\n36", "\n37", "\n38", "\n39", "\n3a", "\n3b", "\n3c", "\n3d", "\n3e", "\n3f",
+	"\n40", "\n41", "\n42", "\n43", "\n44", "\n45", "\n46", "\n47", "\n48", "\n49", "\n4a", "\n4b", "\n4c", "\n4d", "\n4e", "\n4f",
+	"\n50", "\n51", "\n52", "\n53", "\n54", "\n55", "\n56", "\n57", "\n58", "\n59", "\n5a", "\n5b", "\n5c", "\n5d", "\n5e", "\n5f",
+	"\n60", "\n61", "\n62", "\n63", "\n64", "\n65", "\n66", "\n67", "\n68", "\n69", "\n6a", "\n6b", "\n6c", "\n6d", "\n6e", "\n6f",
+	"\n70", "\n71", "\n72", "\n73", "\n74", "\n75", "\n76", "\n77", "\n78", "\n79", "\n7a", "\n7b", "\n7c", "\n7d", "\n7e", "\n7f",
+	"\n80", "\n81", "\n82", "\n83", "\n84", "\n85", "\n86", "\n87", "\n88", "\n89", "\n8a", "\n8b", "\n8c", "\n8d", "\n8e", "\n8f",
+	"\n90", "\n91", "\n92", "\n93", "\n94", "\n95", "\n96", "\n97", "\n98", "\n99", "\n9a", "\n9b", "\n9c", "\n9d", "\n9e", "\n9f",
+	"\na0", "\na1", "\na2", "\na3", "\na4", "\na5", "\na6", "\na7", "\na8", "\na9", "\naa", "\nab", "\nac", "\nad", "\nae", "\naf",
+	"\nb0", "\nb1", "\nb2", "\nb3", "\nb4", "\nb5", "\nb6", "\nb7", "\nb8", "\nb9", "\nba", "\nbb", "\nbc", "\nbd", "\nbe", "\nbf",
+	"\nc0", "\nc1", "\nc2", "\nc3", "\nc4", "\nc5", "\nc6", "\nc7", "\nc8", "\nc9", "\nca", "\ncb", "\ncc", "\ncd", "\nce", "\ncf",
+	"\nd0", "\nd1", "\nd2", "\nd3", "\nd4", "\nd5", "\nd6", "\nd7", "\nd8", "\nd9", "\nda", "\ndb", "\ndc", "\ndd", "\nde", "\ndf",
+	"\ne0", "\ne1", "\ne2", "\ne3", "\ne4", "\ne5", "\ne6", "\ne7", "\ne8", "\ne9", "\nea", "\neb", "\nec", "\ned", "\nee", "\nef",
+	"\nf0", "\nf1", "\nf2", "\nf3", "\nf4", "\nf5", "\nf6", "\nf7", "\nf8", "\nf9", "\nfa", "\nfb", "\nfc", "\nfd", "\nfe", "\nff",
+}
+
+func TestBuildEncoding(t *testing.T) {
+	cases := []struct {
+		name string
+		got  *[256]string
+		want *[256]string
+	}{
+		{"encd", &encd, &literalDot},
+		{"encn", &encn, &literalLines},
+		{"hexd", &hexd, &literalHexDot},
+		{"hexn", &hexn, &literalHexLines},
+	}
+	for _, c := range cases {
+		for i := range c.want {
+			if c.got[i] != c.want[i] {
+				t.Errorf("%s[%d] = %q, want %q", c.name, i, c.got[i], c.want[i])
+			}
+		}
+	}
+	if got := buildEncoding(", "); got[0] != ", 0" || got[255] != ", 255" {
+		t.Errorf("buildEncoding(\", \") gives %q through %q", got[0], got[255])
+	}
+}
+
+func TestBuildFixedEncodings(t *testing.T) {
+	cases := []struct {
+		name string
+		encs *[256]string
+		term int
+		want string
+	}{
+		{"hexd", &hexd, 0, ".00"},
+		{"hexd", &hexd, 10, ".0a"},
+		{"hexd", &hexd, 16, ".10"},
+		{"hexd", &hexd, 255, ".ff"},
+		{"hexn", &hexn, 0, "\n00"},
+		{"hexn", &hexn, 171, "\nab"},
+		{"padd", &padd, 0, ".000"},
+		{"padd", &padd, 7, ".007"},
+		{"padd", &padd, 42, ".042"},
+		{"padd", &padd, 255, ".255"},
+		{"padn", &padn, 99, "\n099"},
+		{"padn", &padn, 100, "\n100"},
+	}
+	for _, c := range cases {
+		if got := c.encs[c.term]; got != c.want {
+			t.Errorf("%s[%d] = %q, want %q", c.name, c.term, got, c.want)
+		}
+	}
+}
+
+func TestWriteHex(t *testing.T) {
+	want := prefix(t, 100000)
+	for _, sep := range []byte{'.', '\n'} {
+		var b strings.Builder
+		g, _ := New(4)
+		// Writing in two parts separates them just as writing at once does.
+		n, err := WriteHex(&b, g, sep, 60000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := WriteHex(&b, g, sep, 40000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n+m != int64(b.Len()) || b.Len() != 3*len(want)-1 {
+			t.Errorf("%q: wrote %d bytes, counted %d, want %d", sep, b.Len(), n+m, 3*len(want)-1)
+		}
+		got, err := hex.DecodeString(strings.ReplaceAll(b.String(), string(sep), ""))
+		if err != nil {
+			t.Fatalf("%q: %v", sep, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%q: hex decodes to different terms than binary", sep)
+		}
+	}
+	g, _ := New(4)
+	if _, err := WriteHex(new(bytes.Buffer), g, ',', 10); !errors.Is(err, errUnsupportedSep) {
+		t.Errorf("',' separator: got %v, want errUnsupportedSep", err)
+	}
+}
+
+func TestWritePadded(t *testing.T) {
+	want := prefix(t, 100000)
+	for _, sep := range []byte{'.', '\n'} {
+		var b strings.Builder
+		g, _ := New(4)
+		n, err := WritePadded(&b, g, sep, 60000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := WritePadded(&b, g, sep, 40000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n+m != int64(b.Len()) || b.Len() != 4*len(want)-1 {
+			t.Errorf("%q: wrote %d bytes, counted %d, want %d", sep, b.Len(), n+m, 4*len(want)-1)
+		}
+		// Term i is the three digits at offset 4i.
+		s := b.String()
+		for i, term := range want {
+			if d := s[4*i : 4*i+3]; d != fmt.Sprintf("%03d", term) {
+				t.Fatalf("%q: term %d is %q, want %03d", sep, i, d, term)
+			}
+			if i > 0 && s[4*i-1] != sep {
+				t.Fatalf("%q: byte %d is %q", sep, 4*i-1, s[4*i-1])
+			}
+		}
+		got, err := io.ReadAll(NewPaddedDecoder(strings.NewReader(s)))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%q: decodes to %d terms which differ from the binary, with error %v", sep, len(got), err)
+		}
+	}
+	// The size is exactly four bytes per term less the first separator.
+	for order := 1; order <= 7; order++ {
+		terms, _ := Size(Binary, order)
+		for _, f := range []Format{PaddedDot, PaddedLines} {
+			if n, err := Size(f, order); err != nil || n != 4*terms-1 {
+				t.Errorf("%v order %d: size %d with error %v, want %d", f, order, n, err, 4*terms-1)
+			}
+		}
+	}
+	g, _ := New(4)
+	if _, err := WritePadded(new(bytes.Buffer), g, ',', 10); !errors.Is(err, errUnsupportedSep) {
+		t.Errorf("',' separator: got %v, want errUnsupportedSep", err)
+	}
+}
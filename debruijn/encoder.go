@@ -0,0 +1,199 @@
+package debruijn
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// Encoder formats terms for Encode. Implementations must be safe to use from
+// multiple goroutines at once; the built-in encoders have no state at all.
+type Encoder interface {
+	// Prologue appends to dst whatever precedes the first term of the
+	// sequence.
+	Prologue(dst []byte) []byte
+	// EncodeTerm appends the encoding of term to dst. first is true for the
+	// first term of the sequence, so that an encoding which separates terms
+	// can omit the separator before it.
+	EncodeTerm(dst []byte, term byte, first bool) []byte
+	// Epilogue appends to dst whatever follows the last term of the sequence.
+	Epilogue(dst []byte) []byte
+}
+
+// Encode writes up to n terms from g to w using e. If n is negative, Encode
+// writes all remaining terms. The prologue is written only if g is at the
+// start of its sequence, and the epilogue only once Encode exhausts g, so
+// that output written in pieces concatenates to the same as output written
+// at once. It returns the number of bytes written and the first error
+// encountered.
+func Encode(w io.Writer, g *Generator, e Encoder, n int64) (int64, error) {
+	return encode(w, g, e, n, g.first())
+}
+
+// encode is Encode, treating the next term as the first of the sequence if
+// first is true.
+func encode(w io.Writer, g *Generator, e Encoder, n int64, first bool) (int64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	buf := make([]byte, 0, 4096)
+	var written int64
+	flush := func() error {
+		c, err := w.Write(buf)
+		written += int64(c)
+		buf = buf[:0]
+		return err
+	}
+	if first {
+		buf = e.Prologue(buf)
+	}
+	done := true
+	for term := range g.Terms() {
+		buf = e.EncodeTerm(buf, term, first)
+		first = false
+		if len(buf) >= 3*cap(buf)/4 {
+			if err := flush(); err != nil {
+				return written, err
+			}
+		}
+		n--
+		if n == 0 {
+			done = false
+			break
+		}
+	}
+	if done {
+		buf = e.Epilogue(buf)
+	}
+	return written, flush()
+}
+
+// tableEncoder encodes each term as an entry of a table of encodings, each of
+// which begins with a separator of sep bytes.
+type tableEncoder struct {
+	encs *[256]string
+	sep  int
+}
+
+func (tableEncoder) Prologue(dst []byte) []byte { return dst }
+func (tableEncoder) Epilogue(dst []byte) []byte { return dst }
+
+func (e tableEncoder) EncodeTerm(dst []byte, term byte, first bool) []byte {
+	s := e.encs[term]
+	if first {
+		s = s[e.sep:]
+	}
+	return append(dst, s...)
+}
+
+// binaryEncoder encodes each term as a single byte.
+type binaryEncoder struct{}
+
+func (binaryEncoder) Prologue(dst []byte) []byte { return dst }
+func (binaryEncoder) Epilogue(dst []byte) []byte { return dst }
+
+func (binaryEncoder) EncodeTerm(dst []byte, term byte, first bool) []byte {
+	return append(dst, term)
+}
+
+var (
+	// BinaryEncoder writes each term as a single byte, like WriteBinary.
+	BinaryEncoder Encoder = binaryEncoder{}
+	// DotEncoder writes terms in decimal separated by ".", like WriteText
+	// with a '.' separator.
+	DotEncoder Encoder = tableEncoder{&encd, 1}
+	// LinesEncoder writes terms in decimal separated by newlines, like
+	// WriteText with a '\n' separator.
+	LinesEncoder Encoder = tableEncoder{&encn, 1}
+	// HexDotEncoder writes terms as two hexadecimal digits separated by ".",
+	// like WriteHex with a '.' separator.
+	HexDotEncoder Encoder = tableEncoder{&hexd, 1}
+	// HexLinesEncoder writes terms as two hexadecimal digits separated by
+	// newlines, like WriteHex with a '\n' separator.
+	HexLinesEncoder Encoder = tableEncoder{&hexn, 1}
+	// PaddedDotEncoder writes terms as three zero-padded decimal digits
+	// separated by ".", like WritePadded with a '.' separator.
+	PaddedDotEncoder Encoder = tableEncoder{&padd, 1}
+	// PaddedLinesEncoder writes terms as three zero-padded decimal digits
+	// separated by newlines, like WritePadded with a '\n' separator.
+	PaddedLinesEncoder Encoder = tableEncoder{&padn, 1}
+)
+
+// TextEncoder returns an Encoder which writes terms in decimal separated by
+// sep, which may be any string, such as ", " or "\r\n". An empty separator
+// concatenates the digits of the terms, which can't then be told apart. The
+// encodings are computed once, so encoding a term costs the same as for
+// DotEncoder. The error is always nil.
+func TextEncoder(sep string) (Encoder, error) {
+	encs := buildEncoding(sep)
+	return tableEncoder{&encs, len(sep)}, nil
+}
+
+// HexEncoder is like TextEncoder, but writes each term as two lowercase
+// hexadecimal digits.
+func HexEncoder(sep string) (Encoder, error) {
+	encs := buildHexEncoding(sep)
+	return tableEncoder{&encs, len(sep)}, nil
+}
+
+// PaddedEncoder is like TextEncoder, but writes each term as three decimal
+// digits, zero-padded like 007.
+func PaddedEncoder(sep string) (Encoder, error) {
+	encs := buildPaddedEncoding(sep)
+	return tableEncoder{&encs, len(sep)}, nil
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = builtinEncoders()
+)
+
+// builtinEncoders returns the Encoder of each format by the format's name,
+// with BinaryEncoder also as "binary", the name it had before the formats.
+func builtinEncoders() map[string]Encoder {
+	m := map[string]Encoder{"binary": BinaryEncoder}
+	for f, info := range formats {
+		if e := Format(f).Encoder(); e != nil {
+			m[info.name] = e
+		}
+	}
+	return m
+}
+
+// RegisterEncoder makes e available by name through LookupEncoder, typically
+// from an init function. The built-in encoders are registered under the names
+// of their formats, as Format.String gives them, and BinaryEncoder also as
+// "binary".
+// RegisterEncoder panics if e is nil or if the name is already registered.
+func RegisterEncoder(name string, e Encoder) {
+	if e == nil {
+		panic("debruijn: RegisterEncoder with nil encoder")
+	}
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	if _, ok := encoders[name]; ok {
+		panic(fmt.Sprintf("debruijn: encoder %q registered twice", name))
+	}
+	encoders[name] = e
+}
+
+// LookupEncoder returns the encoder registered with the given name, or nil if
+// there is none.
+func LookupEncoder(name string) Encoder {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	return encoders[name]
+}
+
+// EncoderNames returns the names of the registered encoders in sorted order.
+func EncoderNames() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,156 @@
+package debruijn
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestEncoderRoundTrip(t *testing.T) {
+	const n = 200000
+	want := prefix(t, n)
+	cases := []struct {
+		name string
+		e    Encoder
+		dec  func(io.Reader) io.Reader
+	}{
+		{"binary", BinaryEncoder, func(r io.Reader) io.Reader { return r }},
+		{"bin", BinaryEncoder, func(r io.Reader) io.Reader { return r }},
+		{"dot", DotEncoder, func(r io.Reader) io.Reader { return NewDecoder(r) }},
+		{"lines", LinesEncoder, func(r io.Reader) io.Reader { return NewDecoder(r) }},
+		{"hex", HexDotEncoder, func(r io.Reader) io.Reader { return NewHexDecoder(r) }},
+		{"hexlines", HexLinesEncoder, func(r io.Reader) io.Reader { return NewHexDecoder(r) }},
+		{"padded", PaddedDotEncoder, func(r io.Reader) io.Reader { return NewPaddedDecoder(r) }},
+		{"paddedlines", PaddedLinesEncoder, func(r io.Reader) io.Reader { return NewPaddedDecoder(r) }},
+	}
+	for _, c := range cases {
+		if LookupEncoder(c.name) != c.e {
+			t.Errorf("%s: registered encoder differs", c.name)
+		}
+		if f, err := ParseFormat(c.name); c.name != "binary" && (err != nil || f.Encoder() != c.e) {
+			t.Errorf("%s: format's encoder differs, with error %v", c.name, err)
+		}
+		var b bytes.Buffer
+		var g Generator
+		if _, err := Encode(&b, &g, c.e, n); err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(c.dec(&b))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: decodes to %d terms which differ from the %d encoded", c.name, len(got), len(want))
+		}
+	}
+}
+
+// listEncoder writes terms like a JSON array, to check the prologue and
+// epilogue.
+type listEncoder struct{}
+
+func (listEncoder) Prologue(dst []byte) []byte { return append(dst, '[') }
+func (listEncoder) Epilogue(dst []byte) []byte { return append(dst, ']') }
+
+func (listEncoder) EncodeTerm(dst []byte, term byte, first bool) []byte {
+	if !first {
+		dst = append(dst, ',')
+	}
+	return strconv.AppendUint(dst, uint64(term), 10)
+}
+
+func TestEncodeCustom(t *testing.T) {
+	g, _ := DeBruijn(2, 3)
+	var b bytes.Buffer
+	// Output in pieces concatenates to the same as all at once, with the
+	// prologue at the start and the epilogue only at the end.
+	for _, n := range []int64{3, 0, 4, -1} {
+		if _, err := Encode(&b, g, listEncoder{}, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := b.String(), "[0,0,0,1,0,1,1,1,0,0]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	// Registration lasts past the test, as with -count.
+	if LookupEncoder("test-list") == nil {
+		RegisterEncoder("test-list", listEncoder{})
+	}
+	if LookupEncoder("test-list") != (listEncoder{}) {
+		t.Error("registered encoder not found")
+	}
+	if !slices.Contains(EncoderNames(), "test-list") || !slices.IsSorted(EncoderNames()) {
+		t.Errorf("names %v", EncoderNames())
+	}
+	if LookupEncoder("nonexistent") != nil {
+		t.Error("found an unregistered encoder")
+	}
+	for _, c := range []struct {
+		name string
+		e    Encoder
+	}{{"dot", listEncoder{}}, {"nil", nil}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %s didn't panic", c.name)
+				}
+			}()
+			RegisterEncoder(c.name, c.e)
+		}()
+	}
+}
+
+func TestSeparatorEncoders(t *testing.T) {
+	cases := []struct {
+		name string
+		new  func(string) (Encoder, error)
+		want string
+	}{
+		{"text", TextEncoder, "0, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0"},
+		{"hex", HexEncoder, "00, 00, 00, 00, 01, 00, 00, 00, 02, 00, 00, 00, 03, 00, 00, 00, 04, 00, 00, 00"},
+		{"padded", PaddedEncoder, "000, 000, 000, 000, 001, 000, 000, 000, 002, 000, 000, 000, 003, 000, 000, 000, 004, 000, 000, 000"},
+	}
+	for _, c := range cases {
+		e, err := c.new(", ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		var g Generator
+		if _, err := Encode(&b, &g, e, 20); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, b.String(), c.want)
+		}
+	}
+}
+
+func TestEmptySeparator(t *testing.T) {
+	cases := []struct {
+		name string
+		new  func(string) (Encoder, error)
+		want string
+	}{
+		{"text", TextEncoder, "000010002000"},
+		{"hex", HexEncoder, "000000000100000002000000"},
+		{"padded", PaddedEncoder, "000000000000001000000000002000000000"},
+	}
+	for _, c := range cases {
+		e, err := c.new("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		var g Generator
+		n, err := Encode(&b, &g, e, 12)
+		if err != nil || n != int64(b.Len()) {
+			t.Errorf("%s: wrote %d bytes, counted %d, with error %v", c.name, b.Len(), n, err)
+		}
+		if b.String() != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, b.String(), c.want)
+		}
+	}
+}
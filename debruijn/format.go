@@ -0,0 +1,99 @@
+package debruijn
+
+import (
+	"fmt"
+	"io"
+)
+
+// formatInfo describes a Format: its name, the encodings of its terms, and
+// how to decode it, so that everything which depends on the format looks it
+// up in one place.
+type formatInfo struct {
+	name string
+	// encs holds the encoding of each term after a one-byte separator, or
+	// nil for Binary and IPv4.
+	encs *[256]string
+	// build returns the encodings of the terms after another separator, or
+	// is nil for Binary and IPv4.
+	build func(sep string) [256]string
+	// width is the number of digits of every term, or 0 if it varies.
+	width int
+	// decoder returns a Decoder for the format, or is nil for Binary and
+	// IPv4.
+	decoder func(io.Reader) *Decoder
+}
+
+// formats holds the description of each Format, indexed by it.
+var formats = [...]formatInfo{
+	Binary:      {name: "bin"},
+	Dot:         {name: "dot", encs: &encd, build: buildEncoding, decoder: NewDecoder},
+	Lines:       {name: "lines", encs: &encn, build: buildEncoding, decoder: NewDecoder},
+	IPv4:        {name: "quad"},
+	HexDot:      {name: "hex", encs: &hexd, build: buildHexEncoding, width: 2, decoder: NewHexDecoder},
+	HexLines:    {name: "hexlines", encs: &hexn, build: buildHexEncoding, width: 2, decoder: NewHexDecoder},
+	PaddedDot:   {name: "padded", encs: &padd, build: buildPaddedEncoding, width: 3, decoder: NewPaddedDecoder},
+	PaddedLines: {name: "paddedlines", encs: &padn, build: buildPaddedEncoding, width: 3, decoder: NewPaddedDecoder},
+}
+
+// String returns the name of the format, as ParseFormat accepts it.
+func (f Format) String() string {
+	if !f.valid() {
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+	return formats[f].name
+}
+
+// ParseFormat returns the format with the given name: "bin", "dot", "lines",
+// "quad", "hex", "hexlines", "padded", or "paddedlines", in the order of
+// FormatNames.
+func ParseFormat(name string) (Format, error) {
+	for f, info := range formats {
+		if info.name == name {
+			return Format(f), nil
+		}
+	}
+	return 0, fmt.Errorf("debruijn: unknown format %q", name)
+}
+
+// FormatNames returns the names of the formats in the order of their values.
+func FormatNames() []string {
+	names := make([]string, len(formats))
+	for f, info := range formats {
+		names[f] = info.name
+	}
+	return names
+}
+
+// Encoder returns an Encoder which writes terms in the format, or nil for
+// IPv4, whose addresses span several terms.
+func (f Format) Encoder() Encoder {
+	if f == Binary {
+		return BinaryEncoder
+	}
+	if encs := f.info().encs; encs != nil {
+		return tableEncoder{encs, 1}
+	}
+	return nil
+}
+
+// NewDecoder returns a Decoder reading terms in the format from r, or nil
+// for Binary, whose bytes are already the terms, and for IPv4.
+func (f Format) NewDecoder(r io.Reader) *Decoder {
+	if d := f.info().decoder; d != nil {
+		return d(r)
+	}
+	return nil
+}
+
+// valid reports whether f is one of the formats.
+func (f Format) valid() bool {
+	return f >= 0 && int(f) < len(formats)
+}
+
+// info returns the description of f, which is empty if f isn't valid.
+func (f Format) info() formatInfo {
+	if !f.valid() {
+		return formatInfo{}
+	}
+	return formats[f]
+}
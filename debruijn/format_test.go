@@ -0,0 +1,63 @@
+package debruijn
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+	names := FormatNames()
+	want := []string{"bin", "dot", "lines", "quad", "hex", "hexlines", "padded", "paddedlines"}
+	if !slices.Equal(names, want) {
+		t.Errorf("format names are %q, want %q", names, want)
+	}
+	var bin bytes.Buffer
+	if _, err := Write(&bin, Options{Order: 2, Limit: 1 << 16}); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range names {
+		f, err := ParseFormat(name)
+		if err != nil || f != Format(i) || f.String() != name {
+			t.Errorf("%s: parses to %v with error %v", name, f, err)
+		}
+		// Each format has a size, an encoder but for IPv4, and a decoder
+		// but for Binary and IPv4, all agreeing with Write.
+		order := 2
+		if f == IPv4 {
+			order = 4
+		}
+		var b bytes.Buffer
+		if _, err := Write(&b, Options{Format: f, Order: order, Limit: 1 << 16}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := Size(f, order); err != nil {
+			t.Errorf("%s: no size: %v", name, err)
+		}
+		if e := f.Encoder(); (e == nil) != (f == IPv4) {
+			t.Errorf("%s: encoder is %v", name, e)
+		} else if e != nil {
+			var enc bytes.Buffer
+			g, _ := New(order)
+			if _, err := Encode(&enc, g, e, 1<<16); err != nil || !bytes.Equal(enc.Bytes(), b.Bytes()) {
+				t.Errorf("%s: encoder differs from Write, with error %v", name, err)
+			}
+		}
+		d := f.NewDecoder(bytes.NewReader(b.Bytes()))
+		if (d == nil) != (f == Binary || f == IPv4) {
+			t.Errorf("%s: decoder is %v", name, d)
+		} else if d != nil {
+			got, err := io.ReadAll(d)
+			if err != nil || !bytes.Equal(got, bin.Bytes()) {
+				t.Errorf("%s: decodes to %d terms which differ from the binary, with error %v", name, len(got), err)
+			}
+		}
+	}
+	if _, err := ParseFormat("binary"); err == nil {
+		t.Error("unknown format parsed")
+	}
+	if s := Format(99).String(); s != "Format(99)" {
+		t.Errorf("invalid format is %q", s)
+	}
+}
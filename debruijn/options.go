@@ -0,0 +1,359 @@
+package debruijn
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+)
+
+// Options describe the output of Write: which sequence to generate, which
+// part of it, and how to format it. The zero Options describe the complete
+// binary encoding of B(256, 4).
+type Options struct {
+	// Alphabet is the number of distinct terms, or 0 for 256. Alphabets
+	// above 256 require the Binary format, in which each term is two bytes
+	// as Wide writes them, and none of the other options but Order and
+	// BufferSize.
+	Alphabet int
+	// Order is the order of the sequence, or 0 for 4.
+	Order int
+	// ByteOrder is the order of the two bytes of each term for alphabets
+	// above 256, or nil for big-endian. Single-byte terms have no order, so
+	// it has no effect on smaller alphabets.
+	ByteOrder binary.ByteOrder
+	// Format is the encoding of the output.
+	Format Format
+	// Separator, if not empty, separates terms in the text formats instead
+	// of the format's own separator. For no separator at all, use an
+	// Encoder from TextEncoder or HexEncoder with an empty one.
+	Separator string
+	// Encoder, if not nil, formats the terms instead of Format and
+	// Separator.
+	Encoder Encoder
+	// Wrap, if positive, ends a line after every Wrap terms in the Dot,
+	// HexDot, and PaddedDot formats, with a newline after the separator, so
+	// that removing the newlines gives the output without Wrap. It can't be
+	// used with Separator or Encoder.
+	Wrap int64
+	// CIDR writes each address in the IPv4 format in CIDR notation with
+	// the prefix length Bits, as WriteCIDR does.
+	CIDR bool
+	Bits int
+	// Exclude lists prefixes whose addresses the IPv4 format omits.
+	Exclude []netip.Prefix
+	// Within, if valid, writes only the addresses of the IPv4 format in
+	// that prefix, in the order AddrsIn gives. It can't be used with Skip
+	// or the options which configure the generator.
+	Within netip.Prefix
+	// Shuffle writes the addresses of the IPv4 format in the order Shuffled
+	// gives for Seed instead of the order of the sequence. Skip is then an
+	// index into that order. Like Within, it can't be used with the options
+	// which configure the generator.
+	Shuffle bool
+	Seed    uint64
+	// Rotate begins the cycle at this offset, as Generator.Rotate does.
+	Rotate uint64
+	// Reverse generates the sequence in reverse, as Generator.Reverse does.
+	// It requires B(256, 4) and can't be used with Rotate.
+	Reverse bool
+	// Mask is XORed with each term, as Generator.Mask does.
+	Mask byte
+	// Permutation, if not nil, substitutes each term after the mask, as
+	// Generator.WithPermutation does.
+	Permutation *[256]byte
+	// Skip begins the output at the term with this index, or with the IPv4
+	// format, the window at this offset. No separator precedes the first
+	// term written, as if the sequence began there.
+	Skip uint64
+	// Limit, if positive, stops the output after this many terms, or with
+	// the IPv4 format, this many addresses written.
+	Limit int64
+	// BufferSize, if positive, is the size of the buffer through which
+	// Write writes to w. Otherwise, Write buffers as the function it uses
+	// for the format does.
+	BufferSize int
+	// Compression compresses the output Write writes at Level, as
+	// Compression.NewWriter does, after the buffer. The Level must be 0 for
+	// NoCompression.
+	Compression Compression
+	Level       int
+}
+
+// Write writes the output described by opts to w. It returns the number of
+// bytes written and the first error encountered, which is that of Validate if
+// the options conflict.
+func Write(w io.Writer, opts Options) (int64, error) {
+	if err := opts.Validate(); err != nil {
+		return 0, err
+	}
+	if opts.Compression != NoCompression {
+		return opts.writeCompressed(w)
+	}
+	if opts.Alphabet > 256 {
+		return opts.writeWide(w)
+	}
+	g, err := opts.Generator()
+	if err != nil {
+		return 0, err
+	}
+	return opts.WriteFrom(w, g)
+}
+
+// Validate returns an error describing the first conflict it finds among the
+// options, such as a Separator with the Binary format or an Order below one,
+// or nil if Write can write the output they describe. It checks the options
+// against each other rather than generating anything, so a sequence which
+// doesn't exist, such as one too large to count, is Generator's to report.
+func (o Options) Validate() error {
+	k := o.Alphabet
+	if k == 0 {
+		k = 256
+	}
+	n := o.Order
+	if n == 0 {
+		n = 4
+	}
+	// The options which apply only to addresses.
+	addrs := o.CIDR || len(o.Exclude) > 0 || o.Within.IsValid() || o.Shuffle
+	// The options which configure the generator, which Within and Shuffle
+	// replace.
+	gen := o.Rotate != 0 || o.Reverse || o.Mask != 0 || o.Permutation != nil
+	switch {
+	case k < 1 || k > 65536:
+		return fmt.Errorf("%w: the alphabet must have between 1 and 65536 terms, got %d", ErrAlphabet, k)
+	case n < 1:
+		return fmt.Errorf("%w: the order must be at least 1, got %d", ErrOrder, n)
+	case o.BufferSize < 0:
+		return errOptionsBuffer
+	case !o.Format.valid():
+		return errOptionsFormat
+	case k > 256:
+		if o.Format != Binary || o.Encoder != nil || o.Separator != "" || o.Wrap > 0 || addrs || gen ||
+			o.Skip != 0 || o.Limit > 0 {
+			return errOptionsWide
+		}
+	case o.Encoder != nil && o.Separator != "":
+		return errOptionsEncoderSep
+	case o.Separator != "" && o.Format.info().build == nil:
+		return errOptionsSep
+	case o.Wrap > 0 && (o.Encoder != nil || o.Separator != "" || o.Format != Dot && o.Format != HexDot && o.Format != PaddedDot):
+		return errOptionsWrap
+	case addrs && (o.Encoder != nil || o.Format != IPv4):
+		return errOptionsAddrs
+	case o.Format == IPv4 && o.Encoder == nil && (k != 256 || n != 4):
+		return errIPv4Order
+	case o.CIDR && (o.Bits < 0 || o.Bits > 32):
+		return errCIDRBits
+	case o.Shuffle && o.Within.IsValid():
+		return errOptionsShuffle
+	case (o.Shuffle || o.Within.IsValid()) && gen:
+		return errOptionsAddrsGen
+	case o.Within.IsValid() && o.Skip != 0:
+		return errOptionsWithinSkip
+	case o.Reverse && (k != 256 || n != 4):
+		return errReverseOrder
+	case o.Reverse && o.Rotate != 0:
+		return errOptionsReverse
+	}
+	return o.Compression.check(o.Level)
+}
+
+// Generator returns a Generator configured by opts and positioned at the
+// start of the output Write would write. It returns an error if the options
+// describe a sequence other than B(k, n) for k up to 256, or a rotation or
+// reversal which the sequence does not support.
+func (o Options) Generator() (*Generator, error) {
+	k := o.Alphabet
+	if k == 0 {
+		k = 256
+	}
+	n := o.Order
+	if n == 0 {
+		n = 4
+	}
+	g, err := DeBruijn(k, n)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.Rotate(o.Rotate); err != nil {
+		return nil, err
+	}
+	if o.Reverse {
+		if err := g.Reverse(); err != nil {
+			return nil, err
+		}
+	}
+	g.Mask(o.Mask)
+	if o.Permutation != nil {
+		if err := g.WithPermutation(*o.Permutation); err != nil {
+			return nil, err
+		}
+	}
+	g.Skip(o.Skip)
+	return g, nil
+}
+
+// WriteFrom is like Write, but writes the remaining terms of g, typically
+// from o.Generator, in place of those of the sequence o describes. It uses
+// only the options which format and limit the output. No separator precedes
+// the first term written if g is at the start of its sequence or if o.Skip is
+// not zero.
+func (o Options) WriteFrom(w io.Writer, g *Generator) (int64, error) {
+	var bw *bufio.Writer
+	if o.BufferSize > 0 {
+		bw = bufio.NewWriterSize(w, o.BufferSize)
+		w = bw
+	}
+	n, err := o.write(w, g)
+	if bw != nil {
+		return flushed(bw, false, n, err)
+	}
+	return n, err
+}
+
+// write writes the remaining output of g to w in the format of o.
+func (o Options) write(w io.Writer, g *Generator) (int64, error) {
+	limit := o.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	first := g.first() || o.Skip != 0
+	e := o.Encoder
+	if e == nil && o.Separator != "" {
+		build := o.Format.info().build
+		if build == nil {
+			return 0, errOptionsSep
+		}
+		encs := build(o.Separator)
+		e = tableEncoder{&encs, len(o.Separator)}
+	}
+	if o.Wrap > 0 {
+		switch {
+		case e != nil:
+		case o.Format == Dot:
+			return writeWrapped(w, g, &encd, o.Wrap, limit, first)
+		case o.Format == HexDot:
+			return writeWrapped(w, g, &hexd, o.Wrap, limit, first)
+		case o.Format == PaddedDot:
+			return writeWrapped(w, g, &padd, o.Wrap, limit, first)
+		}
+		return 0, errOptionsWrap
+	}
+	if e != nil {
+		return encode(w, g, e, limit, first)
+	}
+	switch o.Format {
+	case Binary:
+		if limit < 0 {
+			return g.WriteTo(w)
+		}
+		return WriteBinary(w, g, limit)
+	case IPv4:
+		if !g.standard() {
+			return 0, errIPv4Order
+		}
+		addrs := g.Addrs()
+		switch {
+		case o.Shuffle:
+			addrs = shuffled(o.Seed, o.Skip)
+		case o.Within.IsValid():
+			addrs = AddrsIn(o.Within)
+		}
+		if len(o.Exclude) > 0 {
+			all := addrs
+			addrs = func(yield func(netip.Addr) bool) {
+				for a := range all {
+					if !excluded(a, o.Exclude) && !yield(a) {
+						return
+					}
+				}
+			}
+		}
+		if o.CIDR {
+			return WriteCIDR(w, addrs, o.Bits, limit)
+		}
+		return WriteAddrs(w, addrs, limit)
+	}
+	if encs := o.Format.info().encs; encs != nil {
+		return writeTerms(w, g, encs, limit, first)
+	}
+	return 0, errOptionsFormat
+}
+
+// writeWide writes the output of o for an alphabet above 256, which Validate
+// has checked.
+func (o Options) writeWide(w io.Writer) (int64, error) {
+	n := o.Order
+	if n == 0 {
+		n = 4
+	}
+	g, err := NewWide(o.Alphabet, n)
+	if err != nil {
+		return 0, err
+	}
+	if o.ByteOrder != nil {
+		g.WithByteOrder(o.ByteOrder)
+	}
+	if o.BufferSize > 0 {
+		bw := bufio.NewWriterSize(w, o.BufferSize)
+		c, err := g.WriteTo(bw)
+		return flushed(bw, false, c, err)
+	}
+	return g.WriteTo(w)
+}
+
+// writeCompressed writes the output of o to w through its compressor, which
+// Validate has checked, and returns the number of compressed bytes written.
+func (o Options) writeCompressed(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	zw, err := o.Compression.NewWriter(cw, o.Level)
+	if err != nil {
+		return 0, err
+	}
+	o.Compression, o.Level = NoCompression, 0
+	_, err = Write(zw, o)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	return cw.n, err
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// excluded reports whether addr is in any of prefixes.
+func excluded(addr netip.Addr, prefixes []netip.Prefix) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	errOptionsSep     = errors.New("debruijn: a separator requires a text format")
+	errOptionsFormat  = errors.New("debruijn: invalid format")
+	errOptionsWrap    = errors.New("debruijn: wrapping requires the Dot, HexDot, or PaddedDot format")
+	errOptionsWide    = errors.New("debruijn: alphabets above 256 support only the binary format over the entire sequence")
+	errOptionsBuffer  = errors.New("debruijn: buffer size must not be negative")
+	errOptionsAddrs   = errors.New("debruijn: CIDR, Exclude, Within, and Shuffle require the IPv4 format")
+	errOptionsShuffle = errors.New("debruijn: Shuffle and Within cannot be used together")
+
+	errOptionsEncoderSep = errors.New("debruijn: an Encoder cannot be used with a Separator")
+	errOptionsAddrsGen   = errors.New("debruijn: Shuffle and Within cannot be used with Rotate, Reverse, Mask, or Permutation")
+	errOptionsWithinSkip = errors.New("debruijn: Within cannot be used with Skip")
+	errOptionsReverse    = errors.New("debruijn: Reverse cannot be used with Rotate")
+)
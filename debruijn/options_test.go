@@ -0,0 +1,357 @@
+package debruijn
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var perm [256]byte
+	within := netip.MustParsePrefix("10.0.0.0/8")
+	cases := []struct {
+		name string
+		opts Options
+		err  error
+	}{
+		{"alphabet", Options{Alphabet: 65537}, ErrAlphabet},
+		{"negative alphabet", Options{Alphabet: -1}, ErrAlphabet},
+		{"order", Options{Order: -1}, ErrOrder},
+		{"buffer", Options{BufferSize: -1}, errOptionsBuffer},
+		{"format", Options{Format: PaddedLines + 1}, errOptionsFormat},
+		{"wide format", Options{Alphabet: 300, Format: Dot}, errOptionsWide},
+		{"wide mask", Options{Alphabet: 300, Mask: 1}, errOptionsWide},
+		{"wide permutation", Options{Alphabet: 300, Permutation: &perm}, errOptionsWide},
+		{"wide skip", Options{Alphabet: 300, Skip: 1}, errOptionsWide},
+		{"wide limit", Options{Alphabet: 300, Limit: 1}, errOptionsWide},
+		{"encoder separator", Options{Encoder: Dot.Encoder(), Separator: ","}, errOptionsEncoderSep},
+		{"binary separator", Options{Separator: ","}, errOptionsSep},
+		{"ipv4 separator", Options{Format: IPv4, Separator: ","}, errOptionsSep},
+		{"wrap format", Options{Format: Lines, Wrap: 10}, errOptionsWrap},
+		{"wrap separator", Options{Format: Dot, Separator: ",", Wrap: 10}, errOptionsWrap},
+		{"wrap encoder", Options{Encoder: Dot.Encoder(), Wrap: 10}, errOptionsWrap},
+		{"cidr format", Options{Format: Dot, CIDR: true, Bits: 24}, errOptionsAddrs},
+		{"exclude format", Options{Exclude: []netip.Prefix{within}}, errOptionsAddrs},
+		{"ipv4 order", Options{Format: IPv4, Order: 3}, errIPv4Order},
+		{"ipv4 alphabet", Options{Format: IPv4, Alphabet: 16}, errIPv4Order},
+		{"cidr bits", Options{Format: IPv4, CIDR: true, Bits: 33}, errCIDRBits},
+		{"shuffle within", Options{Format: IPv4, Shuffle: true, Within: within}, errOptionsShuffle},
+		{"shuffle rotate", Options{Format: IPv4, Shuffle: true, Rotate: 1}, errOptionsAddrsGen},
+		{"within mask", Options{Format: IPv4, Within: within, Mask: 1}, errOptionsAddrsGen},
+		{"within skip", Options{Format: IPv4, Within: within, Skip: 1}, errOptionsWithinSkip},
+		{"reverse order", Options{Order: 3, Reverse: true}, errReverseOrder},
+		{"reverse rotate", Options{Reverse: true, Rotate: 1}, errOptionsReverse},
+		{"compression", Options{Compression: Zstd + 1}, errCompression},
+		{"level", Options{Level: 1}, errCompressionLevel},
+	}
+	for _, c := range cases {
+		if err := c.opts.Validate(); !errors.Is(err, c.err) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.err)
+		}
+	}
+	if err := (Options{Compression: Gzip, Level: 10}).Validate(); err == nil {
+		t.Error("gzip level 10 is valid")
+	}
+	if err := (Options{Compression: Zstd, Level: 22}).Validate(); err != nil {
+		t.Errorf("zstd level 22: %v", err)
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	var perm [256]byte
+	valid := []Options{
+		{},
+		{Alphabet: 65536, Order: 2},
+		{Alphabet: 1, Order: 1, Format: Lines},
+		{Format: HexDot, Separator: "\r\n", BufferSize: 1},
+		{Format: PaddedDot, Wrap: 8},
+		{Encoder: DotEncoder, Skip: 10, Limit: 10},
+		{Format: IPv4, CIDR: true, Bits: 32, Exclude: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}},
+		{Format: IPv4, Shuffle: true, Seed: 1},
+		{Rotate: 1, Mask: 0xff, Permutation: &perm},
+		{Reverse: true, Skip: 1},
+		{Compression: Gzip, Level: 9},
+	}
+	for _, o := range valid {
+		if err := o.Validate(); err != nil {
+			t.Errorf("%+v: %v", o, err)
+		}
+	}
+}
+
+func TestOptionsGenerator(t *testing.T) {
+	perm := Permutation(7)
+	cases := []struct {
+		name string
+		opts Options
+		// gen configures a Generator of B(256, 4) as the options do.
+		gen func(g *Generator)
+	}{
+		{"zero", Options{}, func(g *Generator) {}},
+		{"skip", Options{Skip: 1 << 30}, func(g *Generator) { g.Skip(1 << 30) }},
+		{"rotate", Options{Rotate: 12345, Skip: 10}, func(g *Generator) { g.Rotate(12345); g.Skip(10) }},
+		{"reverse", Options{Reverse: true}, func(g *Generator) { g.Reverse() }},
+		{"mask", Options{Mask: 0x5a}, func(g *Generator) { g.Mask(0x5a) }},
+		{"permutation", Options{Permutation: &perm, Skip: 3}, func(g *Generator) { g.WithPermutation(perm); g.Skip(3) }},
+	}
+	for _, c := range cases {
+		g, err := c.opts.Generator()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var want Generator
+		c.gen(&want)
+		if g.Offset() != want.Offset() {
+			t.Errorf("%s: at offset %d, want %d", c.name, g.Offset(), want.Offset())
+		}
+		for i := range 1000 {
+			a, _ := g.Next()
+			b, _ := want.Next()
+			if a != b {
+				t.Fatalf("%s: term %d is %d, want %d", c.name, i, a, b)
+			}
+		}
+	}
+	// Write and WriteFrom with the options' Generator agree, and skipping
+	// begins without a separator.
+	opts := Options{Format: Dot, Skip: 4, Limit: 5}
+	g, _ := opts.Generator()
+	var a, b bytes.Buffer
+	if _, err := Write(&a, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.WriteFrom(&b, g); err != nil {
+		t.Fatal(err)
+	}
+	if a.String() != "1.0.0.0.2" || b.String() != a.String() {
+		t.Errorf("Write gave %q and WriteFrom %q, want %q", a.String(), b.String(), "1.0.0.0.2")
+	}
+	errs := []struct {
+		opts Options
+		err  error
+	}{
+		{Options{Alphabet: 300}, ErrAlphabet},
+		{Options{Order: -1}, ErrOrder},
+		{Options{Order: 3, Reverse: true}, errReverseOrder},
+		{Options{Reverse: true, Rotate: 1}, errReverseStarted},
+	}
+	for _, c := range errs {
+		if _, err := c.opts.Generator(); !errors.Is(err, c.err) {
+			t.Errorf("%+v: got error %v, want %v", c.opts, err, c.err)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	// The first terms are 0 0 0 0 1 0 0 0 2, the Lyndon words 0, 0001, 0002.
+	cases := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"binary", Options{Limit: 6}, "\x00\x00\x00\x00\x01\x00"},
+		{"dot", Options{Format: Dot, Limit: 6}, "0.0.0.0.1.0"},
+		{"lines", Options{Format: Lines, Limit: 6}, "0\n0\n0\n0\n1\n0"},
+		{"ipv4", Options{Format: IPv4, Limit: 3}, "0.0.0.0\n0.0.0.1\n0.0.1.0\n"},
+		{"hex dot", Options{Format: HexDot, Limit: 6}, "00.00.00.00.01.00"},
+		{"hex lines", Options{Format: HexLines, Limit: 3}, "00\n00\n00"},
+		{"padded dot", Options{Format: PaddedDot, Limit: 3}, "000.000.000"},
+		{"padded lines", Options{Format: PaddedLines, Limit: 3}, "000\n000\n000"},
+		{"separator", Options{Format: Dot, Separator: ", ", Limit: 6}, "0, 0, 0, 0, 1, 0"},
+		{"hex separator", Options{Format: HexDot, Separator: ":", Limit: 4}, "00:00:00:00"},
+		{"encoder", Options{Encoder: PaddedDotEncoder, Limit: 3}, "000.000.000"},
+		{"wrap", Options{Format: Dot, Wrap: 2, Limit: 6}, "0.0.\n0.0.\n1.0"},
+		{"skip", Options{Format: Dot, Skip: 4, Limit: 3}, "1.0.0"},
+		{"mask", Options{Mask: 0xff, Limit: 6}, "\xff\xff\xff\xff\xfe\xff"},
+		{"rotate", Options{Format: Dot, Rotate: 1, Limit: 6}, "0.0.0.1.0.0"},
+		{"reverse", Options{Format: Dot, Reverse: true, Limit: 6}, "0.0.0.255.255.255"},
+		{"order 1", Options{Format: Dot, Order: 1, Limit: 3}, "0.1.2"},
+		{"alphabet", Options{Format: Dot, Alphabet: 2, Order: 3}, "0.0.0.1.0.1.1.1.0.0"},
+		{"cidr", Options{Format: IPv4, CIDR: true, Bits: 24, Limit: 2}, "0.0.0.0/24\n0.0.0.1/24\n"},
+		{"within", Options{Format: IPv4, Within: netip.MustParsePrefix("10.0.0.0/8"), Limit: 2}, "10.0.0.0\n10.0.0.1\n"},
+		{"buffer", Options{Format: Dot, BufferSize: 1, Limit: 6}, "0.0.0.0.1.0"},
+		{"b(1, 1)", Options{Format: Dot, Alphabet: 1, Order: 1}, "0"},
+	}
+	for _, c := range cases {
+		var b bytes.Buffer
+		n, err := Write(&b, c.opts)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if b.String() != c.want || n != int64(b.Len()) {
+			t.Errorf("%s: wrote %q, counted %d; want %q", c.name, b.String(), n, c.want)
+		}
+	}
+	if _, err := Write(io.Discard, Options{Order: -1}); !errors.Is(err, ErrOrder) {
+		t.Errorf("invalid options: got error %v, want %v", err, ErrOrder)
+	}
+}
+
+func TestWriteWrapped(t *testing.T) {
+	const n = 100000
+	for _, f := range []Format{Dot, HexDot, PaddedDot} {
+		var want bytes.Buffer
+		if _, err := Write(&want, Options{Format: f, Limit: n}); err != nil {
+			t.Fatal(err)
+		}
+		for _, wrap := range []int64{1, 3, 7, 1000, n, 2 * n} {
+			var b bytes.Buffer
+			c, err := Write(&b, Options{Format: f, Wrap: wrap, Limit: n})
+			if err != nil {
+				t.Fatalf("%v wrap %d: %v", f, wrap, err)
+			}
+			if c != int64(b.Len()) {
+				t.Errorf("%v wrap %d: wrote %d bytes, counted %d", f, wrap, b.Len(), c)
+			}
+			lines := strings.Split(b.String(), "\n")
+			if want := (n + wrap - 1) / wrap; int64(len(lines)) != want {
+				t.Errorf("%v wrap %d: %d lines, want %d", f, wrap, len(lines), want)
+			}
+			// Each full line ends with the separator after its last term.
+			for i, l := range lines[:len(lines)-1] {
+				if k := int64(strings.Count(l, ".")); k != wrap {
+					t.Fatalf("%v wrap %d: line %d has %d terms", f, wrap, i, k)
+				}
+			}
+			if got := strings.ReplaceAll(b.String(), "\n", ""); got != want.String() {
+				t.Errorf("%v wrap %d: without newlines, the output differs", f, wrap)
+			}
+		}
+	}
+}
+
+func TestWriteCompressed(t *testing.T) {
+	opts := Options{Format: HexDot, Limit: 100000}
+	var want bytes.Buffer
+	if _, err := Write(&want, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []Compression{Gzip, Zstd} {
+		opts.Compression = c
+		var b bytes.Buffer
+		n, err := Write(&b, opts)
+		if err != nil {
+			t.Fatalf("compression %d: %v", c, err)
+		}
+		if n != int64(b.Len()) {
+			t.Errorf("compression %d: wrote %d bytes, counted %d", c, b.Len(), n)
+		}
+		r, err := c.NewReader(&b)
+		if err != nil {
+			t.Fatalf("compression %d: %v", c, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("compression %d: %v", c, err)
+		}
+		r.Close()
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("compression %d: decompressed %d bytes which differ from the %d uncompressed", c, len(got), want.Len())
+		}
+	}
+}
+
+func TestExclude(t *testing.T) {
+	// The /16 holds the windows which wrap around the end of the sequence,
+	// 255.255.0.0 and 255.255.255.0 among them.
+	within := netip.MustParsePrefix("255.255.0.0/16")
+	excl := []netip.Prefix{netip.MustParsePrefix("255.255.0.0/24"), netip.MustParsePrefix("255.255.255.0/25")}
+	var b bytes.Buffer
+	if _, err := Write(&b, Options{Format: IPv4, Within: within, Exclude: excl}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 1<<16-256-128 {
+		t.Errorf("%d addresses, want %d", len(lines), 1<<16-256-128)
+	}
+	for _, l := range lines {
+		addr := netip.MustParseAddr(l)
+		if excl[0].Contains(addr) || excl[1].Contains(addr) {
+			t.Fatalf("excluded address %v", addr)
+		}
+	}
+	// Excluding the first octet 0, which begins most of the early windows,
+	// leaves the others in order.
+	zero := netip.MustParsePrefix("0.0.0.0/8")
+	var want []string
+	for addr := range Addrs() {
+		if !zero.Contains(addr) {
+			want = append(want, addr.String())
+		}
+		if len(want) == 10000 {
+			break
+		}
+	}
+	b.Reset()
+	if _, err := Write(&b, Options{Format: IPv4, Exclude: []netip.Prefix{zero}, Limit: 10000}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(want, "\n") + "\n"; b.String() != got {
+		t.Errorf("excluding 0.0.0.0/8 gave %.40q..., want %.40q...", b.String(), got)
+	}
+}
+
+func TestExcludeAll(t *testing.T) {
+	if testing.Short() {
+		t.Skip("checks every window of B(256, 4)")
+	}
+	n, err := Write(io.Discard, Options{Format: IPv4, Exclude: []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")}})
+	if err != nil || n != 0 {
+		t.Errorf("excluding 0.0.0.0/0 wrote %d bytes with error %v", n, err)
+	}
+}
+
+// fullWriter takes up to limit bytes, writing part of the write which
+// reaches it, and then fails with ENOSPC as a full disk does.
+type fullWriter struct {
+	n, limit int64
+}
+
+func (w *fullWriter) Write(p []byte) (int, error) {
+	c := int(min(int64(len(p)), w.limit-w.n))
+	w.n += int64(c)
+	if c < len(p) {
+		return c, syscall.ENOSPC
+	}
+	return c, nil
+}
+
+func (w *fullWriter) WriteAt(p []byte, off int64) (int, error) {
+	return w.Write(p)
+}
+
+func TestWriteErrors(t *testing.T) {
+	const limit = 100000
+	for f := Binary; f <= PaddedLines; f++ {
+		w := &fullWriter{limit: limit}
+		n, err := Write(w, Options{Format: f})
+		if !errors.Is(err, syscall.ENOSPC) || n != limit {
+			t.Errorf("%v: counted %d bytes with error %v, want %d and ENOSPC", f, n, err, limit)
+		}
+	}
+	// The binary writers say where the write failed.
+	var g Generator
+	_, err := g.WriteTo(&fullWriter{limit: limit})
+	if !errors.Is(err, syscall.ENOSPC) || !strings.Contains(err.Error(), "offset 100000:") {
+		t.Errorf("WriteTo: got error %v, want ENOSPC at offset %d", err, limit)
+	}
+	g = Generator{}
+	g.Skip(1000)
+	_, err = g.WriteTo(&fullWriter{limit: limit})
+	if !strings.Contains(fmt.Sprint(err), "offset 101000:") {
+		t.Errorf("WriteTo from 1000: got error %v, want ENOSPC at offset %d", err, 1000+limit)
+	}
+	w, _ := NewWide(300, 2)
+	if _, err := w.WriteTo(&fullWriter{limit: limit}); !errors.Is(err, syscall.ENOSPC) || !strings.Contains(err.Error(), "offset 100000:") {
+		t.Errorf("Wide.WriteTo: got error %v, want ENOSPC at offset %d", err, limit)
+	}
+	if _, err := WriteParallel(&fullWriter{limit: limit}, 0, 4); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("WriteParallel: got error %v, want ENOSPC", err)
+	}
+}
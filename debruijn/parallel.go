@@ -0,0 +1,83 @@
+package debruijn
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// chunkSize is the length of each contiguous range of the sequence that a
+// worker in WriteParallel generates at once.
+const chunkSize = 4 << 20
+
+// WriteParallel writes the binary encoding of B(256, 4) from offset off
+// through the end of the sequence to w, writing the term at each offset o at
+// position o - off. The sequence is divided into contiguous chunks which the
+// given number of goroutines generate and write independently, each jumping
+// directly to the start of its chunk. If w is a file, it is best to size it
+// appropriately beforehand.
+//
+// WriteParallel returns the total number of bytes written. If any write
+// fails, the workers stop promptly, and the first error is returned, wrapped
+// with the offset in the sequence at which it occurred.
+func WriteParallel(w io.WriterAt, off int64, workers int) (int64, error) {
+	return WriteParallelContext(context.Background(), w, off, workers)
+}
+
+// WriteParallelContext is like WriteParallel, but stops once ctx is done,
+// returning the number of bytes written and the context's error. The workers
+// check ctx only between chunks, so each finishes the chunk it is writing,
+// and every goroutine has exited when WriteParallelContext returns.
+func WriteParallelContext(ctx context.Context, w io.WriterAt, off int64, workers int) (int64, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("debruijn: negative offset %d", off)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		next    atomic.Int64
+		written atomic.Int64
+		failed  atomic.Bool
+		once    sync.Once
+		err     error
+		wg      sync.WaitGroup
+	)
+	next.Store(off)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var g Generator
+			buf := make([]byte, chunkSize)
+			for !failed.Load() {
+				if cerr := ctx.Err(); cerr != nil {
+					once.Do(func() {
+						err = cerr
+						failed.Store(true)
+					})
+					return
+				}
+				start := next.Add(chunkSize) - chunkSize
+				if start >= seqLen {
+					return
+				}
+				g.seek(uint64(start))
+				k := g.fill(buf)
+				c, werr := w.WriteAt(buf[:k], start-off)
+				written.Add(int64(c))
+				if werr != nil {
+					once.Do(func() {
+						err = fmt.Errorf("debruijn: writing at offset %d: %w", start+int64(c), werr)
+						failed.Store(true)
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return written.Load(), err
+}
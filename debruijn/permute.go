@@ -0,0 +1,68 @@
+package debruijn
+
+import "errors"
+
+// WithPermutation makes g replace each term t it emits with p[t]. When p is a
+// permutation of the bytes, the result is still a de Bruijn sequence
+// containing every window, in a different order; WithPermutation returns an
+// error otherwise. If g also has a mask, the mask applies first. Like the
+// mask, the permutation applies to the terms g emits after the call, but not
+// to the words Words yields.
+func (g *Generator) WithPermutation(p [256]byte) error {
+	if !isPermutation(&p) {
+		return errPermutation
+	}
+	g.perm = &p
+	return nil
+}
+
+// isPermutation reports whether p maps every byte to a distinct byte.
+func isPermutation(p *[256]byte) bool {
+	var seen [256]bool
+	for _, b := range p {
+		if seen[b] {
+			return false
+		}
+		seen[b] = true
+	}
+	return true
+}
+
+var errPermutation = errors.New("debruijn: substitution is not a permutation")
+
+// Permutation returns a pseudorandom permutation of the bytes derived from
+// seed, suitable for WithPermutation. The same seed always gives the same
+// permutation, across platforms and versions of this package. It is not
+// cryptographically secure.
+func Permutation(seed uint64) [256]byte {
+	var p [256]byte
+	for i := range p {
+		p[i] = byte(i)
+	}
+	// Fisher-Yates with SplitMix64 as the source. The modulo is biased by at
+	// most 2^-56, which is no concern for scrambling output.
+	s := seed
+	for i := len(p) - 1; i > 0; i-- {
+		j := splitmix(&s) % uint64(i+1)
+		p[i], p[j] = p[j], p[i]
+	}
+	return p
+}
+
+// splitmix advances the SplitMix64 state s and returns its next output.
+func splitmix(s *uint64) uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := *s
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// sub applies g's mask and permutation to a term.
+func (g *Generator) sub(term byte) byte {
+	term ^= g.mask
+	if g.perm != nil {
+		term = g.perm[term]
+	}
+	return term
+}
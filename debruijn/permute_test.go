@@ -0,0 +1,69 @@
+package debruijn
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestPermutation(t *testing.T) {
+	for seed := range uint64(1000) {
+		p := Permutation(seed)
+		if !isPermutation(&p) {
+			t.Fatalf("seed %d: %v is not a permutation", seed, p)
+		}
+		if Permutation(seed) != p {
+			t.Fatalf("seed %d: permutations differ", seed)
+		}
+		if seed > 0 && Permutation(seed-1) == p {
+			t.Errorf("seeds %d and %d give the same permutation", seed-1, seed)
+		}
+	}
+	// The permutations are the same everywhere, so pin a few.
+	cases := []struct {
+		seed uint64
+		head [8]byte
+	}{
+		{0, [8]byte{99, 179, 124, 78, 196, 203, 221, 113}},
+		{42, [8]byte{203, 217, 124, 199, 53, 101, 223, 240}},
+	}
+	for _, c := range cases {
+		if p := Permutation(c.seed); [8]byte(p[:8]) != c.head {
+			t.Errorf("seed %d: permutation begins %v, want %v", c.seed, p[:8], c.head)
+		}
+	}
+}
+
+func TestWithPermutation(t *testing.T) {
+	for _, seed := range []uint64{1, 2, 3} {
+		p := Permutation(seed)
+		var plain, permuted bytes.Buffer
+		g, _ := New(2)
+		g.WriteTo(&plain)
+		g, _ = New(2)
+		if err := g.WithPermutation(p); err != nil {
+			t.Fatal(err)
+		}
+		g.WriteTo(&permuted)
+		name := fmt.Sprintf("seed %d", seed)
+		checkCovers(t, name, permuted.Bytes(), 256, 2)
+		for i, b := range plain.Bytes() {
+			if permuted.Bytes()[i] != p[b] {
+				t.Fatalf("%s: term %d is %d, want %d", name, i, permuted.Bytes()[i], p[b])
+			}
+		}
+	}
+	// The mask applies before the permutation.
+	p := Permutation(4)
+	var g Generator
+	g.Mask(0x0f)
+	g.WithPermutation(p)
+	if b, _ := g.Next(); b != p[0x0f] {
+		t.Errorf("masked and permuted first term is %d, want %d", b, p[0x0f])
+	}
+	var bad [256]byte
+	bad[1] = 1
+	if err := g.WithPermutation(bad); err != errPermutation {
+		t.Errorf("got %v, want errPermutation", err)
+	}
+}
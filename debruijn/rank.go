@@ -0,0 +1,97 @@
+package debruijn
+
+import (
+	"errors"
+	"net/netip"
+)
+
+// Ranking a window relies on the words of the sequence being the necklaces of
+// length 4, each written as its Lyndon root, in increasing order. A window w
+// usually begins inside the word for its own necklace N: if w = N[s:]N[:s],
+// then w begins s terms into that word, modulo the length of the root. That
+// fails when the terms after the word don't continue N, which happens only
+// when w contains the maximal symbol. Then, with w = 0xff^t x for x not
+// beginning with 0xff, the window instead begins t terms before the first word
+// whose necklace has x as a prefix or exceeds it, the 0xff terms being the end
+// of the words before it.
+
+// Rank returns the offset of the window of B(256, 4) which is the given IPv4
+// address. Every address occurs exactly once, so the offset is in [0, 2^32).
+// Rank returns -1 if addr is not an IPv4 address.
+func Rank(addr netip.Addr) int64 {
+	addr = addr.Unmap()
+	if !addr.Is4() {
+		return -1
+	}
+	w := addr.As4()
+	// The window of the necklace itself.
+	n, s := w, 0
+	for r := 1; r < 4; r++ {
+		v := [4]byte{w[r], w[(r+1)%4], w[(r+2)%4], w[(r+3)%4]}
+		if string(v[:]) < string(n[:]) {
+			n, s = v, r
+		}
+	}
+	off, l := necklace(n)
+	// w is n rotated right by s, so it begins 4-s terms into n.
+	if c := off + uint64(4-s)%uint64(l); window(c) == w {
+		return int64(c)
+	}
+	// The window which begins in a run of maximal symbols.
+	t := 0
+	for t < 4 && w[t] == 0xff {
+		t++
+	}
+	var y [4]byte
+	copy(y[:], w[t:])
+	off, _ = necklace(y)
+	return int64((off - uint64(t)) % blocks[256])
+}
+
+// AddrAt returns the IPv4 address which is the window of B(256, 4) beginning
+// at offset off. It is the inverse of Rank. Windows begin only at offsets in
+// [0, 2^32); in particular, no complete window begins in the last three terms.
+func AddrAt(off int64) (netip.Addr, error) {
+	if off < 0 || off >= int64(blocks[256]) {
+		return netip.Addr{}, errNoWindow
+	}
+	return netip.AddrFrom4(window(uint64(off))), nil
+}
+
+var errNoWindow = errors.New("debruijn: no window begins at offset")
+
+// necklace returns the offset of the first word of the sequence whose
+// necklace is at least y, along with the length of that word.
+func necklace(y [4]byte) (off uint64, n int) {
+	var u [4]byte
+	var words [5]int64
+	g := Generator{order: 4, u: u[:], words: words[:]}
+	// Necklaces increase with offset, so binary search for the first word
+	// whose necklace is at least y. Every term of a word shares its necklace,
+	// so the result is the start of the word.
+	lo, hi := uint64(0), blocks[256]-1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		g.locate(mid)
+		if string(u[:]) >= string(y[:]) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	g.locate(lo)
+	return lo, g.n
+}
+
+// window returns the four terms of the sequence beginning at offset off, which
+// must be less than 2^32.
+func window(off uint64) (w [4]byte) {
+	var u [4]byte
+	var words [5]int64
+	g := Generator{order: 4, u: u[:], words: words[:]}
+	g.locate(off)
+	for i := range w {
+		w[i], _ = g.Next()
+	}
+	return w
+}
@@ -0,0 +1,84 @@
+package debruijn
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestRank(t *testing.T) {
+	seq := prefix(t, 1<<20)
+	r := rand.New(rand.NewPCG(3, 4))
+	for range 2000 {
+		var w [4]byte
+		copy(w[:], seq[r.IntN(len(seq)-3):])
+		want := bytes.Index(seq, w[:])
+		if got := Rank(netip.AddrFrom4(w)); got != int64(want) {
+			t.Errorf("Rank(%v) = %d, want %d", netip.AddrFrom4(w), got, want)
+		}
+	}
+}
+
+func TestRankEdges(t *testing.T) {
+	cases := []struct {
+		addr string
+		off  int64
+	}{
+		{"0.0.0.0", 0},
+		{"0.0.0.1", 1},
+		{"::ffff:0.0.0.1", 1},
+		{"255.255.255.255", 1<<32 - 4},
+		// The windows which wrap around the end.
+		{"255.255.255.0", 1<<32 - 3},
+		{"255.255.0.0", 1<<32 - 2},
+		{"255.0.0.0", 1<<32 - 1},
+		{"::1", -1},
+	}
+	for _, c := range cases {
+		if got := Rank(netip.MustParseAddr(c.addr)); got != c.off {
+			t.Errorf("Rank(%s) = %d, want %d", c.addr, got, c.off)
+		}
+	}
+	if got := Rank(netip.Addr{}); got != -1 {
+		t.Errorf("Rank of the zero Addr = %d, want -1", got)
+	}
+}
+
+func TestAddrAt(t *testing.T) {
+	seq := prefix(t, 1<<20)
+	for off := 0; off+4 <= len(seq); off += 997 {
+		addr, err := AddrAt(int64(off))
+		if err != nil {
+			t.Fatalf("AddrAt(%d): %v", off, err)
+		}
+		if want := netip.AddrFrom4([4]byte(seq[off : off+4])); addr != want {
+			t.Errorf("AddrAt(%d) = %v, want %v", off, addr, want)
+		}
+	}
+	if addr, err := AddrAt(0); err != nil || addr != netip.IPv4Unspecified() {
+		t.Errorf("AddrAt(0) = %v with error %v, want 0.0.0.0", addr, err)
+	}
+	for _, off := range []int64{-1, 1 << 32, seqLen - 1, seqLen} {
+		if addr, err := AddrAt(off); err == nil {
+			t.Errorf("AddrAt(%d) = %v, want an error", off, addr)
+		}
+	}
+}
+
+func TestRankAddrAt(t *testing.T) {
+	r := rand.New(rand.NewPCG(5, 6))
+	offs := []int64{0, 1, 2, 3, 4, 1<<32 - 4, 1<<32 - 3, 1<<32 - 2, 1<<32 - 1}
+	for range 100000 {
+		offs = append(offs, r.Int64N(1<<32))
+	}
+	for _, off := range offs {
+		addr, err := AddrAt(off)
+		if err != nil {
+			t.Fatalf("AddrAt(%d): %v", off, err)
+		}
+		if got := Rank(addr); got != off {
+			t.Errorf("Rank(AddrAt(%d)) = Rank(%v) = %d", off, addr, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package debruijn
+
+import (
+	"errors"
+	"io"
+)
+
+// Reader is an io.ReadSeeker producing the binary encoding of B(256, 4), exactly
+// the bytes that WriteBinary writes for the entire sequence: 4 GiB plus three.
+type Reader struct {
+	g Generator
+}
+
+// NewReader returns a Reader positioned at the start of the sequence.
+func NewReader() *Reader {
+	return new(Reader)
+}
+
+// Read fills p with the next terms of the sequence. It returns io.EOF only
+// once every term has been read.
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := r.g.fill(p)
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// Read fills p with the binary encoding of the next terms of g, generating
+// them directly into p, so that a Generator is an io.Reader of its output. It
+// returns io.EOF only once g is exhausted.
+func (g *Generator) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := g.fill(p)
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// WriteTo writes the remainder of the sequence to w, implementing io.WriterTo
+// so that io.Copy can generate terms directly into large slabs. It behaves
+// like Generator.WriteTo.
+func (r *Reader) WriteTo(w io.Writer) (int64, error) {
+	return r.g.WriteTo(w)
+}
+
+// Seek sets the offset for the next Read, interpreted according to whence as
+// described by io.Seeker. Seeking to any offset takes constant time; it does
+// not generate the terms before it. It is an error to seek to a negative
+// offset, but seeking beyond the end of the sequence is allowed, after which
+// Read reports io.EOF.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = int64(r.g.terms) + offset
+	case io.SeekEnd:
+		abs = seqLen + offset
+	default:
+		return 0, errors.New("debruijn.Reader.Seek: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("debruijn.Reader.Seek: negative position")
+	}
+	r.g.seek(uint64(abs))
+	return abs, nil
+}
+
+// ReaderAt is an io.ReaderAt over the binary encoding of B(256, 4). It holds
+// no state, so it is safe to call ReadAt concurrently from any number of
+// goroutines. The zero value is ready to use.
+type ReaderAt struct{}
+
+// ReadAt reads len(p) bytes of the sequence starting at offset off. Each call
+// begins generating independently at off, in constant time. If fewer than
+// len(p) bytes remain, ReadAt returns io.EOF along with those that do.
+func (ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("debruijn.ReaderAt.ReadAt: negative offset")
+	}
+	if off >= seqLen {
+		return 0, io.EOF
+	}
+	var g Generator
+	g.seek(uint64(off))
+	n := g.fill(p)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,240 @@
+package debruijn
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// tail is the end of B(256, 4): the words fe ff fe ff, fe ff ff ff, and ff,
+// then the wrap-around terms.
+var tail = []byte{0xfe, 0xff, 0xfe, 0xfe, 0xff, 0xff, 0xfe, 0xff, 0xfe, 0xff, 0xff, 0xff, 0xff, 0, 0, 0}
+
+// readerOnly hides all but the Read method of a reader, so that io.Copy
+// can't use WriteTo.
+type readerOnly struct {
+	io.Reader
+}
+
+// sampleWriter collects the n bytes written through it at each of the
+// offsets, which must be sorted, and counts the bytes.
+type sampleWriter struct {
+	offs    []int64
+	n       int64
+	off     int64
+	samples map[int64][]byte
+}
+
+func (w *sampleWriter) Write(p []byte) (int, error) {
+	end := w.off + int64(len(p))
+	for _, o := range w.offs {
+		if o >= end {
+			break
+		}
+		s := w.samples[o]
+		if int64(len(s)) == w.n || o+w.n <= w.off {
+			continue
+		}
+		lo := max(o+int64(len(s)), w.off)
+		hi := min(o+w.n, end)
+		w.samples[o] = append(s, p[lo-w.off:hi-w.off]...)
+	}
+	w.off = end
+	return len(p), nil
+}
+
+// stream generates all of B(256, 4) and returns the n bytes at each of offs,
+// fewer at the end of the sequence.
+func stream(t *testing.T, offs []int64, n int64) map[int64][]byte {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("generates all of B(256, 4)")
+	}
+	offs = slices.Clone(offs)
+	slices.Sort(offs)
+	w := &sampleWriter{offs: offs, n: n, samples: make(map[int64][]byte)}
+	var g Generator
+	if _, err := g.WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	return w.samples
+}
+
+func TestReader(t *testing.T) {
+	want := prefix(t, 1<<20)
+	for _, size := range []int{1, 7, 4096, 100000} {
+		r := NewReader()
+		got := make([]byte, 0, len(want)+size)
+		buf := make([]byte, size)
+		for len(got) < len(want) {
+			n, err := r.Read(buf)
+			if err != nil {
+				t.Fatalf("buffer of %d: %v", size, err)
+			}
+			if n != size {
+				t.Fatalf("buffer of %d: read only %d", size, n)
+			}
+			got = append(got, buf[:n]...)
+		}
+		if !bytes.Equal(got[:len(want)], want) {
+			t.Errorf("buffer of %d: reads differ from the sequence", size)
+		}
+	}
+}
+
+func TestReaderEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("reads all of B(256, 4)")
+	}
+	w := &sampleWriter{offs: []int64{seqLen - int64(len(tail))}, n: int64(len(tail)), samples: make(map[int64][]byte)}
+	n, err := io.CopyBuffer(w, readerOnly{NewReader()}, make([]byte, 1<<20-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1<<32+3 {
+		t.Errorf("read %d bytes, want %d", n, int64(1<<32+3))
+	}
+	if got := w.samples[seqLen-int64(len(tail))]; !bytes.Equal(got, tail) {
+		t.Errorf("sequence ends %x, want %x", got, tail)
+	}
+}
+
+func TestGeneratorRead(t *testing.T) {
+	cases := []struct{ k, n int }{{4, 3}, {256, 1}, {256, 2}, {2, 6}}
+	for _, c := range cases {
+		var want bytes.Buffer
+		g, _ := DeBruijn(c.k, c.n)
+		if _, err := g.WriteTo(&want); err != nil {
+			t.Fatal(err)
+		}
+		g, _ = DeBruijn(c.k, c.n)
+		got, err := io.ReadAll(g)
+		if err != nil {
+			t.Fatalf("B(%d, %d): %v", c.k, c.n, err)
+		}
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("B(%d, %d): read %x, want %x", c.k, c.n, got, want.Bytes())
+		}
+		if n, err := g.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+			t.Errorf("B(%d, %d): read after the end gave %d bytes and %v, want io.EOF", c.k, c.n, n, err)
+		}
+		if n, err := g.Read(nil); n != 0 || err != nil {
+			t.Errorf("B(%d, %d): empty read gave %d bytes and %v", c.k, c.n, n, err)
+		}
+	}
+}
+
+// seekOffsets are offsets scattered through B(256, 4), including the
+// boundaries of the blocks of words with the same first term and the
+// wrap-around terms.
+var seekOffsets = []int64{
+	0, 1, 4, 5, 1000003, int64(blocks[1]) - 1, int64(blocks[1]), int64(blocks[1]) + 1,
+	int64(blocks[128]), 1 << 31, 3000000000, int64(blocks[255]), 1<<32 - 5, 1<<32 - 1, 1 << 32,
+}
+
+func TestReaderSeek(t *testing.T) {
+	want := stream(t, seekOffsets, 1000)
+	r := NewReader()
+	for _, off := range seekOffsets {
+		if n, err := r.Seek(off, io.SeekStart); n != off || err != nil {
+			t.Fatalf("seek to %d gave %d and %v", off, n, err)
+		}
+		got, err := io.ReadAll(io.LimitReader(r, 1000))
+		if err != nil {
+			t.Fatalf("at %d: %v", off, err)
+		}
+		if !bytes.Equal(got, want[off]) {
+			t.Errorf("at %d: read %x, want %x", off, got, want[off])
+		}
+	}
+}
+
+func TestReaderSeekWhence(t *testing.T) {
+	r := NewReader()
+	buf := make([]byte, 100)
+	cases := []struct {
+		off    int64
+		whence int
+		abs    int64
+	}{
+		{1000, io.SeekStart, 1000},
+		{-50, io.SeekCurrent, 1050},
+		{5, io.SeekCurrent, 1155},
+		{-int64(len(tail)), io.SeekEnd, seqLen - int64(len(tail))},
+		{-seqLen, io.SeekEnd, 0},
+	}
+	for _, c := range cases {
+		abs, err := r.Seek(c.off, c.whence)
+		if err != nil || abs != c.abs {
+			t.Fatalf("Seek(%d, %d) gave %d and %v, want %d", c.off, c.whence, abs, err, c.abs)
+		}
+		n, _ := io.ReadFull(r, buf)
+		want := make([]byte, n)
+		for i := range want {
+			want[i] = At(uint64(abs) + uint64(i))
+		}
+		if !bytes.Equal(buf[:n], want) {
+			t.Errorf("Seek(%d, %d): read %x, want %x", c.off, c.whence, buf[:n], want)
+		}
+		if c.whence == io.SeekEnd && c.off < 0 && -c.off <= int64(len(buf)) && !bytes.Equal(buf[:n], tail) {
+			t.Errorf("the end of the sequence is %x, want %x", buf[:n], tail)
+		}
+	}
+	for _, c := range []struct {
+		off    int64
+		whence int
+	}{{-1, io.SeekStart}, {-seqLen - 1, io.SeekEnd}, {0, 3}} {
+		if _, err := r.Seek(c.off, c.whence); err == nil {
+			t.Errorf("Seek(%d, %d) succeeded", c.off, c.whence)
+		}
+	}
+	if _, err := r.Seek(seqLen+10, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("read past the end gave %d bytes and %v, want io.EOF", n, err)
+	}
+}
+
+func TestReaderAt(t *testing.T) {
+	want := prefix(t, 4<<20)
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var r ReaderAt
+			p := make([]byte, 64<<10)
+			// Each goroutine reads windows overlapping its neighbors'.
+			for off := int64(w) * 12345; off+int64(len(p)) <= int64(len(want)); off += 200000 {
+				n, err := r.ReadAt(p, off)
+				if n != len(p) || err != nil {
+					t.Errorf("ReadAt at %d read %d bytes with %v", off, n, err)
+					return
+				}
+				if !bytes.Equal(p, want[off:off+int64(n)]) {
+					t.Errorf("ReadAt at %d differs from the sequence", off)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReaderAtEnd(t *testing.T) {
+	var r ReaderAt
+	p := make([]byte, 100)
+	n, err := r.ReadAt(p, seqLen-int64(len(tail)))
+	if n != len(tail) || err != io.EOF || !bytes.Equal(p[:n], tail) {
+		t.Errorf("ReadAt at the end read %x with %v, want %x and io.EOF", p[:n], err, tail)
+	}
+	if n, err := r.ReadAt(p, seqLen); n != 0 || err != io.EOF {
+		t.Errorf("ReadAt past the end read %d bytes with %v, want io.EOF", n, err)
+	}
+	if _, err := r.ReadAt(p, -1); err == nil {
+		t.Error("ReadAt at -1 succeeded")
+	}
+}
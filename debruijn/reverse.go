@@ -0,0 +1,92 @@
+package debruijn
+
+import (
+	"errors"
+	"slices"
+)
+
+// reverse is the state of a Generator producing the sequence in reverse.
+// Since the generator can jump directly to any term of B(256, 4), rather than
+// generating the words in reverse order, it fills chunks forward from the
+// appropriate offset and reverses them in place.
+type reverse struct {
+	// buf holds terms for Next and Terms, of which buf[i:n] remain.
+	buf  [4096]byte
+	i, n int
+}
+
+// Reverse makes g generate the reverse of its sequence, which is also a de
+// Bruijn sequence, beginning with the wrap-around terms and ending with the
+// first term. g must generate B(256, 4), and Reverse must be called before it
+// has generated any terms; Reverse returns an error otherwise. A reversed
+// generator does not count Lyndon words.
+func (g *Generator) Reverse() error {
+	if !g.standard() {
+		return errReverseOrder
+	}
+	if g.stage != stageStart || g.terms != 0 || g.rot != 0 {
+		return errReverseStarted
+	}
+	g.init()
+	g.rev = new(reverse)
+	return nil
+}
+
+var (
+	errReverseOrder   = errors.New("debruijn: reversing requires B(256, 4)")
+	errReverseStarted = errors.New("debruijn: reversing a generator which has begun or is rotated")
+)
+
+// revNext returns the next term of a reversed generator.
+func (g *Generator) revNext() (byte, bool) {
+	r := g.rev
+	if r.i == r.n {
+		r.i, r.n = 0, g.revFill(r.buf[:])
+		// revFill counts the terms it produces, but these are yet to be
+		// emitted.
+		g.terms -= uint64(r.n)
+		if r.n == 0 {
+			return 0, false
+		}
+	}
+	term := r.buf[r.i]
+	r.i++
+	g.terms++
+	return term, true
+}
+
+// revFill fills p with the next terms of a reversed generator, ignoring any
+// buffered for revNext, and returns the number of terms.
+func (g *Generator) revFill(p []byte) int {
+	var u [4]byte
+	var words [5]int64
+	f := Generator{order: 4, u: u[:], words: words[:]}
+	rest := seqLen - min(g.terms, seqLen)
+	c := int(min(uint64(len(p)), rest))
+	f.seek(rest - uint64(c))
+	f.fill(p[:c])
+	slices.Reverse(p[:c])
+	g.terms += uint64(c)
+	return c
+}
+
+// revFillAll fills p like fill for a reversed generator, first taking the
+// terms buffered for revNext.
+func (g *Generator) revFillAll(p []byte) int {
+	r := g.rev
+	k := copy(p, r.buf[r.i:r.n])
+	r.i += k
+	g.terms += uint64(k)
+	return k + g.revFill(p[k:])
+}
+
+// revSkip skips the next n terms of a reversed generator.
+func (g *Generator) revSkip(n uint64) {
+	r := g.rev
+	k := min(n, uint64(r.n-r.i))
+	r.i += int(k)
+	g.terms = min(g.terms+n, seqLen)
+	if k < n {
+		r.i, r.n = 0, 0
+	}
+}
@@ -0,0 +1,91 @@
+package debruijn
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReverse(t *testing.T) {
+	want := prefix(t, 1<<20)
+	slices.Reverse(want)
+	var g Generator
+	if err := g.Reverse(); err != nil {
+		t.Fatal(err)
+	}
+	// The reversed sequence begins with the end of the sequence reversed.
+	head := make([]byte, len(tail))
+	io.ReadFull(&g, head)
+	end := slices.Clone(tail)
+	slices.Reverse(end)
+	if !bytes.Equal(head, end) {
+		t.Errorf("reversed sequence begins %x, want %x", head, end)
+	}
+	g.Skip(seqLen - uint64(len(want)) - uint64(len(head)))
+	got, err := io.ReadAll(&g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("last %d bytes of the reversed sequence differ from the first reversed", len(got))
+	}
+}
+
+func TestReverseTerms(t *testing.T) {
+	// Next and Terms agree with Read, and the text formats separate the
+	// reversed terms as they do the others.
+	var g Generator
+	g.Reverse()
+	want := make([]byte, 10000)
+	io.ReadFull(&g, want)
+	var g2 Generator
+	g2.Reverse()
+	var got []byte
+	for b := range g2.Terms() {
+		got = append(got, b)
+		if len(got) == len(want) {
+			break
+		}
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("Terms of the reversed generator differ from Read")
+	}
+	var b strings.Builder
+	if _, err := Write(&b, Options{Reverse: true, Format: Dot, Limit: int64(len(want))}); err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Split(b.String(), ".")
+	if len(fields) != len(want) {
+		t.Fatalf("%d terms of text, want %d", len(fields), len(want))
+	}
+	for i, f := range fields {
+		if f != strconv.Itoa(int(want[i])) {
+			t.Fatalf("text term %d is %q, want %d", i, f, want[i])
+		}
+	}
+}
+
+func TestReverseErrors(t *testing.T) {
+	g, _ := New(3)
+	if err := g.Reverse(); err != errReverseOrder {
+		t.Errorf("B(256, 3): got %v, want errReverseOrder", err)
+	}
+	var started Generator
+	started.Next()
+	if err := started.Reverse(); err != errReverseStarted {
+		t.Errorf("started: got %v, want errReverseStarted", err)
+	}
+	var rotated Generator
+	rotated.Rotate(5)
+	if err := rotated.Reverse(); err != errReverseStarted {
+		t.Errorf("rotated: got %v, want errReverseStarted", err)
+	}
+	var reversed Generator
+	reversed.Reverse()
+	if err := reversed.Rotate(5); err == nil {
+		t.Error("rotated a reversed generator")
+	}
+}
@@ -0,0 +1,249 @@
+package debruijn
+
+import (
+	"errors"
+	"iter"
+)
+
+// Locating a term by its offset relies on counting the Lyndon words which
+// precede it. The rules for 4-element Lyndon words on Generator give those
+// counts in closed form. Grouping the words by their first symbol α, the
+// sequence is a series of blocks, one for each α, in increasing order. Let
+// m = 255 - α. Each block begins with the 1-element word α. Then, for each
+// β ≥ α in increasing order, there is a sub-block holding the 2-element word
+// αβ if β > α, followed by the 4-element words αβγδ. By the rules, those are
+// the 255 - β words with γ = α and δ > β, then the m² words with γ > α and
+// δ > α. So, the sub-block for β spans
+//
+//	s(α, β) = 2[β > α] + 4(m² + 255 - β)
+//
+// terms, and summing over each β, the block for α spans
+//
+//	b(α) = 1 + 2m + 4m²(m + 1) + 2m(m + 1)
+//
+// terms. The blocks for every α together span exactly 2^32 terms, after which
+// follow the three wrap-around terms.
+
+// seqLen is the total number of terms in the sequence.
+const seqLen = 1<<32 + 3
+
+// blocks holds the offset of the start of each block, with the final entry
+// being the offset of the wrap-around terms.
+var blocks = func() (r [257]uint64) {
+	for a := 0; a < 256; a++ {
+		m := uint64(255 - a)
+		r[a+1] = r[a] + 1 + 2*m + 4*m*m*(m+1) + 2*m*(m+1)
+	}
+	return r
+}()
+
+// subblock returns the offset of the sub-block for β = α + j relative to the
+// start of the block for α, where m = 255 - α.
+func subblock(m, j uint64) uint64 {
+	// The first sub-block begins after the 1-element word. Each preceding
+	// sub-block other than the first has a 2-element word, and preceding
+	// sub-block t has m² + m - t 4-element words.
+	r := 1 + 4*(m*m*j+m*j-j*(j-1)/2)
+	if j > 0 {
+		r += 2 * (j - 1)
+	}
+	return r
+}
+
+// At returns the term at index k of B(256, 4) in constant time, without
+// generating the terms before it. It panics if k is not less than 2^32 + 3.
+func At(k uint64) byte {
+	if k >= seqLen {
+		panic("debruijn: index out of range")
+	}
+	var u [4]byte
+	var words [5]int64
+	g := Generator{order: 4, u: u[:], words: words[:]}
+	g.locate(k)
+	return g.u[g.i]
+}
+
+// TermAt returns the term at index i of B(256, 4), like At, for callers
+// working with the signed offsets of Rank and AddrAt. It panics if i is
+// negative or not less than 2^32 + 3.
+func TermAt(i int64) byte {
+	if i < 0 {
+		panic("debruijn: index out of range")
+	}
+	return At(uint64(i))
+}
+
+// TermsFrom returns an iterator over the terms of B(256, 4) beginning with the
+// Lyndon word word and ending just before the Lyndon word stop, or at the end
+// of the sequence, including the wrap-around terms, if stop is nil. Each word
+// must be a Lyndon word of length 1, 2, or 4, and stop must not precede word;
+// TermsFrom returns an error otherwise. The iterator jumps directly to word.
+func TermsFrom(word, stop []byte) (iter.Seq[byte], error) {
+	start, ok := wordOffset(word)
+	if !ok {
+		return nil, errNotLyndon
+	}
+	end := uint64(seqLen)
+	if stop != nil {
+		end, ok = wordOffset(stop)
+		if !ok {
+			return nil, errNotLyndon
+		}
+		if end < start {
+			return nil, errStopFirst
+		}
+	}
+	f := func(yield func(byte) bool) {
+		var g Generator
+		g.seek(start)
+		for g.terms < end {
+			term, ok := g.Next()
+			if !ok || !yield(term) {
+				return
+			}
+		}
+	}
+	return f, nil
+}
+
+var (
+	errNotLyndon = errors.New("debruijn: not a Lyndon word of length 1, 2, or 4")
+	errStopFirst = errors.New("debruijn: stop word precedes start word")
+)
+
+// WordOffset returns the offset of B(256, 4) at which the Lyndon word word
+// begins, e.g. to pass to Generator.Rotate to begin the cycle with the word.
+// It returns an error if word is not a Lyndon word of length 1, 2, or 4.
+func WordOffset(word []byte) (int64, error) {
+	off, ok := wordOffset(word)
+	if !ok {
+		return 0, errNotLyndon
+	}
+	return int64(off), nil
+}
+
+// wordOffset returns the offset at which the Lyndon word w begins. If w is
+// not a Lyndon word of length 1, 2, or 4, ok is false.
+func wordOffset(w []byte) (off uint64, ok bool) {
+	if len(w) != 1 && len(w) != 2 && len(w) != 4 {
+		return 0, false
+	}
+	// A Lyndon word is strictly less than each of its proper rotations.
+	for r := 1; r < len(w); r++ {
+		if string(w[r:])+string(w[:r]) <= string(w) {
+			return 0, false
+		}
+	}
+	// The word's necklace is itself repeated to length 4.
+	var y [4]byte
+	for i := range y {
+		y[i] = w[i%len(w)]
+	}
+	off, _ = necklace(y)
+	return off, true
+}
+
+// seek positions g so that its next term is the one at offset off. If off is
+// at or beyond the end of the sequence, g is exhausted. g must generate
+// B(256, 4).
+func (g *Generator) seek(off uint64) {
+	g.init()
+	switch {
+	case off == 0:
+		g.stage = stageStart
+		g.i, g.n = 0, 0
+		g.terms = 0
+		clear(g.u)
+		clear(g.words)
+	case off >= seqLen:
+		g.locate(seqLen - 1)
+		g.i = g.n
+		g.terms = off
+	default:
+		// Locate the term just before off and mark it emitted. That way, if
+		// off is the start of a word, the generator advances to it exactly as
+		// it would have without seeking.
+		g.locate(off - 1)
+		g.i++
+		g.terms = off
+	}
+}
+
+// locate sets g's state to the word containing offset off, which must be less
+// than seqLen, with the term at off next. The word counts as begun.
+func (g *Generator) locate(off uint64) {
+	g.terms = off
+	if off >= blocks[256] {
+		g.stage = stageWrap
+		clear(g.u)
+		g.i, g.n = int(off-blocks[256]), 3
+		g.words[1], g.words[2], g.words[4] = 256, 255*256/2, (1<<32-1<<16)/4
+		return
+	}
+	g.stage = stageWords
+	// Find the block with a binary search over the block offsets.
+	lo, hi := 0, 255
+	for lo < hi {
+		mid := int(uint(lo+hi+1) >> 1)
+		if blocks[mid] <= off {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	a := lo
+	m := uint64(255 - a)
+	r := off - blocks[a]
+	// 1-element words precede the current position for each block up to and
+	// including this one, and 2-element words for each sub-block with β > α
+	// in the preceding blocks.
+	g.words[1] = int64(a) + 1
+	g.words[2] = int64(a*255 - a*(a-1)/2)
+	if r == 0 {
+		g.u[0], g.u[1], g.u[2], g.u[3] = byte(a), byte(a), byte(a), byte(a)
+		g.i, g.n = 0, 1
+		g.words[4] = g.count4(off, 1)
+		return
+	}
+	// Find the sub-block, again with a binary search.
+	jlo, jhi := uint64(0), m
+	for jlo < jhi {
+		mid := (jlo + jhi + 1) >> 1
+		if subblock(m, mid) <= r {
+			jlo = mid
+		} else {
+			jhi = mid - 1
+		}
+	}
+	j := jlo
+	b := byte(uint64(a) + j)
+	r -= subblock(m, j)
+	g.words[2] += int64(j)
+	if j > 0 {
+		if r < 2 {
+			g.u[0], g.u[1], g.u[2], g.u[3] = byte(a), b, byte(a), b
+			g.i, g.n = int(r), 2
+			g.words[4] = g.count4(off-r, 2)
+			return
+		}
+		r -= 2
+	}
+	// Find the 4-element word directly.
+	q := r / 4
+	var c, d byte
+	if k := uint64(255 - b); q < k {
+		c, d = byte(a), b+1+byte(q)
+	} else {
+		q -= k
+		c, d = byte(a)+1+byte(q/m), byte(a)+1+byte(q%m)
+	}
+	g.u[0], g.u[1], g.u[2], g.u[3] = byte(a), b, c, d
+	g.i, g.n = int(r%4), 4
+	g.words[4] = g.count4(off-r%4, 4)
+}
+
+// count4 computes the number of 4-element words begun once the word of length
+// n starting at offset start has begun, given the counts of shorter words.
+func (g *Generator) count4(start uint64, n int) int64 {
+	return (int64(start) + int64(n) - g.words[1] - 2*g.words[2]) / 4
+}
@@ -0,0 +1,146 @@
+package debruijn
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestAt(t *testing.T) {
+	want := prefix(t, 1<<22)
+	for k, b := range want {
+		if got := At(uint64(k)); got != b {
+			t.Fatalf("At(%d) = %d, want %d", k, got, b)
+		}
+	}
+}
+
+func TestAtBlocks(t *testing.T) {
+	// Straddle the start of each block, where the first term changes.
+	var offs []int64
+	for _, b := range blocks[1:] {
+		offs = append(offs, int64(b)-8)
+	}
+	want := stream(t, offs, 16)
+	for _, off := range offs {
+		for i, b := range want[off] {
+			k := uint64(off) + uint64(i)
+			if got := At(k); got != b {
+				t.Errorf("At(%d) = %d, want %d", k, got, b)
+			}
+		}
+	}
+}
+
+func TestAtRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("At(2^32 + 3) didn't panic")
+		}
+	}()
+	At(seqLen)
+}
+
+func TestTermAt(t *testing.T) {
+	var g Generator
+	for i := range int64(1000000) {
+		want, _ := g.Next()
+		if got := TermAt(i); got != want {
+			t.Fatalf("TermAt(%d) = %d, want %d", i, got, want)
+		}
+	}
+	// The sequence ends with the three repeated zeros.
+	for i := int64(seqLen - 3); i < seqLen; i++ {
+		if got := TermAt(i); got != 0 {
+			t.Errorf("TermAt(%d) = %d, want 0", i, got)
+		}
+	}
+	for _, i := range []int64{-1, seqLen} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("TermAt(%d) didn't panic", i)
+				}
+			}()
+			TermAt(i)
+		}()
+	}
+}
+
+func TestTermAtRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	offs := make([]int64, 8)
+	for i := range offs {
+		offs[i] = r.Int64N(seqLen-1000) + 1
+	}
+	want := stream(t, offs, 1000)
+	for _, off := range offs {
+		for i, b := range want[off] {
+			if got := TermAt(off + int64(i)); got != b {
+				t.Errorf("TermAt(%d) = %d, want %d", off+int64(i), got, b)
+			}
+		}
+	}
+}
+
+func TestTermsFrom(t *testing.T) {
+	bounds := [][]byte{{0}, {0, 0, 0, 200}, {0, 0, 1, 1}, {0, 0, 1, 2}, {0, 0, 2, 7}}
+	end, err := WordOffset(bounds[len(bounds)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := prefix(t, end)
+	var got []byte
+	for i := range bounds[:len(bounds)-1] {
+		seg, err := TermsFrom(bounds[i], bounds[i+1])
+		if err != nil {
+			t.Fatalf("%x to %x: %v", bounds[i], bounds[i+1], err)
+		}
+		for b := range seg {
+			got = append(got, b)
+		}
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("segments give %d terms which differ from the %d of the sequence", len(got), len(want))
+	}
+	// The last segment includes the wrap-around terms.
+	seg, err := TermsFrom([]byte{0xfe, 0xff, 0xff, 0xff}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = got[:0]
+	for b := range seg {
+		got = append(got, b)
+	}
+	if want := tail[len(tail)-8:]; !bytes.Equal(got, want) {
+		t.Errorf("last segment is %x, want %x", got, want)
+	}
+}
+
+func TestTermsFromErrors(t *testing.T) {
+	cases := []struct {
+		word, stop []byte
+		err        error
+	}{
+		{nil, nil, errNotLyndon},
+		{[]byte{0, 0}, nil, errNotLyndon},
+		{[]byte{1, 0}, nil, errNotLyndon},
+		{[]byte{0, 1, 2}, nil, errNotLyndon},
+		{[]byte{0, 1, 0, 1}, nil, errNotLyndon},
+		{[]byte{0, 0, 0, 0, 1}, nil, errNotLyndon},
+		{[]byte{0}, []byte{2, 1}, errNotLyndon},
+		{[]byte{0, 5}, []byte{0, 4}, errStopFirst},
+	}
+	for _, c := range cases {
+		if _, err := TermsFrom(c.word, c.stop); err != c.err {
+			t.Errorf("TermsFrom(%x, %x): got %v, want %v", c.word, c.stop, err, c.err)
+		}
+	}
+	seg, err := TermsFrom([]byte{3, 9}, []byte{3, 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for b := range seg {
+		t.Fatalf("empty segment gave %d", b)
+	}
+}
@@ -0,0 +1,58 @@
+package debruijn
+
+import (
+	"iter"
+	"net/netip"
+)
+
+// Shuffled returns an iterator over every IPv4 address, each exactly once, in
+// a pseudorandom order derived from seed. It is meant for testing consumers
+// which must not depend on the order of the addresses; the order is not a de
+// Bruijn sequence, and writing it takes a full four bytes per address. The
+// same seed always gives the same order, across platforms and versions of this
+// package. Each range over the iterator starts again from the beginning.
+//
+// The order is the image of the indices 0 through 2^32-1 under a keyed
+// permutation of the 32-bit values, so it takes constant memory. It is not
+// cryptographically secure.
+func Shuffled(seed uint64) iter.Seq[netip.Addr] {
+	return shuffled(seed, 0)
+}
+
+// shuffled is like Shuffled, but begins with the address at index start.
+func shuffled(seed, start uint64) iter.Seq[netip.Addr] {
+	f := newFeistel(seed)
+	return func(yield func(netip.Addr) bool) {
+		for i := start; i < 1<<32; i++ {
+			x := f.permute(uint32(i))
+			if !yield(netip.AddrFrom4([4]byte{byte(x >> 24), byte(x >> 16), byte(x >> 8), byte(x)})) {
+				return
+			}
+		}
+	}
+}
+
+// feistel is a balanced Feistel network over 32-bit values with a round key
+// for each round. Each round is invertible whatever its round function, so the
+// network is a permutation; eight rounds mix the halves thoroughly.
+type feistel [8]uint64
+
+// newFeistel derives the round keys of a feistel from seed.
+func newFeistel(seed uint64) feistel {
+	var f feistel
+	s := seed
+	for i := range f {
+		f[i] = splitmix(&s)
+	}
+	return f
+}
+
+// permute maps x through the network.
+func (f *feistel) permute(x uint32) uint32 {
+	l, r := uint16(x>>16), uint16(x)
+	for _, k := range f {
+		s := k ^ uint64(r)
+		l, r = r, l^uint16(splitmix(&s)>>48)
+	}
+	return uint32(l)<<16 | uint32(r)
+}
@@ -0,0 +1,126 @@
+package debruijn
+
+// Format is an encoding of the sequence.
+type Format int
+
+const (
+	// Binary writes each term as a single byte, as WriteBinary does.
+	Binary Format = iota
+	// Dot writes terms in decimal separated by ".", as WriteText does with
+	// a '.' separator.
+	Dot
+	// Lines writes terms in decimal separated by newlines, as WriteText does
+	// with a '\n' separator.
+	Lines
+	// IPv4 writes each window of four terms as a dotted-quad address on its
+	// own line, as WriteIPv4 does.
+	IPv4
+	// HexDot writes terms as two hexadecimal digits separated by ".", as
+	// WriteHex does with a '.' separator.
+	HexDot
+	// HexLines writes terms as two hexadecimal digits separated by newlines,
+	// as WriteHex does with a '\n' separator.
+	HexLines
+	// PaddedDot writes terms as three decimal digits, zero-padded like 007,
+	// separated by ".", as WritePadded does with a '.' separator.
+	PaddedDot
+	// PaddedLines writes terms as three zero-padded decimal digits
+	// separated by newlines, as WritePadded does with a '\n' separator.
+	PaddedLines
+)
+
+// Size returns the exact number of bytes in the entire sequence B(256, order)
+// encoded in the given format, computed without generating it. It returns
+// ErrOrder if order is less than 1, if the size does not fit in an int64, or
+// if the format is IPv4 and order is not 4.
+//
+// There are 256^n + n - 1 terms in B(256, n). Every window of n terms in the
+// cycle is distinct, so each symbol appears at the start of exactly 256^(n-1)
+// of them, hence that many times in the cycle. Writing each of 0 through 255
+// once in decimal takes 10·1 + 90·2 + 156·3 = 658 digits. The n - 1 terms
+// which wrap around the cycle are zeros, taking one digit each. Lastly, the
+// text formats have one separator between each pair of terms. In hexadecimal,
+// every term takes exactly two digits, and padded, exactly three.
+func Size(format Format, order int) (int64, error) {
+	if order < 1 || order > 7 {
+		// 256^8 terms would overflow.
+		return 0, ErrOrder
+	}
+	cycle := uint64(1) << (8 * order)
+	terms := cycle + uint64(order) - 1
+	switch format {
+	case Binary:
+		return int64(terms), nil
+	case Dot, Lines:
+		digits := (cycle>>8)*658 + uint64(order) - 1
+		return int64(digits + terms - 1), nil
+	case IPv4:
+		if order != 4 {
+			return 0, ErrOrder
+		}
+		// Each of the 2^32 addresses is on its own line. Each of the four
+		// positions in an address cycles through every symbol 2^24 times,
+		// and there are three dots and a newline per address.
+		return 4*(1<<24)*658 + 4*(1<<32), nil
+	}
+	if width := format.info().width; width > 0 {
+		return int64(uint64(width+1)*terms - 1), nil
+	}
+	panic("debruijn: invalid format")
+}
+
+// Size returns the exact number of bytes in the entire output of g encoded in
+// the given format, from its first term regardless of how many it has
+// emitted, computed without generating it. Unlike the package-level Size, it
+// accounts for the alphabet, rotation, mask, and permutation of g. It returns
+// ErrOrder if the size does not fit in an int64 or if the format is IPv4 and
+// g does not generate B(256, 4).
+//
+// The terms of the cycle are the same for every rotation and direction, each
+// symbol appearing k^(n-1) times, and the mask and permutation only relabel
+// them. What differs is the n - 1 terms which wrap around the cycle, which
+// repeat the first n - 1 terms of the output.
+func (g *Generator) Size(format Format) (int64, error) {
+	k, n := uint64(g.Alphabet()), g.Order()
+	// per is the number of times each symbol appears in the cycle. Bound
+	// the cycle so that four bytes per term fit in an int64.
+	per := uint64(1)
+	for range n - 1 {
+		if per > (1<<60)/k/k {
+			return 0, ErrOrder
+		}
+		per *= k
+	}
+	cycle := per * k
+	if cycle > 1<<60 {
+		return 0, ErrOrder
+	}
+	terms := cycle + uint64(n) - 1
+	switch format {
+	case Binary:
+		return int64(terms), nil
+	case IPv4:
+		if !g.standard() {
+			return 0, ErrOrder
+		}
+		return Size(IPv4, 4)
+	case Dot, Lines:
+		var digits uint64
+		for b := range k {
+			digits += per * uint64(len(encd[g.sub(byte(b))])-1)
+		}
+		// A reversed generator is never rotated, so its wrap-around terms
+		// are zeros just as for the unrotated sequence.
+		t := Generator{order: n, k: g.k}
+		t.Skip(g.rot)
+		for range n - 1 {
+			term, _ := t.next()
+			digits += uint64(len(encd[g.sub(term)]) - 1)
+		}
+		return int64(digits + terms - 1), nil
+	}
+	if width := format.info().width; width > 0 {
+		return int64(uint64(width+1)*terms - 1), nil
+	}
+	panic("debruijn: invalid format")
+}
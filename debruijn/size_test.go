@@ -0,0 +1,112 @@
+package debruijn
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// textFormats are the formats other than IPv4, which have a size for every
+// order.
+var textFormats = []Format{Binary, Dot, Lines, HexDot, HexLines, PaddedDot, PaddedLines}
+
+func TestSizeGenerated(t *testing.T) {
+	for _, order := range []int{1, 2} {
+		for _, f := range textFormats {
+			n, err := Write(io.Discard, Options{Order: order, Format: f})
+			if err != nil {
+				t.Fatal(err)
+			}
+			size, err := Size(f, order)
+			if err != nil || size != n {
+				t.Errorf("%v of order %d: size %d with error %v, but wrote %d bytes", f, order, size, err, n)
+			}
+		}
+	}
+}
+
+func TestSizeClosedForm(t *testing.T) {
+	// B(256, 2) has 65537 terms, each symbol appearing 256 times in the
+	// cycle and the wrap-around term a zero.
+	cases := []struct {
+		f    Format
+		size int64
+	}{
+		{Binary, 65537},
+		{Dot, 256*(10*1+90*2+156*3) + 1 + 65536},
+		{HexLines, 3*65537 - 1},
+		{PaddedDot, 4*65537 - 1},
+	}
+	for _, c := range cases {
+		if size, err := Size(c.f, 2); err != nil || size != c.size {
+			t.Errorf("%v: size %d with error %v, want %d", c.f, size, err, c.size)
+		}
+	}
+	for _, order := range []int{0, -1, 8} {
+		if _, err := Size(Binary, order); !errors.Is(err, ErrOrder) {
+			t.Errorf("order %d: got error %v, want ErrOrder", order, err)
+		}
+	}
+	if _, err := Size(IPv4, 3); !errors.Is(err, ErrOrder) {
+		t.Errorf("IPv4 of order 3: got error %v, want ErrOrder", err)
+	}
+}
+
+func TestGeneratorSize(t *testing.T) {
+	cases := []struct {
+		name string
+		k, n int
+		set  func(*Generator)
+	}{
+		{"B(10, 3)", 10, 3, func(*Generator) {}},
+		{"B(200, 2)", 200, 2, func(*Generator) {}},
+		{"B(256, 2) rotated", 256, 2, func(g *Generator) { g.Rotate(4097) }},
+		{"B(256, 2) masked", 256, 2, func(g *Generator) { g.Mask(0xc3) }},
+		{"B(100, 2) permuted", 100, 2, func(g *Generator) {
+			p := Permutation(7)
+			g.WithPermutation(p)
+		}},
+	}
+	for _, c := range cases {
+		for _, f := range textFormats {
+			g, _ := DeBruijn(c.k, c.n)
+			c.set(g)
+			size, err := g.Size(f)
+			if err != nil {
+				t.Fatalf("%s %v: %v", c.name, f, err)
+			}
+			n, err := Options{Format: f}.WriteFrom(io.Discard, g)
+			if err != nil {
+				t.Fatalf("%s %v: %v", c.name, f, err)
+			}
+			if size != n {
+				t.Errorf("%s %v: size %d, but wrote %d bytes", c.name, f, size, n)
+			}
+		}
+	}
+}
+
+func TestSizePinned(t *testing.T) {
+	cases := []struct {
+		f    Format
+		size int64
+	}{
+		{Binary, 4294967299},
+		{Dot, 15334375429},
+		{Lines, 15334375429},
+		{HexDot, 12884901896},
+		{HexLines, 12884901896},
+		{PaddedDot, 17179869195},
+		{PaddedLines, 17179869195},
+		{IPv4, 61337501696},
+	}
+	for _, c := range cases {
+		if size, err := Size(c.f, 4); err != nil || size != c.size {
+			t.Errorf("%v: size %d with error %v, want %d", c.f, size, err, c.size)
+		}
+		var g Generator
+		if size, err := g.Size(c.f); err != nil || size != c.size {
+			t.Errorf("%v: Generator.Size %d with error %v, want %d", c.f, size, err, c.size)
+		}
+	}
+}
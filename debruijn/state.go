@@ -0,0 +1,247 @@
+package debruijn
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+// State is a snapshot of the position of a Generator. It can be encoded with
+// MarshalBinary and decoded with UnmarshalBinary, e.g. to save a checkpoint
+// of a long run, and ResumeFrom continues generation exactly where the
+// snapshot was taken. The zero State is invalid.
+type State struct {
+	order int
+	k     int
+	u     []byte
+	i, n  int
+	stage stage
+	terms uint64
+	words []int64
+	// rot, end, and lapped are as on Generator.
+	rot, end uint64
+	lapped   bool
+	// rev is whether the generator is reversed. A reversed generator's
+	// position is just the number of terms it has emitted.
+	rev bool
+	// mask and perm are as on Generator.
+	mask byte
+	perm *[256]byte
+}
+
+// stateVersion is the version of the encoding of State, which is the first
+// byte of the encoding. It changes whenever the layout does.
+const stateVersion = 6
+
+var (
+	errStateVersion = errors.New("debruijn: unsupported state version")
+	errState        = errors.New("debruijn: invalid state")
+)
+
+// State returns a snapshot of g's position.
+func (g *Generator) State() State {
+	g.init()
+	return State{
+		order:  g.order,
+		k:      g.k,
+		u:      slices.Clone(g.u),
+		i:      g.i,
+		n:      g.n,
+		stage:  g.stage,
+		terms:  g.terms,
+		words:  slices.Clone(g.words),
+		rot:    g.rot,
+		end:    g.end,
+		lapped: g.lapped,
+		rev:    g.rev != nil,
+		mask:   g.mask,
+		perm:   g.perm,
+	}
+}
+
+// ResumeFrom returns a Generator positioned where the generator was when s
+// was taken from it. It returns an error if s is invalid.
+func ResumeFrom(s State) (*Generator, error) {
+	if !s.valid() {
+		return nil, errState
+	}
+	g := &Generator{
+		order:  s.order,
+		k:      s.k,
+		u:      slices.Clone(s.u),
+		i:      s.i,
+		n:      s.n,
+		stage:  s.stage,
+		terms:  s.terms,
+		words:  slices.Clone(s.words),
+		rot:    s.rot,
+		end:    s.end,
+		lapped: s.lapped,
+		mask:   s.mask,
+		perm:   s.perm,
+	}
+	if s.rev {
+		g.rev = new(reverse)
+	}
+	return g, nil
+}
+
+// Terms returns the offset in the sequence of the generator's next term when s
+// was taken, counting from the end if the generator is reversed. Unless the
+// generator was rotated, that is the number of terms it had emitted.
+func (s State) Terms() uint64 {
+	return s.terms
+}
+
+// valid reports whether s describes a possible generator position.
+func (s State) valid() bool {
+	return s.order >= 1 &&
+		0 <= s.k && s.k < 256 &&
+		len(s.u) == s.order &&
+		len(s.words) == s.order+1 &&
+		s.stage <= stageWrap &&
+		0 <= s.i && s.i <= s.n && s.n <= s.order &&
+		(s.lapped || s.end == 0) &&
+		(!s.rev || s.order == 4 && s.k == 0 && s.rot == 0)
+}
+
+// MarshalBinary encodes s. The encoding is a version byte followed by the
+// order, the alphabet size or 0 for 256, the stage, the position within the
+// current word, the length of that word, the offset of the next term, the
+// count of words of each length from 1 through the order, the rotation, the
+// offset at which to stop, 1 if the generator has lapped or 0 otherwise, and
+// 1 if it is reversed or 0 otherwise, and 1 if it has a permutation or 0
+// otherwise, each as a uvarint, then the mask, then the permutation if any,
+// then the current word repeated to the length of the order.
+func (s State) MarshalBinary() ([]byte, error) {
+	if !s.valid() {
+		return nil, errState
+	}
+	b := []byte{stateVersion}
+	b = binary.AppendUvarint(b, uint64(s.order))
+	b = binary.AppendUvarint(b, uint64(s.k))
+	b = binary.AppendUvarint(b, uint64(s.stage))
+	b = binary.AppendUvarint(b, uint64(s.i))
+	b = binary.AppendUvarint(b, uint64(s.n))
+	b = binary.AppendUvarint(b, s.terms)
+	for _, c := range s.words[1:] {
+		b = binary.AppendUvarint(b, uint64(c))
+	}
+	b = binary.AppendUvarint(b, s.rot)
+	b = binary.AppendUvarint(b, s.end)
+	lapped := uint64(0)
+	if s.lapped {
+		lapped = 1
+	}
+	b = binary.AppendUvarint(b, lapped)
+	rev := uint64(0)
+	if s.rev {
+		rev = 1
+	}
+	b = binary.AppendUvarint(b, rev)
+	perm := uint64(0)
+	if s.perm != nil {
+		perm = 1
+	}
+	b = binary.AppendUvarint(b, perm)
+	b = append(b, s.mask)
+	if s.perm != nil {
+		b = append(b, s.perm[:]...)
+	}
+	b = append(b, s.u...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes an encoding of a State produced by MarshalBinary
+// into s. It returns an error if the encoding is of a different version or
+// does not describe a valid State.
+func (s *State) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errState
+	}
+	if data[0] != stateVersion {
+		return errStateVersion
+	}
+	data = data[1:]
+	next := func() uint64 {
+		v, k := binary.Uvarint(data)
+		if k <= 0 {
+			data = nil
+			return 1<<64 - 1
+		}
+		data = data[k:]
+		return v
+	}
+	order := next()
+	// Every term of the word follows the counts, so the order can't exceed
+	// what remains of the encoding.
+	if order < 1 || order > uint64(len(data)) {
+		return errState
+	}
+	k := next()
+	if k >= 256 {
+		return errState
+	}
+	r := State{
+		order: int(order),
+		k:     int(k),
+		words: make([]int64, order+1),
+	}
+	stg, i, n := next(), next(), next()
+	if stg > uint64(stageWrap) || i > order || n > order {
+		return errState
+	}
+	r.stage, r.i, r.n = stage(stg), int(i), int(n)
+	r.terms = next()
+	for l := range r.words[1:] {
+		c := next()
+		if c > 1<<63-1 {
+			return errState
+		}
+		r.words[l+1] = int64(c)
+	}
+	r.rot, r.end = next(), next()
+	switch next() {
+	case 0:
+	case 1:
+		r.lapped = true
+	default:
+		return errState
+	}
+	switch next() {
+	case 0:
+	case 1:
+		r.rev = true
+	default:
+		return errState
+	}
+	permuted := false
+	switch next() {
+	case 0:
+	case 1:
+		permuted = true
+	default:
+		return errState
+	}
+	want := order + 1
+	if permuted {
+		want += 256
+	}
+	if uint64(len(data)) != want {
+		return errState
+	}
+	r.mask, data = data[0], data[1:]
+	if permuted {
+		r.perm = new([256]byte)
+		data = data[copy(r.perm[:], data):]
+		if !isPermutation(r.perm) {
+			return errState
+		}
+	}
+	r.u = slices.Clone(data)
+	if !r.valid() {
+		return errState
+	}
+	*s = r
+	return nil
+}
@@ -0,0 +1,106 @@
+package debruijn
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"testing"
+)
+
+// roundTrip takes the State of g, encodes and decodes it, and resumes from it.
+func roundTrip(t *testing.T, g *Generator) *Generator {
+	t.Helper()
+	b, err := g.State().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s State
+	if err := s.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ResumeFrom(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestStateCheckpoints(t *testing.T) {
+	const size = 10 << 20
+	want := prefix(t, size)
+	r := rand.New(rand.NewPCG(7, 8))
+	for range 10 {
+		at := r.IntN(size)
+		var g Generator
+		if _, err := io.ReadFull(&g, make([]byte, at)); err != nil {
+			t.Fatal(err)
+		}
+		g2 := roundTrip(t, &g)
+		if s := g2.State(); s.Terms() != uint64(at) {
+			t.Errorf("checkpoint at %d resumed at %d", at, s.Terms())
+		}
+		got := make([]byte, size-at)
+		if _, err := io.ReadFull(g2, got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want[at:]) {
+			t.Errorf("resumed at %d differs from the uninterrupted run", at)
+		}
+	}
+}
+
+func TestStateOptions(t *testing.T) {
+	perm := Permutation(9)
+	cases := []struct {
+		name string
+		opts Options
+	}{
+		{"B(10, 4)", Options{Alphabet: 10}},
+		{"B(256, 2)", Options{Order: 2}},
+		{"rotated", Options{Rotate: 1 << 31}},
+		{"masked", Options{Mask: 0x5a}},
+		{"permuted", Options{Permutation: &perm}},
+		{"reversed", Options{Reverse: true}},
+	}
+	for _, c := range cases {
+		g, err := c.opts.Generator()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		want := make([]byte, 100000)
+		io.ReadFull(g, want)
+		g, _ = c.opts.Generator()
+		io.ReadFull(g, make([]byte, 12345))
+		g = roundTrip(t, g)
+		got := make([]byte, len(want)-12345)
+		io.ReadFull(g, got)
+		if !bytes.Equal(got, want[12345:]) {
+			t.Errorf("%s: resumed generator differs", c.name)
+		}
+	}
+}
+
+func TestStateErrors(t *testing.T) {
+	if _, err := (State{}).MarshalBinary(); err == nil {
+		t.Error("marshaled the zero State")
+	}
+	if _, err := ResumeFrom(State{}); err == nil {
+		t.Error("resumed from the zero State")
+	}
+	var g Generator
+	io.ReadFull(&g, make([]byte, 1000))
+	b, _ := g.State().MarshalBinary()
+	var s State
+	if err := s.UnmarshalBinary(append([]byte{stateVersion + 1}, b[1:]...)); !errors.Is(err, errStateVersion) {
+		t.Errorf("other version: got %v, want errStateVersion", err)
+	}
+	for n := range len(b) {
+		if err := s.UnmarshalBinary(b[:n]); err == nil {
+			t.Errorf("decoded the first %d of %d bytes", n, len(b))
+		}
+	}
+	if err := s.UnmarshalBinary(append(b, 0)); err == nil {
+		t.Error("decoded with a trailing byte")
+	}
+}
@@ -0,0 +1,186 @@
+package debruijn
+
+import (
+	"fmt"
+	"io"
+)
+
+// Verify generates B(256, order) and checks that every string of order terms
+// appears in it exactly once, returning an error describing the first string
+// which is duplicated or missing. It records the strings seen in a bitset of
+// 256^order bits, so the order must be at most 4; Verify returns ErrOrder
+// otherwise. For order 4, the bitset takes 512 MiB.
+func Verify(order int) error {
+	if order < 1 || order > 4 {
+		return ErrOrder
+	}
+	return verify(256, order)
+}
+
+// verify checks B(k, n) as Verify does. k^n must be at most 2^32.
+func verify(k, n int) error {
+	g, err := DeBruijn(k, n)
+	if err != nil {
+		return err
+	}
+	c := newChecker(k, n)
+	var buf [4096]byte
+	for {
+		m := g.fill(buf[:])
+		if m == 0 {
+			break
+		}
+		c.write(buf[:m])
+	}
+	if c.dups != 0 {
+		return fmt.Errorf("debruijn: %v appears again at offset %d", tuple(c.dup, k, n), c.firstDup)
+	}
+	if missing, first := c.missing(); missing != 0 {
+		return fmt.Errorf("debruijn: %v is missing", tuple(first, k, n))
+	}
+	if want := c.total + uint64(n) - 1; c.off != want {
+		return fmt.Errorf("debruijn: sequence has %d terms, not %d", c.off, want)
+	}
+	return nil
+}
+
+// Report describes the windows found in a stream by VerifyReader.
+type Report struct {
+	// Length is the number of bytes in the stream.
+	Length int64
+	// Windows is the number of windows in the stream, one beginning at
+	// each offset with enough bytes after it.
+	Windows int64
+	// Missing is the number of strings which appear in no window.
+	Missing int64
+	// Duplicates is the number of windows which repeat an earlier one.
+	Duplicates int64
+	// FirstAnomaly is the offset of the first window which repeats an
+	// earlier one. If there are none but the stream is the wrong length, it
+	// is the offset at which the stream should have ended or did end,
+	// whichever is first. It is -1 if the stream is a valid sequence.
+	FirstAnomaly int64
+}
+
+// OK reports whether the stream contained every string exactly once and was
+// exactly the length of a de Bruijn sequence.
+func (r Report) OK() bool {
+	return r.FirstAnomaly < 0
+}
+
+// VerifyReader reads a purported binary encoding of B(256, order) from r and
+// checks that every string of order bytes appears in it exactly once as a
+// window, reading through to the end of r even if the stream is longer than
+// it should be. It uses a bitset like Verify's, so the order must be at most
+// 4; VerifyReader returns ErrOrder otherwise. The returned error is non-nil
+// only if reading fails, in which case the report covers what was read; an
+// invalid stream is reported by the Report.
+func VerifyReader(r io.Reader, order int) (Report, error) {
+	if order < 1 || order > 4 {
+		return Report{}, ErrOrder
+	}
+	c := newChecker(256, order)
+	buf := make([]byte, slabSize)
+	var err error
+	for {
+		var m int
+		m, err = r.Read(buf)
+		c.write(buf[:m])
+		if err != nil {
+			break
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	rep := Report{
+		Length:       int64(c.off),
+		Windows:      int64(max(c.off, uint64(order)-1) - uint64(order) + 1),
+		Duplicates:   c.dups,
+		FirstAnomaly: c.firstDup,
+	}
+	rep.Missing, _ = c.missing()
+	if want := c.total + uint64(order) - 1; rep.FirstAnomaly < 0 && c.off != want {
+		rep.FirstAnomaly = int64(min(c.off, want))
+	}
+	return rep, err
+}
+
+// checker tracks which windows of n terms over an alphabet of k symbols
+// appear in a stream, in a bitset of k^n bits.
+type checker struct {
+	k, total uint64
+	n        int
+	seen     []uint64
+	// v is the value in base k of the last n terms, and off is the number
+	// of terms seen.
+	v, off uint64
+	// dups counts repeated windows. firstDup is the offset of the first, or
+	// -1 if there are none, and dup is its value.
+	dups     int64
+	firstDup int64
+	dup      uint64
+}
+
+func newChecker(k, n int) *checker {
+	total := uint64(1)
+	for range n {
+		total *= uint64(k)
+	}
+	return &checker{
+		k:        uint64(k),
+		total:    total,
+		n:        n,
+		seen:     make([]uint64, (total+63)/64),
+		firstDup: -1,
+	}
+}
+
+// write records the windows completed by the terms in p.
+func (c *checker) write(p []byte) {
+	for _, b := range p {
+		c.v = (c.v*c.k + uint64(b)) % c.total
+		c.off++
+		if c.off < uint64(c.n) {
+			continue
+		}
+		v := c.v
+		if c.seen[v/64]&(1<<(v%64)) != 0 {
+			if c.dups == 0 {
+				c.firstDup, c.dup = int64(c.off-uint64(c.n)), v
+			}
+			c.dups++
+			continue
+		}
+		c.seen[v/64] |= 1 << (v % 64)
+	}
+}
+
+// missing returns the number of windows not seen and the value of the first.
+func (c *checker) missing() (count int64, first uint64) {
+	for i, w := range c.seen {
+		if w == ^uint64(0) {
+			continue
+		}
+		for j := range 64 {
+			if v := uint64(i)*64 + uint64(j); v < c.total && w&(1<<j) == 0 {
+				if count == 0 {
+					first = v
+				}
+				count++
+			}
+		}
+	}
+	return count, first
+}
+
+// tuple returns the string of n terms over an alphabet of size k whose digits
+// in base k are v.
+func tuple(v uint64, k, n int) []byte {
+	t := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		t[i] = byte(v % uint64(k))
+		v /= uint64(k)
+	}
+	return t
+}
@@ -0,0 +1,51 @@
+package debruijn
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	for order := 1; order <= 4; order++ {
+		if order == 4 && testing.Short() {
+			continue
+		}
+		if err := Verify(order); err != nil {
+			t.Errorf("order %d: %v", order, err)
+		}
+	}
+	for _, order := range []int{0, 5} {
+		if err := Verify(order); !errors.Is(err, ErrOrder) {
+			t.Errorf("order %d: got %v, want ErrOrder", order, err)
+		}
+	}
+}
+
+// sequence returns the whole of B(256, order).
+func sequence(t *testing.T, order int) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if _, err := Write(&b, Options{Order: order}); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestChecker(t *testing.T) {
+	s := sequence(t, 2)
+	// Changing 01 00 02 to 01 01 02 moves the windows 01 01 and 01 02 earlier,
+	// so that they repeat where they belong, and loses 01 00 and 00 02.
+	i := bytes.Index(s, []byte{1, 0, 2})
+	s[i+1] = 1
+	// The first repeat is then the original 01 01.
+	j := i + 1 + bytes.Index(s[i+1:], []byte{1, 1})
+	c := newChecker(256, 2)
+	c.write(s)
+	if c.dups != 2 || c.firstDup != int64(j) || !bytes.Equal(tuple(c.dup, 256, 2), []byte{1, 1}) {
+		t.Errorf("%d duplicates, the first %x at %d; want 2, the first 0101 at %d", c.dups, tuple(c.dup, 256, 2), c.firstDup, j)
+	}
+	if n, first := c.missing(); n != 2 || !bytes.Equal(tuple(first, 256, 2), []byte{0, 2}) {
+		t.Errorf("%d missing, the first %x; want 2, the first 0002", n, tuple(first, 256, 2))
+	}
+}
@@ -0,0 +1,168 @@
+package debruijn
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// Wide produces the successive terms of B(k, n) for alphabets of up to 65536
+// symbols, such as B(65536, 2), which contains every pair of 16-bit values. It
+// uses Duval's algorithm as Generator does, without the specializations for
+// B(256, 4).
+//
+// Sizes grow quickly with such large alphabets. B(65536, n) has 65536^n + n - 1
+// terms, so B(65536, 2) is just over 4 billion terms, or 8 GiB in binary, and
+// B(65536, 3) is 512 TiB.
+type Wide struct {
+	k int
+	// u is the current Lyndon word repeated to length n, of which u[i:l] is
+	// yet to be emitted.
+	u     []uint16
+	i, l  int
+	stage stage
+	// order is the byte order of terms in WriteTo, or nil for big-endian.
+	order binary.ByteOrder
+}
+
+// NewWide returns a Wide positioned at the start of B(k, n). k must be between
+// 1 and 65536, and n must be at least 1; NewWide returns ErrAlphabet or
+// ErrOrder otherwise.
+func NewWide(k, n int) (*Wide, error) {
+	if k < 1 || k > 65536 {
+		return nil, ErrAlphabet
+	}
+	if n < 1 {
+		return nil, ErrOrder
+	}
+	return &Wide{k: k, u: make([]uint16, n)}, nil
+}
+
+// Alphabet returns the number of distinct terms in the sequence g generates.
+func (g *Wide) Alphabet() int {
+	return g.k
+}
+
+// Order returns the order of the sequence g generates.
+func (g *Wide) Order() int {
+	return len(g.u)
+}
+
+// WithByteOrder sets the order of the two bytes of each term that WriteTo
+// writes. The default is big-endian.
+func (g *Wide) WithByteOrder(order binary.ByteOrder) {
+	g.order = order
+}
+
+// Size returns the exact number of bytes in the entire output of g in the
+// encoding WriteTo uses, two bytes per term, computed without generating it.
+// It returns ErrOrder if the size does not fit in an int64.
+func (g *Wide) Size() (int64, error) {
+	cycle, k := uint64(1), uint64(g.k)
+	for range g.u {
+		if cycle > (1<<61)/k {
+			return 0, ErrOrder
+		}
+		cycle *= k
+	}
+	return int64(2 * (cycle + uint64(len(g.u)) - 1)), nil
+}
+
+// Next returns the next term of the sequence. If the sequence is exhausted,
+// ok is false.
+func (g *Wide) Next() (term uint16, ok bool) {
+	if g.i == g.l && !g.advance() {
+		return 0, false
+	}
+	term = g.u[g.i]
+	g.i++
+	return term, true
+}
+
+// Terms returns an iterator over the remaining terms of g. Breaking out of a
+// range over the iterator leaves g positioned just after the last term it
+// yielded.
+func (g *Wide) Terms() iter.Seq[uint16] {
+	return func(yield func(uint16) bool) {
+		for {
+			term, ok := g.Next()
+			if !ok || !yield(term) {
+				return
+			}
+		}
+	}
+}
+
+// WriteTo writes the binary encoding of the remaining terms of g to w, each
+// term as two bytes in big-endian order or the order set by WithByteOrder.
+// It returns the number of bytes
+// written. If a write fails, the returned error wraps it along with the
+// offset in bytes at which it happened.
+func (g *Wide) WriteTo(w io.Writer) (int64, error) {
+	var order binary.ByteOrder = binary.BigEndian
+	if g.order != nil {
+		order = g.order
+	}
+	buf := make([]byte, slabSize)
+	var written int64
+	for {
+		k := 0
+		for k < len(buf) {
+			term, ok := g.Next()
+			if !ok {
+				break
+			}
+			order.PutUint16(buf[k:], term)
+			k += 2
+		}
+		if k == 0 {
+			return written, nil
+		}
+		c, err := w.Write(buf[:k])
+		if err == nil && c < k {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return written + int64(c), fmt.Errorf("debruijn: writing at offset %d: %w", written+int64(c), err)
+		}
+		written += int64(c)
+	}
+}
+
+// advance queues the terms of the next Lyndon word whose length divides the
+// order, or the wrap-around terms once the words are exhausted. It returns
+// false if there are no more terms.
+func (g *Wide) advance() bool {
+	u := g.u
+	switch g.stage {
+	case stageStart:
+		g.stage = stageWords
+		g.i, g.l = 0, 1
+		return true
+	case stageWrap:
+		return false
+	}
+	top := uint16(g.k - 1)
+	for {
+		j := len(u) - 1
+		for j >= 0 && u[j] == top {
+			j--
+		}
+		if j < 0 {
+			g.stage = stageWrap
+			clear(u)
+			g.i, g.l = 0, len(u)-1
+			return g.l > 0
+		}
+		u[j]++
+		l := j + 1
+		for k := l; k < len(u); k++ {
+			u[k] = u[k-l]
+		}
+		if len(u)%l == 0 {
+			g.i, g.l = 0, l
+			return true
+		}
+	}
+}
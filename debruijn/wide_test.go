@@ -0,0 +1,138 @@
+package debruijn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestWideNarrow(t *testing.T) {
+	// For alphabets which fit in a byte, Wide gives the same sequence as
+	// DeBruijn.
+	for _, c := range []struct{ k, n int }{{1, 1}, {2, 5}, {3, 3}, {10, 4}, {256, 2}} {
+		g, err := DeBruijn(c.k, c.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewWide(c.k, c.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		i := 0
+		for term := range w.Terms() {
+			want, ok := g.Next()
+			if !ok || uint16(want) != term {
+				t.Fatalf("B(%d, %d): term %d is %d, want %d", c.k, c.n, i, term, want)
+			}
+			i++
+		}
+		if _, ok := g.Next(); ok {
+			t.Errorf("B(%d, %d): Wide ended after %d terms", c.k, c.n, i)
+		}
+	}
+}
+
+func TestWideByteOrder(t *testing.T) {
+	// B(65536, 1) is each 16-bit value in order.
+	cases := []struct {
+		name  string
+		order binary.ByteOrder
+	}{
+		{"default", nil},
+		{"big-endian", binary.BigEndian},
+		{"little-endian", binary.LittleEndian},
+	}
+	for _, c := range cases {
+		w, _ := NewWide(65536, 1)
+		if c.order != nil {
+			w.WithByteOrder(c.order)
+		}
+		var b bytes.Buffer
+		n, err := w.WriteTo(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1<<17 || b.Len() != 1<<17 {
+			t.Fatalf("%s: wrote %d bytes, counted %d, want %d", c.name, b.Len(), n, 1<<17)
+		}
+		order := c.order
+		if order == nil {
+			order = binary.BigEndian
+		}
+		p := b.Bytes()
+		for i := range 1 << 16 {
+			if v := order.Uint16(p[2*i:]); v != uint16(i) {
+				t.Fatalf("%s: term %d is %d", c.name, i, v)
+			}
+		}
+	}
+	// Write threads the byte order through Options.
+	var big, little bytes.Buffer
+	if _, err := Write(&big, Options{Alphabet: 300, Order: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Write(&little, Options{Alphabet: 300, Order: 2, ByteOrder: binary.LittleEndian}); err != nil {
+		t.Fatal(err)
+	}
+	if big.Len() != little.Len() {
+		t.Fatalf("%d bytes big-endian but %d little-endian", big.Len(), little.Len())
+	}
+	b, l := big.Bytes(), little.Bytes()
+	for i := 0; i < len(b); i += 2 {
+		if b[i] != l[i+1] || b[i+1] != l[i] {
+			t.Fatalf("term %d is %x big-endian but %x little-endian", i/2, b[i:i+2], l[i:i+2])
+		}
+	}
+}
+
+func TestWideCovers(t *testing.T) {
+	// Each pair of symbols of B(300, 2) appears exactly once.
+	const k = 300
+	w, _ := NewWide(k, 2)
+	seen := make([]bool, k*k)
+	prev, count := -1, 0
+	for term := range w.Terms() {
+		if prev >= 0 {
+			pair := prev*k + int(term)
+			if seen[pair] {
+				t.Fatalf("pair %d, %d repeats", prev, term)
+			}
+			seen[pair] = true
+			count++
+		}
+		prev = int(term)
+	}
+	if count != k*k {
+		t.Errorf("%d pairs, want %d", count, k*k)
+	}
+}
+
+func TestWideSize(t *testing.T) {
+	for _, c := range []struct{ k, n int }{{300, 1}, {300, 2}, {1000, 2}, {17, 4}} {
+		w, _ := NewWide(c.k, c.n)
+		size, err := w.Size()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if _, err := w.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		if size != int64(b.Len()) {
+			t.Errorf("B(%d, %d): Size is %d, but WriteTo wrote %d", c.k, c.n, size, b.Len())
+		}
+	}
+	w, _ := NewWide(65536, 4)
+	if _, err := w.Size(); !errors.Is(err, ErrOrder) {
+		t.Errorf("B(65536, 4): got error %v, want %v", err, ErrOrder)
+	}
+	for _, c := range []struct {
+		k, n int
+		err  error
+	}{{0, 1, ErrAlphabet}, {65537, 1, ErrAlphabet}, {300, 0, ErrOrder}} {
+		if _, err := NewWide(c.k, c.n); !errors.Is(err, c.err) {
+			t.Errorf("NewWide(%d, %d): got error %v, want %v", c.k, c.n, err, c.err)
+		}
+	}
+}
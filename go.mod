@@ -1,3 +1,11 @@
 module github.com/zephyrtronium/conip
 
-go 1.14
+go 1.23.0
+
+require (
+	github.com/klauspost/compress v1.18.0
+	golang.org/x/crypto v0.36.0
+	golang.org/x/term v0.30.0
+)
+
+require golang.org/x/sys v0.31.0 // indirect